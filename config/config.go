@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"time"
+	"uptime-monitor/models"
 	"uptime-monitor/services"
 	"uptime-monitor/types"
 
@@ -14,6 +15,9 @@ import (
 var DB *gorm.DB
 
 func InitConfig() {
+	// Secrets in the database are encrypted with the master key
+	initSecrets()
+
 	var err error
 	DB, err = gorm.Open(sqlite.Open("monitor.db"), &gorm.Config{})
 	if err != nil {
@@ -24,16 +28,33 @@ func InitConfig() {
 	log.Println("Ensuring database schema is up to date...")
 	err = DB.AutoMigrate(
 		&types.Profile{},
+		&types.ProfileMembership{},
 		&types.Monitor{},
 		&types.Log{},
 		&types.SMTPSettings{},
 		&types.NotificationSettings{},
 		&types.NotificationMethod{},
+		&types.NotificationDelivery{},
+		&types.NotificationAttempt{},
+		&types.NotificationRule{},
+		&types.EscalationPolicy{},
+		&types.EscalationLevel{},
+		&types.Escalation{},
+		&types.Incident{},
+		&types.IncidentEvent{},
+		&types.MaintenanceWindow{},
+		&types.StatusPage{},
+		&types.StatusPageComponent{},
+		&models.User{},
+		&models.Session{},
+		&models.APIToken{},
+		&models.APITokenUsage{},
 		&services.Credential{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	migrateSecrets(DB)
 
 	// Create default profile if none exists
 	var profileCount int64
@@ -43,7 +64,6 @@ func InitConfig() {
 			ID:          uuid.New().String(),
 			Name:        "Default Profile",
 			Description: "Default monitoring profile",
-			IsActive:    true,
 			CreatedAt:   time.Now(),
 		}
 		if err := DB.Create(&defaultProfile).Error; err != nil {
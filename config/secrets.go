@@ -0,0 +1,104 @@
+package config
+
+import (
+	"log"
+	"uptime-monitor/secrets"
+
+	"gorm.io/gorm"
+)
+
+// secretColumns lists the columns holding secrets. The monitors' headers and
+// the notification methods' config columns are JSON objects, of which only the
+// secret fields are encrypted.
+var secretColumns = []struct {
+	table, column string
+	json          bool
+}{
+	{"credentials", "token", false},
+	{"credentials", "password", false},
+	{"credentials", "client_secret", false},
+	{"credentials", "refresh_token", false},
+	{"smtp_settings", "password", false},
+	{"smtp_settings", "smtp_password", false},
+	{"notification_settings", "smtp_password", false},
+	{"monitors", "db_password", false},
+	{"monitors", "headers", true},
+	{"notification_methods", "config", true},
+}
+
+// initSecrets loads the master keys that encrypt secrets in the database
+func initSecrets() {
+	keyring, generated, err := secrets.LoadKeyring()
+	if err != nil {
+		log.Fatal("Failed to load master key: ", err)
+	}
+	if generated {
+		log.Printf("⚠️ Generated a new master key in %s. Back it up with the database: encrypted secrets cannot be read without it.", secrets.DefaultKeyFile)
+	}
+	secrets.SetDefault(keyring)
+	log.Printf("Loaded master key %s", keyring.KeyID())
+}
+
+// migrateSecrets encrypts secrets stored before encryption was enabled and
+// re-seals those sealed with a previous master key, so that the previous key
+// can be dropped after a rotation
+func migrateSecrets(db *gorm.DB) {
+	keyring := secrets.Default()
+	total := 0
+	for _, c := range secretColumns {
+		if !db.Migrator().HasColumn(c.table, c.column) {
+			continue
+		}
+		count, err := resealColumn(db, keyring, c.table, c.column, c.json)
+		if err != nil {
+			log.Fatalf("Failed to encrypt %s.%s: %v", c.table, c.column, err)
+		}
+		total += count
+	}
+	if total > 0 {
+		log.Printf("Encrypted %d stored secrets with master key %s", total, keyring.KeyID())
+	}
+}
+
+// resealColumn reseals the values of a column that need it and returns how
+// many rows were updated. Rows are addressed by SQLite's rowid, as not every
+// table has an id column.
+func resealColumn(db *gorm.DB, keyring *secrets.Keyring, table, column string, isJSON bool) (int, error) {
+	var rows []struct {
+		RowID int64
+		Value string
+	}
+	err := db.Table(table).Select("rowid AS row_id, " + column + " AS value").
+		Where(column + " IS NOT NULL AND " + column + " <> ''").Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			value, changed := row.Value, false
+			if isJSON {
+				var resealed []byte
+				if resealed, changed, err = keyring.ResealJSON([]byte(row.Value)); err != nil {
+					return err
+				}
+				value = string(resealed)
+			} else if keyring.NeedsReseal(row.Value) {
+				if value, err = keyring.Reseal(row.Value); err != nil {
+					return err
+				}
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+			if err := tx.Exec("UPDATE "+table+" SET "+column+" = ? WHERE rowid = ?", value, row.RowID).Error; err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"uptime-monitor/models"
+	"uptime-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenUsageLimit caps the usage records returned for a token
+const apiTokenUsageLimit = 200
+
+type APITokenController struct {
+	repo     *repository.APITokenRepository
+	profiles *repository.ProfileRepository
+}
+
+func NewAPITokenController(repo *repository.APITokenRepository, profiles *repository.ProfileRepository) *APITokenController {
+	return &APITokenController{repo: repo, profiles: profiles}
+}
+
+// GetTokens lists the caller's personal tokens; admins see every token
+func (c *APITokenController) GetTokens(ctx *gin.Context) {
+	user := CurrentUser(ctx)
+	owner := user.ID
+	if user.IsAdmin {
+		owner = ""
+	}
+
+	tokens, err := c.repo.GetTokens(owner)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API tokens"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a token and returns it once, in the token field
+func (c *APITokenController) CreateToken(ctx *gin.Context) {
+	var req struct {
+		Name      string     `json:"name" binding:"required"`
+		Kind      string     `json:"kind"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		ProfileID string     `json:"profile_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := CurrentUser(ctx)
+	token := &models.APIToken{
+		Name:      req.Name,
+		Kind:      req.Kind,
+		CreatedBy: user.Username,
+		ProfileID: req.ProfileID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	switch token.Kind {
+	case "", models.APITokenPersonal:
+		token.Kind = models.APITokenPersonal
+		token.UserID = user.ID
+	case models.APITokenService:
+		if !user.IsAdmin {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create service tokens"})
+			return
+		}
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Kind must be personal or service"})
+		return
+	}
+
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token.Scopes = scopes
+
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+	if token.ProfileID != "" {
+		if _, err := c.profiles.GetProfileByID(token.ProfileID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile not found"})
+			return
+		}
+	}
+
+	plain, err := c.repo.CreateToken(token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+		return
+	}
+	c.repo.PruneUsage()
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"token":     plain,
+		"api_token": token,
+		"message":   "Store this token now, it will not be shown again",
+	})
+}
+
+// RevokeToken revokes a token
+func (c *APITokenController) RevokeToken(ctx *gin.Context) {
+	token, ok := c.loadToken(ctx)
+	if !ok {
+		return
+	}
+	if err := c.repo.RevokeToken(token); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+	ctx.JSON(http.StatusOK, token)
+}
+
+// GetTokenUsage returns the most recent requests made with a token
+func (c *APITokenController) GetTokenUsage(ctx *gin.Context) {
+	token, ok := c.loadToken(ctx)
+	if !ok {
+		return
+	}
+	usage, err := c.repo.GetUsage(token.ID, apiTokenUsageLimit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API token usage"})
+		return
+	}
+	ctx.JSON(http.StatusOK, usage)
+}
+
+// loadToken loads the token in the path if the caller may manage it: its owner,
+// or an admin
+func (c *APITokenController) loadToken(ctx *gin.Context) (*models.APIToken, bool) {
+	token, err := c.repo.GetTokenByID(ctx.Param("id"))
+	user := CurrentUser(ctx)
+	if err != nil || (!user.IsAdmin && token.UserID != user.ID) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return nil, false
+	}
+	return token, true
+}
+
+// validateScopes checks requested scopes and joins them for storage
+func validateScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("At least one scope is required")
+	}
+	seen := make(map[string]bool)
+	var valid []string
+	for _, scope := range scopes {
+		known := false
+		for _, s := range models.APITokenScopes {
+			known = known || s == scope
+		}
+		if !known {
+			return "", fmt.Errorf("Unknown scope %q, expected one of %s", scope, strings.Join(models.APITokenScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+	return strings.Join(valid, ","), nil
+}
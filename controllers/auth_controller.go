@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"uptime-monitor/models"
+	"uptime-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SessionCookieName is the cookie holding the browser session token
+	SessionCookieName = "heimdall_session"
+
+	defaultSessionTTL = 7 * 24 * time.Hour
+	// sessionTouchInterval limits how often activity is written back to a session
+	sessionTouchInterval = time.Minute
+
+	// Failed logins allowed per client address and per username within
+	// loginFailureWindow
+	maxLoginFailures   = 10
+	loginFailureWindow = 15 * time.Minute
+	// loginFailureSweepInterval is how often failures that left the window are
+	// dropped for every client and username
+	loginFailureSweepInterval = time.Minute
+)
+
+// Context keys set by RequireAuth
+const (
+	contextUserKey    = "user"
+	contextSessionKey = "session"
+	contextTokenKey   = "api_token"
+)
+
+type AuthController struct {
+	users      *repository.UserRepository
+	tokens     *repository.APITokenRepository
+	profiles   *repository.ProfileRepository
+	sessionTTL time.Duration
+
+	failuresMu sync.Mutex
+	failures   map[string][]time.Time // Failed logins by client address and by username
+	lastSweep  time.Time
+}
+
+// NewAuthController creates an AuthController. The session lifetime can be set
+// with HEIMDALL_SESSION_TTL (e.g. "12h"); it slides with activity.
+func NewAuthController(users *repository.UserRepository, tokens *repository.APITokenRepository, profiles *repository.ProfileRepository) *AuthController {
+	ttl := defaultSessionTTL
+	if value := os.Getenv("HEIMDALL_SESSION_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+	return &AuthController{
+		users:      users,
+		tokens:     tokens,
+		profiles:   profiles,
+		sessionTTL: ttl,
+		failures:   make(map[string][]time.Time),
+	}
+}
+
+// CurrentUser returns the signed-in user set by RequireAuth, or nil for
+// requests made with a service token
+func CurrentUser(ctx *gin.Context) *models.User {
+	if user, ok := ctx.Get(contextUserKey); ok {
+		return user.(*models.User)
+	}
+	return nil
+}
+
+// CurrentToken returns the API token the request was made with, or nil
+func CurrentToken(ctx *gin.Context) *models.APIToken {
+	if token, ok := ctx.Get(contextTokenKey); ok {
+		return token.(*models.APIToken)
+	}
+	return nil
+}
+
+// Login checks a username and password and starts a session cookie
+func (c *AuthController) Login(ctx *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username and password are required"})
+		return
+	}
+
+	client := ctx.ClientIP()
+	failureKeys := loginFailureKeys(client, req.Username)
+	if c.tooManyFailures(failureKeys...) {
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed logins, try again later"})
+		return
+	}
+
+	user, err := c.users.Authenticate(req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidLogin) {
+			c.recordFailure(failureKeys...)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	token, _, err := c.users.CreateSession(user.ID, ctx.Request.UserAgent(), client, c.sessionTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	c.users.DeleteExpiredSessions()
+
+	c.setSessionCookie(ctx, token, int(c.sessionTTL.Seconds()))
+	ctx.JSON(http.StatusOK, user)
+}
+
+// Logout ends the current session
+func (c *AuthController) Logout(ctx *gin.Context) {
+	if token, err := ctx.Cookie(SessionCookieName); err == nil && token != "" {
+		if err := c.users.DeleteSession(token); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+			return
+		}
+	}
+	c.setSessionCookie(ctx, "", -1)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Signed out"})
+}
+
+// Me returns the signed-in user
+func (c *AuthController) Me(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, CurrentUser(ctx))
+}
+
+// ChangePassword changes the signed-in user's password after checking the
+// current one. All of the user's sessions end, including this one.
+func (c *AuthController) ChangePassword(ctx *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Current and new password are required"})
+		return
+	}
+
+	user := CurrentUser(ctx)
+	if _, err := c.users.Authenticate(user.Username, req.CurrentPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if err := c.users.UpdatePassword(user.ID, req.NewPassword); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.setSessionCookie(ctx, "", -1)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed, please sign in again"})
+}
+
+// RequireAuth rejects requests without a valid session or API token, except
+// for the paths that are public: health checks, public status pages, the login
+// page and static assets. Browsers asking for a page are redirected to the
+// login page.
+func (c *AuthController) RequireAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if isPublicPath(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		if header := ctx.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			c.authenticateToken(ctx, strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+			return
+		}
+
+		if token, err := ctx.Cookie(SessionCookieName); err == nil && token != "" {
+			if session, err := c.users.GetSession(token); err == nil {
+				if time.Since(session.LastSeenAt) > sessionTouchInterval {
+					if err := c.users.TouchSession(session, c.sessionTTL); err == nil {
+						c.setSessionCookie(ctx, token, int(c.sessionTTL.Seconds()))
+					}
+				}
+				ctx.Set(contextUserKey, session.User)
+				ctx.Set(contextSessionKey, session)
+				ctx.Next()
+				return
+			}
+		}
+
+		if ctx.Request.Method == http.MethodGet && ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+			ctx.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(ctx.Request.URL.Path))
+			ctx.Abort()
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	}
+}
+
+// authenticateToken authorizes a request made with an API token against the
+// token's scopes and profile restriction, and logs its use
+func (c *AuthController) authenticateToken(ctx *gin.Context, plain string) {
+	token, err := c.tokens.GetValidToken(plain)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API token"})
+		return
+	}
+
+	method, path := ctx.Request.Method, ctx.Request.URL.Path
+	scope := requiredTokenScope(method, path)
+	if scope == "" {
+		c.recordTokenUsage(token, ctx, http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available to API tokens"})
+		return
+	}
+	if !token.HasScope(scope) {
+		c.recordTokenUsage(token, ctx, http.StatusForbidden)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token lacks the " + scope + " scope"})
+		return
+	}
+
+	if token.User != nil {
+		ctx.Set(contextUserKey, token.User)
+	}
+	ctx.Set(contextTokenKey, token)
+	ctx.Next()
+	c.recordTokenUsage(token, ctx, ctx.Writer.Status())
+}
+
+func (c *AuthController) recordTokenUsage(token *models.APIToken, ctx *gin.Context, status int) {
+	if err := c.tokens.RecordUsage(token, ctx.Request.Method, ctx.Request.URL.Path, status, ctx.ClientIP()); err != nil {
+		log.Printf("Failed to record usage of API token %s: %v", token.ID, err)
+	}
+}
+
+// requiredTokenScope returns the scope an API token needs for a request, or ""
+// for endpoints tokens cannot use (accounts, tokens and profile changes)
+func requiredTokenScope(method, path string) string {
+	read := method == http.MethodGet || method == http.MethodHead
+	hasPrefix := func(prefix string) bool {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+
+	switch {
+	case hasPrefix("/api/credentials"):
+		return models.ScopeCredentialsManage
+	case hasPrefix("/api/notifications"), hasPrefix("/api/smtp_settings"), path == "/save_smtp":
+		return models.ScopeNotificationsManage
+	case hasPrefix("/api/monitors"), hasPrefix("/api/stats"), hasPrefix("/api/incidents"),
+		hasPrefix("/api/maintenance"), hasPrefix("/api/status-pages"), hasPrefix("/logs"):
+		if read {
+			return models.ScopeMonitorsRead
+		}
+		return models.ScopeMonitorsWrite
+	case hasPrefix("/api/profiles") && read:
+		return models.ScopeMonitorsRead
+	}
+	return ""
+}
+
+// RequireAdmin rejects signed-in users that are not admins
+func (c *AuthController) RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if user := CurrentUser(ctx); user == nil || !user.IsAdmin {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// isPublicPath reports whether a path is served without authentication
+func isPublicPath(path string) bool {
+	switch path {
+	case "/health", "/login", "/api/auth/login":
+		return true
+	}
+	return strings.HasPrefix(path, "/status/") ||
+		strings.HasPrefix(path, "/css/") ||
+		strings.HasPrefix(path, "/static/")
+}
+
+func (c *AuthController) setSessionCookie(ctx *gin.Context, token string, maxAge int) {
+	secure := ctx.Request.TLS != nil ||
+		ctx.GetHeader("X-Forwarded-Proto") == "https" ||
+		os.Getenv("HEIMDALL_SECURE_COOKIES") == "true"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(SessionCookieName, token, maxAge, "/", "", secure, true)
+}
+
+// loginFailureKeys returns the keys failed logins are counted under: the
+// client address, so that one client cannot try many accounts, and the
+// username, so that many clients cannot try one account
+func loginFailureKeys(client, username string) []string {
+	return []string{"client:" + client, "user:" + strings.ToLower(strings.TrimSpace(username))}
+}
+
+// tooManyFailures reports whether any of keys is locked out of logging in
+func (c *AuthController) tooManyFailures(keys ...string) bool {
+	c.failuresMu.Lock()
+	defer c.failuresMu.Unlock()
+
+	now := time.Now()
+	locked := false
+	for _, key := range keys {
+		if len(c.recentFailures(key, now)) >= maxLoginFailures {
+			locked = true
+		}
+	}
+	return locked
+}
+
+// recordFailure counts a failed login under keys. Every
+// loginFailureSweepInterval the failures that left the window are dropped for
+// every key, so clients and usernames that stopped trying are forgotten.
+func (c *AuthController) recordFailure(keys ...string) {
+	c.failuresMu.Lock()
+	defer c.failuresMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= loginFailureSweepInterval {
+		for key := range c.failures {
+			c.recentFailures(key, now)
+		}
+		c.lastSweep = now
+	}
+	for _, key := range keys {
+		c.failures[key] = append(c.failures[key], now)
+	}
+}
+
+// recentFailures drops the failures of a key older than loginFailureWindow and
+// returns the others. Callers must hold c.failuresMu.
+func (c *AuthController) recentFailures(key string, now time.Time) []time.Time {
+	cutoff := now.Add(-loginFailureWindow)
+	recent := c.failures[key][:0]
+	for _, t := range c.failures[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(c.failures, key)
+	} else {
+		c.failures[key] = recent
+	}
+	return recent
+}
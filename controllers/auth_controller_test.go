@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+	c := &AuthController{failures: make(map[string][]time.Time)}
+	for i := 0; i < maxLoginFailures; i++ {
+		if c.tooManyFailures(loginFailureKeys("10.0.0.1", "admin")...) {
+			t.Fatalf("locked out after %d failures", i)
+		}
+		c.recordFailure(loginFailureKeys("10.0.0.1", "admin")...)
+	}
+
+	tests := []struct {
+		name     string
+		client   string
+		username string
+		want     bool
+	}{
+		{"same client and username", "10.0.0.1", "admin", true},
+		{"same client, other username", "10.0.0.1", "alice", true},
+		{"other client, same username", "10.0.0.2", " Admin ", true},
+		{"other client and username", "10.0.0.2", "alice", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.tooManyFailures(loginFailureKeys(tt.client, tt.username)...); got != tt.want {
+				t.Errorf("tooManyFailures(%s, %s) = %v, want %v", tt.client, tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginFailureSweep(t *testing.T) {
+	stale := time.Now().Add(-loginFailureWindow - time.Minute)
+	c := &AuthController{failures: map[string][]time.Time{
+		"client:10.0.0.1": {stale},
+		"user:alice":      {stale, time.Now()},
+	}}
+
+	c.recordFailure(loginFailureKeys("10.0.0.2", "bob")...)
+	if _, ok := c.failures["client:10.0.0.1"]; ok {
+		t.Error("recordFailure() kept a client whose failures left the window")
+	}
+	if got := len(c.failures["user:alice"]); got != 1 {
+		t.Errorf("recordFailure() kept %d failures of alice, want the recent one", got)
+	}
+	if len(c.failures) != 3 {
+		t.Errorf("failures = %v, want alice, 10.0.0.2 and bob", c.failures)
+	}
+
+	// Sweeps only run once per loginFailureSweepInterval
+	c.failures["client:10.0.0.3"] = []time.Time{stale}
+	c.recordFailure(loginFailureKeys("10.0.0.2", "bob")...)
+	if _, ok := c.failures["client:10.0.0.3"]; !ok {
+		t.Error("recordFailure() swept again within loginFailureSweepInterval")
+	}
+}
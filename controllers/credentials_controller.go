@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type CredentialsController struct {
@@ -19,48 +21,8 @@ func NewCredentialsController(service *services.CredentialsService) *Credentials
 }
 
 func (c *CredentialsController) GetCredentials(ctx *gin.Context) {
-	// Comprehensive logging for debugging
-	log.Printf("🔍 GetCredentials Request Received")
-	log.Printf("  Full Request URL: %s", ctx.Request.URL.String())
-	log.Printf("  Request Method: %s", ctx.Request.Method)
-
-	// Log all incoming parameters for debugging
-	log.Printf("  Query Parameters:")
-	for key, values := range ctx.Request.URL.Query() {
-		log.Printf("    %s: %v", key, values)
-	}
-
-	log.Printf("  Request Headers:")
-	for key, values := range ctx.Request.Header {
-		log.Printf("    %s: %v", key, values)
-	}
-
-	// Try to get profile ID from query parameter first
-	profileID := ctx.Query("profileId")
-
-	// If not in query parameter, try from header
-	if profileID == "" {
-		profileID = ctx.GetHeader("X-Profile-ID")
-	}
-
-	// Log the determined profile ID
-	log.Printf("  Determined Profile ID: %s", profileID)
-
-	// If still no profile ID, return error with detailed logging
-	if profileID == "" {
-		log.Println("❌ ERROR: No profile ID provided")
-		log.Printf("  Available Query Params: %v", ctx.Request.URL.Query())
-		log.Printf("  Available Headers: %v", ctx.Request.Header)
-
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Profile ID is required",
-			"details": gin.H{
-				"query_params": ctx.Request.URL.Query(),
-				"headers":      ctx.Request.Header,
-			},
-		})
-		return
-	}
+	// The profile comes from the path, X-Profile-ID header or profileId query parameter
+	profileID := RequestProfileID(ctx)
 
 	// Fetch credentials for the specific profile
 	credentials, err := c.service.GetCredentials(profileID)
@@ -95,6 +57,10 @@ func (c *CredentialsController) GetCredentials(ctx *gin.Context) {
 			"name":        cred.Name,
 			"type":        cred.Type,
 			"header_name": cred.HeaderName,
+			"created_at":  cred.CreatedAt,
+		}
+		if cred.RefreshError != "" {
+			maskedCredentials[i]["refresh_error"] = cred.RefreshError
 		}
 	}
 
@@ -103,17 +69,22 @@ func (c *CredentialsController) GetCredentials(ctx *gin.Context) {
 }
 
 func (c *CredentialsController) CreateCredential(ctx *gin.Context) {
-	profileID := ctx.GetHeader("X-Profile-ID")
-	if profileID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile ID is required"})
-		return
-	}
+	profileID := RequestProfileID(ctx)
 
 	var cred services.Credential
 	if err := ctx.ShouldBindJSON(&cred); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential data: " + err.Error()})
 		return
 	}
+	if !bodyProfileMatches(ctx, cred.ProfileID) {
+		return
+	}
+	if cred.Type == "oauth2" {
+		if err := services.ValidateOAuth2Credential(&cred, true); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	cred.ID = uuid.New().String()
 	cred.ProfileID = profileID
@@ -130,11 +101,7 @@ func (c *CredentialsController) CreateCredential(ctx *gin.Context) {
 }
 
 func (c *CredentialsController) GetCredential(ctx *gin.Context) {
-	profileID := ctx.GetHeader("X-Profile-ID")
-	if profileID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile ID is required"})
-		return
-	}
+	profileID := RequestProfileID(ctx)
 
 	id := ctx.Param("id")
 	cred, err := c.service.GetCredential(id)
@@ -153,11 +120,7 @@ func (c *CredentialsController) GetCredential(ctx *gin.Context) {
 }
 
 func (c *CredentialsController) UpdateCredential(ctx *gin.Context) {
-	profileID := ctx.GetHeader("X-Profile-ID")
-	if profileID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile ID is required"})
-		return
-	}
+	profileID := RequestProfileID(ctx)
 
 	id := ctx.Param("id")
 	var cred services.Credential
@@ -165,12 +128,25 @@ func (c *CredentialsController) UpdateCredential(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential data: " + err.Error()})
 		return
 	}
+	if !bodyProfileMatches(ctx, cred.ProfileID) {
+		return
+	}
+	if cred.Type == "oauth2" {
+		if err := services.ValidateOAuth2Credential(&cred, false); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	cred.ID = id
 	cred.ProfileID = profileID
 	cred.UpdatedAt = time.Now()
 
 	if err := c.service.UpdateCredential(&cred); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Credential not found"})
+			return
+		}
 		log.Printf("Error updating credential %s: %v", id, err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update credential"})
 		return
@@ -180,11 +156,7 @@ func (c *CredentialsController) UpdateCredential(ctx *gin.Context) {
 }
 
 func (c *CredentialsController) DeleteCredential(ctx *gin.Context) {
-	profileID := ctx.GetHeader("X-Profile-ID")
-	if profileID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile ID is required"})
-		return
-	}
+	profileID := RequestProfileID(ctx)
 
 	id := ctx.Param("id")
 	if err := c.service.DeleteCredential(id, profileID); err != nil {
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"uptime-monitor/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+type DeliveryController struct {
+	repo *repository.DeliveryRepository
+}
+
+func NewDeliveryController(repo *repository.DeliveryRepository) *DeliveryController {
+	return &DeliveryController{repo: repo}
+}
+
+// GetDeliveries lists the latest notifications of the request profile,
+// optionally filtered by the status, monitor_id and method_id query parameters.
+// limit caps the number returned, 100 by default.
+func (c *DeliveryController) GetDeliveries(ctx *gin.Context) {
+	limit := defaultDeliveryLimit
+	if l := ctx.Query("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > maxDeliveryLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	deliveries, err := c.repo.GetDeliveries(RequestProfileID(ctx), ctx.Query("status"), ctx.Query("monitor_id"), ctx.Query("method_id"), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification deliveries"})
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery returns a notification with its delivery attempts
+func (c *DeliveryController) GetDelivery(ctx *gin.Context) {
+	delivery, err := c.repo.GetDeliveryByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification delivery not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// ResendDelivery queues a notification to be sent again through the same
+// method, whatever became of it the first time
+func (c *DeliveryController) ResendDelivery(ctx *gin.Context) {
+	delivery, err := c.repo.GetDeliveryByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification delivery not found"})
+		return
+	}
+
+	resent, err := c.repo.Resend(delivery)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend notification"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, resent)
+}
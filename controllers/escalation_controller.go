@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultEscalationLimit = 100
+
+type EscalationController struct {
+	repo     *repository.EscalationRepository
+	profiles *repository.ProfileRepository
+}
+
+func NewEscalationController(repo *repository.EscalationRepository, profiles *repository.ProfileRepository) *EscalationController {
+	return &EscalationController{repo: repo, profiles: profiles}
+}
+
+// GetPolicies lists the escalation policies of the request profile
+func (c *EscalationController) GetPolicies(ctx *gin.Context) {
+	policies, err := c.repo.GetPolicies(RequestProfileID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch escalation policies"})
+		return
+	}
+	ctx.JSON(http.StatusOK, policies)
+}
+
+// GetPolicy returns an escalation policy with its levels
+func (c *EscalationController) GetPolicy(ctx *gin.Context) {
+	policy, err := c.repo.GetPolicyByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, policy)
+}
+
+// CreatePolicy creates an escalation policy. A default policy replaces the
+// profile's previous default.
+func (c *EscalationController) CreatePolicy(ctx *gin.Context) {
+	var policy types.EscalationPolicy
+	if err := ctx.ShouldBindJSON(&policy); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, policy.ProfileID) {
+		return
+	}
+	if err := c.validatePolicy(RequestProfileID(ctx), &policy); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escalation policy: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.CreatePolicy(RequestProfileID(ctx), &policy); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create escalation policy"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, policy)
+}
+
+// UpdatePolicy replaces an escalation policy and its levels. Running
+// escalations continue with the new levels.
+func (c *EscalationController) UpdatePolicy(ctx *gin.Context) {
+	existing, err := c.repo.GetPolicyByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+
+	var policy types.EscalationPolicy
+	if err := ctx.ShouldBindJSON(&policy); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, policy.ProfileID) {
+		return
+	}
+	policy.ID = existing.ID
+	policy.ProfileID = existing.ProfileID
+	policy.CreatedAt = existing.CreatedAt
+
+	if err := c.validatePolicy(policy.ProfileID, &policy); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escalation policy: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.UpdatePolicy(&policy); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update escalation policy"})
+		return
+	}
+	ctx.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy deletes an escalation policy
+func (c *EscalationController) DeletePolicy(ctx *gin.Context) {
+	if err := c.repo.DeletePolicy(RequestProfileID(ctx), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Escalation policy deleted successfully"})
+}
+
+// GetEscalations lists the latest escalations of the request profile,
+// optionally filtered by the status and incident_id query parameters
+func (c *EscalationController) GetEscalations(ctx *gin.Context) {
+	limit := defaultEscalationLimit
+	if l := ctx.Query("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > maxDeliveryLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	escalations, err := c.repo.GetEscalations(RequestProfileID(ctx), ctx.Query("status"), ctx.Query("incident_id"), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch escalations"})
+		return
+	}
+	ctx.JSON(http.StatusOK, escalations)
+}
+
+// validatePolicy checks a policy's levels, and that their methods belong to the profile
+func (c *EscalationController) validatePolicy(profileID string, policy *types.EscalationPolicy) error {
+	if err := services.ValidateEscalationPolicy(policy); err != nil {
+		return err
+	}
+	for i, level := range policy.Levels {
+		for _, id := range types.SplitList(level.MethodIDs) {
+			if _, err := c.profiles.GetNotificationMethod(profileID, id); err != nil {
+				return fmt.Errorf("level %d: notification method %s not found", i+1, id)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"uptime-monitor/repository"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IncidentController struct {
+	repo *repository.IncidentRepository
+}
+
+func NewIncidentController(repo *repository.IncidentRepository) *IncidentController {
+	return &IncidentController{repo: repo}
+}
+
+// incidentActionRequest is the body of the acknowledge, resolve and comment endpoints
+type incidentActionRequest struct {
+	Author  string `json:"author"`
+	Message string `json:"message"`
+}
+
+// GetIncidents lists the incidents of the request profile, optionally filtered by
+// the status and monitor_id query parameters
+func (c *IncidentController) GetIncidents(ctx *gin.Context) {
+	incidents, err := c.repo.GetIncidents(RequestProfileID(ctx), ctx.Query("status"), ctx.Query("monitor_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incidents"})
+		return
+	}
+	ctx.JSON(http.StatusOK, incidents)
+}
+
+// GetIncident returns an incident with its timeline
+func (c *IncidentController) GetIncident(ctx *gin.Context) {
+	incident, err := c.repo.GetIncidentByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, incident)
+}
+
+// AcknowledgeIncident marks an open incident as being worked on, which stops
+// repeat notifications until it is resolved
+func (c *IncidentController) AcknowledgeIncident(ctx *gin.Context) {
+	incident, req, ok := c.loadForAction(ctx)
+	if !ok {
+		return
+	}
+	if incident.Status != types.IncidentStatusOpen {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Only open incidents can be acknowledged"})
+		return
+	}
+
+	if err := c.repo.Acknowledge(incident, req.Author, req.Message); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge incident"})
+		return
+	}
+	c.respondWithIncident(ctx, incident.ID)
+}
+
+// ResolveIncident closes an incident by hand. If the monitor is still failing a
+// new incident is opened on its next failing check.
+func (c *IncidentController) ResolveIncident(ctx *gin.Context) {
+	incident, req, ok := c.loadForAction(ctx)
+	if !ok {
+		return
+	}
+	if incident.Status == types.IncidentStatusResolved {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Incident is already resolved"})
+		return
+	}
+
+	if err := c.repo.Resolve(incident, types.IncidentEventResolved, req.Author, req.Message); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve incident"})
+		return
+	}
+	c.respondWithIncident(ctx, incident.ID)
+}
+
+// AddComment adds a comment to an incident's timeline
+func (c *IncidentController) AddComment(ctx *gin.Context) {
+	incident, req, ok := c.loadForAction(ctx)
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Comment message is required"})
+		return
+	}
+
+	event, err := c.repo.AddComment(incident.ID, req.Author, req.Message)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, event)
+}
+
+// loadForAction loads the incident named in the path and binds the optional
+// action body, writing an error response if either fails
+func (c *IncidentController) loadForAction(ctx *gin.Context) (*types.Incident, incidentActionRequest, bool) {
+	var req incidentActionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, req, false
+	}
+	if req.Author == "" {
+		req.Author = "anonymous"
+	}
+
+	incident, err := c.repo.GetIncidentByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return nil, req, false
+	}
+	return incident, req, true
+}
+
+// respondWithIncident writes the current state of an incident with its timeline
+func (c *IncidentController) respondWithIncident(ctx *gin.Context, id string) {
+	incident, err := c.repo.GetIncidentByID(RequestProfileID(ctx), id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incident"})
+		return
+	}
+	ctx.JSON(http.StatusOK, incident)
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 	"uptime-monitor/repository"
 	"uptime-monitor/types"
@@ -25,6 +27,11 @@ func (c *LogController) CreateLog(ctx *gin.Context) {
 		return
 	}
 
+	if err := c.repo.VerifyProfileMonitor(RequestProfileID(ctx), log.MonitorID); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
 	log.ID = uuid.New().String()
 	log.CreatedAt = time.Now()
 
@@ -36,12 +43,71 @@ func (c *LogController) CreateLog(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, log)
 }
 
+// GetLogsByMonitor returns a monitor's check history. The optional from and to
+// query parameters (RFC 3339) restrict it to a time range and limit caps the
+// number of logs returned.
 func (c *LogController) GetLogsByMonitor(ctx *gin.Context) {
-	monitorID := ctx.Param("monitorId")
-	logs, err := c.repo.GetLogsByMonitorID(monitorID)
+	monitorID := ctx.Param("monitor_id")
+
+	from, to, err := parseTimeRange(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if l := ctx.Query("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	var logs []types.Log
+	if from.IsZero() && to.IsZero() && limit == 0 {
+		logs, err = c.repo.GetLogsByMonitorID(RequestProfileID(ctx), monitorID)
+	} else {
+		logs, err = c.repo.GetLogsByMonitorIDInRange(RequestProfileID(ctx), monitorID, from, to, limit)
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logs"})
 		return
 	}
 	ctx.JSON(http.StatusOK, logs)
 }
+
+// GetResponseCodeCounts returns how often each response code was returned by a
+// monitor, optionally restricted to the from/to time range
+func (c *LogController) GetResponseCodeCounts(ctx *gin.Context) {
+	monitorID := ctx.Param("monitor_id")
+
+	from, to, err := parseTimeRange(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts, err := c.repo.CountResponseCodes(RequestProfileID(ctx), monitorID, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count response codes"})
+		return
+	}
+	ctx.JSON(http.StatusOK, counts)
+}
+
+// parseTimeRange reads the optional from and to query parameters
+func parseTimeRange(ctx *gin.Context) (from, to time.Time, err error) {
+	if v := ctx.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, fmt.Errorf("Invalid from time: %s", v)
+		}
+	}
+	if v := ctx.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, fmt.Errorf("Invalid to time: %s", v)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceController struct {
+	repo *repository.MaintenanceRepository
+}
+
+func NewMaintenanceController(repo *repository.MaintenanceRepository) *MaintenanceController {
+	return &MaintenanceController{repo: repo}
+}
+
+// startMaintenanceRequest is the body of the ad-hoc maintenance endpoint
+type startMaintenanceRequest struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required"`
+	AllMonitors     bool   `json:"all_monitors"`
+	MonitorIDs      string `json:"monitor_ids"`
+	Tags            string `json:"tags"`
+}
+
+// GetWindows lists the maintenance windows of the request profile
+func (c *MaintenanceController) GetWindows(ctx *gin.Context) {
+	windows, err := c.repo.GetWindowsForProfile(RequestProfileID(ctx), false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch maintenance windows"})
+		return
+	}
+	ctx.JSON(http.StatusOK, windows)
+}
+
+// GetActiveWindows lists the maintenance windows of the request profile that are in effect now
+func (c *MaintenanceController) GetActiveWindows(ctx *gin.Context) {
+	windows, err := c.repo.GetWindowsForProfile(RequestProfileID(ctx), false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch maintenance windows"})
+		return
+	}
+
+	now := time.Now()
+	active := []types.MaintenanceWindow{}
+	for i := range windows {
+		if len(services.MaintenanceIntervals(&windows[i], now, now.Add(time.Second))) > 0 {
+			active = append(active, windows[i])
+		}
+	}
+	ctx.JSON(http.StatusOK, active)
+}
+
+// GetWindow returns a maintenance window
+func (c *MaintenanceController) GetWindow(ctx *gin.Context) {
+	window, err := c.repo.GetWindowByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, window)
+}
+
+// CreateWindow creates a one-off or recurring maintenance window. New windows are enabled.
+func (c *MaintenanceController) CreateWindow(ctx *gin.Context) {
+	var window types.MaintenanceWindow
+	if err := ctx.ShouldBindJSON(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, window.ProfileID) {
+		return
+	}
+	window.Enabled = true
+
+	if err := services.ValidateMaintenanceWindow(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance window: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.CreateWindow(RequestProfileID(ctx), &window); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, window)
+}
+
+// StartWindow starts a one-off maintenance window right away
+func (c *MaintenanceController) StartWindow(ctx *gin.Context) {
+	var req startMaintenanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = "Ad-hoc maintenance"
+	}
+
+	now := time.Now()
+	window := types.MaintenanceWindow{
+		Name:        req.Name,
+		Description: req.Description,
+		Enabled:     true,
+		StartsAt:    now,
+		EndsAt:      now.Add(time.Duration(req.DurationMinutes) * time.Minute),
+		AllMonitors: req.AllMonitors,
+		MonitorIDs:  req.MonitorIDs,
+		Tags:        req.Tags,
+	}
+	if err := services.ValidateMaintenanceWindow(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance window: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.CreateWindow(RequestProfileID(ctx), &window); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start maintenance window"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, window)
+}
+
+// EndWindow ends a running one-off maintenance window now
+func (c *MaintenanceController) EndWindow(ctx *gin.Context) {
+	window, err := c.repo.GetWindowByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+
+	now := time.Now()
+	if window.IsRecurring() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Recurring windows cannot be ended early, disable the window instead"})
+		return
+	}
+	if !window.StartsAt.Before(now) || !window.EndsAt.After(now) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Maintenance window is not running"})
+		return
+	}
+
+	window.EndsAt = now
+	if err := c.repo.UpdateWindow(window); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end maintenance window"})
+		return
+	}
+	ctx.JSON(http.StatusOK, window)
+}
+
+// UpdateWindow replaces a maintenance window's schedule and targets
+func (c *MaintenanceController) UpdateWindow(ctx *gin.Context) {
+	existing, err := c.repo.GetWindowByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+
+	var window types.MaintenanceWindow
+	if err := ctx.ShouldBindJSON(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, window.ProfileID) {
+		return
+	}
+	window.ID = existing.ID
+	window.ProfileID = existing.ProfileID
+	window.CreatedAt = existing.CreatedAt
+
+	if err := services.ValidateMaintenanceWindow(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance window: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.UpdateWindow(&window); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update maintenance window"})
+		return
+	}
+	ctx.JSON(http.StatusOK, window)
+}
+
+// DeleteWindow deletes a maintenance window
+func (c *MaintenanceController) DeleteWindow(ctx *gin.Context) {
+	if err := c.repo.DeleteWindow(RequestProfileID(ctx), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Maintenance window deleted successfully"})
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 	"uptime-monitor/config"
 	"uptime-monitor/repository"
+	"uptime-monitor/secrets"
+	"uptime-monitor/services"
 	"uptime-monitor/types"
 
 	"github.com/gin-gonic/gin"
@@ -17,11 +20,12 @@ import (
 )
 
 type MonitorController struct {
-	repo *repository.MonitorRepository
+	repo     *repository.MonitorRepository
+	checkers *services.CheckerRegistry
 }
 
-func NewMonitorController(repo *repository.MonitorRepository) *MonitorController {
-	return &MonitorController{repo: repo}
+func NewMonitorController(repo *repository.MonitorRepository, checkers *services.CheckerRegistry) *MonitorController {
+	return &MonitorController{repo: repo, checkers: checkers}
 }
 
 // CreateMonitor creates a new monitor with SMTP details and sends a confirmation email
@@ -31,15 +35,24 @@ func (c *MonitorController) CreateMonitor(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !bodyProfileMatches(ctx, monitor.ProfileID) {
+		return
+	}
 
 	// Debug logging
-	fmt.Printf("Creating monitor: %+v\n", monitor)
+	fmt.Printf("Creating monitor: %s (%s)\n", monitor.Name, monitor.Type)
 
 	// Set default method if not provided
 	if monitor.Method == "" {
 		monitor.Method = "GET"
 	}
 
+	// Validate monitor type, type-specific settings and body assertions
+	if err := c.validateMonitorSettings(&monitor); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Validate method
 	validMethods := map[string]bool{
 		"GET":     true,
@@ -56,22 +69,14 @@ func (c *MonitorController) CreateMonitor(ctx *gin.Context) {
 		return
 	}
 
-	// Validate credential if provided
-	if monitor.CredentialID != "" {
-		// Check if credential exists and belongs to the active profile
-		credRepo := repository.NewCredentialsRepository(config.DB)
-		credential, err := credRepo.GetCredentialByID(monitor.CredentialID)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential"})
-			return
-		}
-
-		// Ensure the credential belongs to the active profile
-		activeProfile, err := repository.NewProfileRepository(config.DB).GetActiveProfile()
-		if err != nil || credential.ProfileID != activeProfile.ID {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Credential does not belong to active profile"})
-			return
-		}
+	// Validate the credentials of the monitor and its transaction steps
+	if err := validateMonitorCredentials(&monitor, RequestProfileID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateMonitorEscalationPolicy(&monitor, RequestProfileID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Validate headers format if provided
@@ -108,6 +113,7 @@ func (c *MonitorController) CreateMonitor(ctx *gin.Context) {
 	}
 
 	monitor.ID = uuid.New().String()
+	monitor.ProfileID = RequestProfileID(ctx)
 	monitor.CreatedAt = time.Now()
 	monitor.UpdatedAt = time.Now()
 	monitor.Status = "pending"
@@ -127,7 +133,7 @@ func (c *MonitorController) CreateMonitor(ctx *gin.Context) {
 // GetMonitor retrieves a monitor by its ID
 func (c *MonitorController) GetMonitor(ctx *gin.Context) {
 	id := ctx.Param("id")
-	monitor, err := c.repo.GetMonitorByID(id)
+	monitor, err := c.repo.GetMonitorByID(RequestProfileID(ctx), id)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
 		return
@@ -137,7 +143,7 @@ func (c *MonitorController) GetMonitor(ctx *gin.Context) {
 
 // GetAllMonitors retrieves all monitors
 func (c *MonitorController) GetAllMonitors(ctx *gin.Context) {
-	monitors, err := c.repo.GetAllMonitors()
+	monitors, err := c.repo.GetAllMonitors(RequestProfileID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch monitors"})
 		return
@@ -145,12 +151,26 @@ func (c *MonitorController) GetAllMonitors(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, monitors)
 }
 
+// CheckMonitor runs the monitor's check immediately and returns the result.
+// The stored status is left to the scheduler.
+func (c *MonitorController) CheckMonitor(ctx *gin.Context) {
+	id := ctx.Param("id")
+	monitor, err := c.repo.GetMonitorByID(RequestProfileID(ctx), id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
+	result := c.checkers.Check(ctx.Request.Context(), monitor)
+	ctx.JSON(http.StatusOK, result)
+}
+
 func (c *MonitorController) DeleteMonitor(ctx *gin.Context) {
 	id := ctx.Param("id")
 	log.Printf("DELETE REQUEST: Deleting monitor with ID: %s", id)
 
 	// Check if monitor exists first
-	_, err := c.repo.GetMonitorByID(id)
+	_, err := c.repo.GetMonitorByID(RequestProfileID(ctx), id)
 	if err != nil {
 		log.Printf("Monitor not found for deletion: %s - Error: %v", id, err)
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
@@ -158,14 +178,14 @@ func (c *MonitorController) DeleteMonitor(ctx *gin.Context) {
 	}
 
 	// Delete the monitor
-	if err := c.repo.DeleteMonitor(id); err != nil {
+	if err := c.repo.DeleteMonitor(RequestProfileID(ctx), id); err != nil {
 		log.Printf("Failed to delete monitor %s: %v", id, err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor"})
 		return
 	}
 
 	// Verify the monitor was actually deleted
-	_, verifyErr := c.repo.GetMonitorByID(id)
+	_, verifyErr := c.repo.GetMonitorByID(RequestProfileID(ctx), id)
 	if verifyErr == nil {
 		log.Printf("WARNING: Monitor %s still exists after deletion attempt!", id)
 		// Try to delete again with a direct database query
@@ -182,6 +202,11 @@ func (c *MonitorController) DeleteMonitor(ctx *gin.Context) {
 		log.Printf("Verified monitor %s was successfully deleted", id)
 	}
 
+	// Drop the rules that overrode the profile's notification rules for the monitor
+	if err := repository.NewNotificationRuleRepository(config.DB).DeleteMonitorRules(RequestProfileID(ctx), id); err != nil {
+		log.Printf("Failed to delete notification rules of monitor %s: %v", id, err)
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Monitor deleted successfully", "id": id})
 }
 
@@ -193,6 +218,9 @@ func (c *MonitorController) UpdateMonitor(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !bodyProfileMatches(ctx, monitor.ProfileID) {
+		return
+	}
 
 	// Validate method
 	validMethods := map[string]bool{
@@ -203,27 +231,26 @@ func (c *MonitorController) UpdateMonitor(ctx *gin.Context) {
 		"HEAD":   true,
 	}
 
-	if !validMethods[monitor.Method] {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid HTTP method"})
+	// Validate monitor type, type-specific settings and body assertions
+	if err := c.validateMonitorSettings(&monitor); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate credential if provided
-	if monitor.CredentialID != "" {
-		// Check if credential exists and belongs to the active profile
-		credRepo := repository.NewCredentialsRepository(config.DB)
-		credential, err := credRepo.GetCredentialByID(monitor.CredentialID)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential"})
-			return
-		}
+	// Only HTTP monitors send requests, so the method is irrelevant for other types
+	if monitor.IsHTTPMonitor() && !validMethods[monitor.Method] {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid HTTP method"})
+		return
+	}
 
-		// Ensure the credential belongs to the active profile
-		activeProfile, err := repository.NewProfileRepository(config.DB).GetActiveProfile()
-		if err != nil || credential.ProfileID != activeProfile.ID {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Credential does not belong to active profile"})
-			return
-		}
+	// Validate the credentials of the monitor and its transaction steps
+	if err := validateMonitorCredentials(&monitor, RequestProfileID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateMonitorEscalationPolicy(&monitor, RequestProfileID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Validate headers format if provided
@@ -260,8 +287,8 @@ func (c *MonitorController) UpdateMonitor(ctx *gin.Context) {
 	// Set the ID from the URL parameter
 	monitor.ID = id
 
-	// Check if monitor exists and belongs to active profile
-	existingMonitor, err := c.repo.GetMonitorByID(id)
+	// Check if monitor exists and belongs to the request profile
+	existingMonitor, err := c.repo.GetMonitorByID(RequestProfileID(ctx), id)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
 		return
@@ -270,6 +297,29 @@ func (c *MonitorController) UpdateMonitor(ctx *gin.Context) {
 	// Preserve the profile ID from the existing monitor
 	monitor.ProfileID = existingMonitor.ProfileID
 
+	// Secrets sent back masked keep their stored values
+	if secrets.IsMasked(string(monitor.DBPassword)) {
+		monitor.DBPassword = existingMonitor.DBPassword
+	}
+	monitor.Headers = monitor.Headers.KeepMasked(existingMonitor.Headers)
+
+	// Preserve the check state so an edit doesn't reset the monitor's status;
+	// the scheduler picks up the new configuration on its next reload
+	monitor.Status = existingMonitor.Status
+	monitor.FailureCount = existingMonitor.FailureCount
+	monitor.ResponseCode = existingMonitor.ResponseCode
+	monitor.ResponseTime = existingMonitor.ResponseTime
+	monitor.LastChecked = existingMonitor.LastChecked
+	monitor.TLSCertExpiry = existingMonitor.TLSCertExpiry
+	monitor.TLSCertIssuer = existingMonitor.TLSCertIssuer
+	monitor.TLSCertSANs = existingMonitor.TLSCertSANs
+	monitor.TLSChainValid = existingMonitor.TLSChainValid
+	monitor.TLSChainError = existingMonitor.TLSChainError
+	monitor.TLSAlertLevel = existingMonitor.TLSAlertLevel
+	monitor.Degraded = existingMonitor.Degraded
+	monitor.CreatedAt = existingMonitor.CreatedAt
+	monitor.UpdatedAt = time.Now()
+
 	// Update the monitor
 	if err := c.repo.UpdateMonitor(&monitor); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update monitor"})
@@ -278,3 +328,136 @@ func (c *MonitorController) UpdateMonitor(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, monitor)
 }
+
+// validateMonitorSettings checks that a checker is registered for the monitor type
+// and that the settings required by that type are present and well formed
+func (c *MonitorController) validateMonitorSettings(monitor *types.Monitor) error {
+	if monitor.Type != "" {
+		if _, ok := c.checkers.Get(monitor.Type); !ok {
+			return fmt.Errorf("Invalid monitor type: %s", monitor.Type)
+		}
+	}
+
+	if err := services.ValidateSeverity(monitor.Severity); err != nil {
+		return fmt.Errorf("Invalid severity: %v", err)
+	}
+	if monitor.DegradedThreshold < 0 {
+		return fmt.Errorf("Degraded threshold cannot be negative")
+	}
+
+	if monitor.IsDatabaseMonitor() {
+		if monitor.DBHost == "" || monitor.DBPort == "" {
+			return fmt.Errorf("Database host and port are required for %s monitors", monitor.Type)
+		}
+		if _, err := strconv.Atoi(monitor.DBPort); err != nil {
+			return fmt.Errorf("Invalid database port: %s", monitor.DBPort)
+		}
+		if monitor.Type != types.MonitorTypeRedis && monitor.DBName == "" {
+			return fmt.Errorf("Database name is required for %s monitors", monitor.Type)
+		}
+		if monitor.DBQuery != "" && monitor.DBExpectedValue == "" {
+			return fmt.Errorf("Expected value is required when a test query is set")
+		}
+		return nil
+	}
+
+	if monitor.Port < 0 || monitor.Port > 65535 {
+		return fmt.Errorf("Invalid port: %d", monitor.Port)
+	}
+	if monitor.TLSWarningDays < 0 || monitor.TLSCriticalDays < 0 {
+		return fmt.Errorf("Certificate expiry thresholds cannot be negative")
+	}
+	if monitor.TLSWarningDays > 0 && monitor.TLSCriticalDays > monitor.TLSWarningDays {
+		return fmt.Errorf("Certificate critical threshold cannot exceed the warning threshold")
+	}
+	switch monitor.Type {
+	case types.MonitorTypeTLS:
+		if monitor.Hostname == "" {
+			return fmt.Errorf("Hostname is required for tls monitors")
+		}
+		return nil
+	case types.MonitorTypeTCP:
+		if monitor.Hostname == "" || monitor.Port == 0 {
+			return fmt.Errorf("Hostname and port are required for tcp monitors")
+		}
+		return nil
+	case types.MonitorTypeDNS:
+		if monitor.Hostname == "" {
+			return fmt.Errorf("Hostname is required for dns monitors")
+		}
+		if monitor.DNSResolver != "" {
+			if err := services.ValidateDNSResolver(monitor.DNSResolver); err != nil {
+				return fmt.Errorf("Invalid DNS resolver: %v", err)
+			}
+		}
+		validRecordType := false
+		for _, t := range services.DNSRecordTypes {
+			if monitor.GetDNSRecordType() == t {
+				validRecordType = true
+			}
+		}
+		if !validRecordType {
+			return fmt.Errorf("Invalid DNS record type: %s", monitor.DNSRecordType)
+		}
+		return nil
+	case types.MonitorTypeTransaction:
+		if err := services.ValidateTransactionSteps(monitor); err != nil {
+			return fmt.Errorf("Invalid transaction steps: %v", err)
+		}
+		return nil
+	}
+
+	if monitor.AcceptedStatusCodes != "" {
+		if err := services.ValidateStatusCodes(monitor.AcceptedStatusCodes); err != nil {
+			return fmt.Errorf("Invalid accepted status codes: %v", err)
+		}
+	}
+	if monitor.ExpectedStatus != 0 && (monitor.ExpectedStatus < 100 || monitor.ExpectedStatus > 599) {
+		return fmt.Errorf("Invalid expected status: %d", monitor.ExpectedStatus)
+	}
+	if monitor.MaxBodyBytes < 0 {
+		return fmt.Errorf("Max body bytes cannot be negative")
+	}
+	if err := services.ValidateBodyAssertions(monitor); err != nil {
+		return fmt.Errorf("Invalid body assertions: %v", err)
+	}
+	return nil
+}
+
+// validateMonitorEscalationPolicy checks that a monitor's escalation policy belongs to the profile
+func validateMonitorEscalationPolicy(monitor *types.Monitor, profileID string) error {
+	if monitor.EscalationPolicyID == "" {
+		return nil
+	}
+	if _, err := repository.NewEscalationRepository(config.DB).GetPolicyByID(profileID, monitor.EscalationPolicyID); err != nil {
+		return fmt.Errorf("Escalation policy not found")
+	}
+	return nil
+}
+
+// validateMonitorCredentials checks that the credentials used by a monitor, and
+// by the steps of a transaction monitor, exist and belong to the profile
+func validateMonitorCredentials(monitor *types.Monitor, profileID string) error {
+	credentialIDs := []string{monitor.CredentialID}
+	if monitor.Type == types.MonitorTypeTransaction {
+		steps, _ := monitor.GetTransactionSteps()
+		for _, step := range steps {
+			credentialIDs = append(credentialIDs, step.CredentialID)
+		}
+	}
+
+	credRepo := repository.NewCredentialsRepository(config.DB)
+	for _, id := range credentialIDs {
+		if id == "" {
+			continue
+		}
+		credential, err := credRepo.GetCredentialByID(id)
+		if err != nil {
+			return fmt.Errorf("Invalid credential")
+		}
+		if credential.ProfileID != profileID {
+			return fmt.Errorf("Credential does not belong to this profile")
+		}
+	}
+	return nil
+}
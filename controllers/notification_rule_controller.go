@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationRuleController struct {
+	repo     *repository.NotificationRuleRepository
+	profiles *repository.ProfileRepository
+	monitors *repository.MonitorRepository
+}
+
+func NewNotificationRuleController(repo *repository.NotificationRuleRepository, profiles *repository.ProfileRepository, monitors *repository.MonitorRepository) *NotificationRuleController {
+	return &NotificationRuleController{repo: repo, profiles: profiles, monitors: monitors}
+}
+
+// GetRules lists the notification rules of the request profile, only the
+// overrides of one monitor if the monitor_id query parameter is set
+func (c *NotificationRuleController) GetRules(ctx *gin.Context) {
+	rules, err := c.repo.GetRules(RequestProfileID(ctx), ctx.Query("monitor_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification rules"})
+		return
+	}
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// GetRule returns a notification rule
+func (c *NotificationRuleController) GetRule(ctx *gin.Context) {
+	rule, err := c.repo.GetRuleByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification rule not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// CreateRule creates a notification rule
+func (c *NotificationRuleController) CreateRule(ctx *gin.Context) {
+	var rule types.NotificationRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, rule.ProfileID) {
+		return
+	}
+	if err := c.validateRule(RequestProfileID(ctx), &rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification rule: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.CreateRule(RequestProfileID(ctx), &rule); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification rule"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule replaces a notification rule's conditions and methods
+func (c *NotificationRuleController) UpdateRule(ctx *gin.Context) {
+	existing, err := c.repo.GetRuleByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification rule not found"})
+		return
+	}
+
+	var rule types.NotificationRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, rule.ProfileID) {
+		return
+	}
+	rule.ID = existing.ID
+	rule.ProfileID = existing.ProfileID
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := c.validateRule(rule.ProfileID, &rule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification rule: " + err.Error()})
+		return
+	}
+
+	if err := c.repo.UpdateRule(&rule); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification rule"})
+		return
+	}
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule deletes a notification rule
+func (c *NotificationRuleController) DeleteRule(ctx *gin.Context) {
+	if err := c.repo.DeleteRule(RequestProfileID(ctx), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification rule not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Notification rule deleted successfully"})
+}
+
+// validateRule checks a rule's conditions, and that its monitor and methods
+// belong to the profile
+func (c *NotificationRuleController) validateRule(profileID string, rule *types.NotificationRule) error {
+	if err := services.ValidateNotificationRule(rule); err != nil {
+		return err
+	}
+	if rule.MonitorID != "" {
+		if _, err := c.monitors.GetMonitorByID(profileID, rule.MonitorID); err != nil {
+			return fmt.Errorf("monitor %s not found", rule.MonitorID)
+		}
+	}
+	for _, id := range types.SplitList(rule.MethodIDs) {
+		if _, err := c.profiles.GetNotificationMethod(profileID, id); err != nil {
+			return fmt.Errorf("notification method %s not found", id)
+		}
+	}
+	return nil
+}
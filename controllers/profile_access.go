@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"uptime-monitor/models"
+	"uptime-monitor/repository"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// profileRole returns the caller's role in a profile, or "" without access.
+// Admins own every profile. Service tokens act as editors, limited further by
+// their scopes and profile restriction.
+func profileRole(ctx *gin.Context, profiles *repository.ProfileRepository, profileID string) (string, error) {
+	if token := CurrentToken(ctx); token != nil && token.ProfileID != "" && token.ProfileID != profileID {
+		return "", nil
+	}
+	if user := CurrentUser(ctx); user != nil {
+		if user.IsAdmin {
+			return types.RoleOwner, nil
+		}
+		return profiles.GetMemberRole(profileID, user.ID)
+	}
+	if token := CurrentToken(ctx); token != nil && token.Kind == models.APITokenService {
+		if token.ProfileID == "" || token.ProfileID == profileID {
+			return types.RoleEditor, nil
+		}
+	}
+	return "", nil
+}
+
+// requireProfileRole writes an error response and returns false unless the
+// caller has at least the required role in the profile
+func requireProfileRole(ctx *gin.Context, profiles *repository.ProfileRepository, profileID, required string) bool {
+	role, err := profileRole(ctx, profiles, profileID)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check profile access"})
+		return false
+	}
+	if role == "" {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have access to this profile"})
+		return false
+	}
+	if !types.RoleAllows(role, required) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Your " + role + " role in this profile does not allow this action"})
+		return false
+	}
+	return true
+}
+
+// ProfileHeader names the profile a request works on
+const ProfileHeader = "X-Profile-ID"
+
+const contextProfileKey = "profile_id"
+
+// RequestProfileID returns the profile resolved for the request by RequireProfileAccess
+func RequestProfileID(ctx *gin.Context) string {
+	return ctx.GetString(contextProfileKey)
+}
+
+// RequireProfileAccess resolves the profile a request works on and checks the
+// caller's role in it, for requests that read or change profile data
+func (c *AuthController) RequireProfileAccess() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		required := requiredProfileRole(ctx.Request.Method, ctx.Request.URL.Path)
+		if required == "" {
+			ctx.Next()
+			return
+		}
+
+		profileID, status, message := c.resolveProfile(ctx)
+		if status != http.StatusOK {
+			ctx.AbortWithStatusJSON(status, gin.H{"error": message})
+			return
+		}
+		if requireProfileRole(ctx, c.profiles, profileID, required) {
+			ctx.Set(contextProfileKey, profileID)
+			ctx.Next()
+		}
+	}
+}
+
+// resolveProfile picks the request's profile from the profile path prefix, the
+// X-Profile-ID header, the profileId query parameter and the token's profile
+// restriction, which must all agree. A request that names no profile works on
+// the user's default profile, or else the first one they can access. On failure
+// it returns the response status and error message.
+func (c *AuthController) resolveProfile(ctx *gin.Context) (string, int, string) {
+	requested := ""
+	candidates := []string{pathProfileID(ctx.Request), ctx.GetHeader(ProfileHeader), ctx.Query("profileId")}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if requested != "" && candidate != requested {
+			return "", http.StatusBadRequest, "The request names more than one profile"
+		}
+		requested = candidate
+	}
+
+	if token := CurrentToken(ctx); token != nil && token.ProfileID != "" {
+		if requested != "" && requested != token.ProfileID {
+			return "", http.StatusForbidden, "API token is restricted to another profile"
+		}
+		return token.ProfileID, http.StatusOK, ""
+	}
+	if requested != "" {
+		return requested, http.StatusOK, ""
+	}
+
+	user := CurrentUser(ctx)
+	if user != nil && user.DefaultProfileID != "" {
+		if role, err := profileRole(ctx, c.profiles, user.DefaultProfileID); err == nil && role != "" {
+			return user.DefaultProfileID, http.StatusOK, ""
+		}
+	}
+
+	var profiles []types.Profile
+	var err error
+	if user != nil && !user.IsAdmin {
+		profiles, err = c.profiles.GetProfilesForUser(user.ID)
+	} else {
+		profiles, err = c.profiles.GetAllProfiles()
+	}
+	if err != nil {
+		return "", http.StatusInternalServerError, "Failed to load profiles"
+	}
+	if len(profiles) == 0 {
+		return "", http.StatusNotFound, "No profile found"
+	}
+	return profiles[0].ID, http.StatusOK, ""
+}
+
+// bodyProfileMatches writes an error response and returns false when a request
+// body names a profile other than the request's
+func bodyProfileMatches(ctx *gin.Context, profileID string) bool {
+	if profileID != "" && profileID != RequestProfileID(ctx) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "profile_id does not match the request profile"})
+		return false
+	}
+	return true
+}
+
+// profilePathResources maps the resources reachable under /api/profiles/:pid/
+// to the routes serving them
+var profilePathResources = map[string]string{
+	"monitors":      "/api/monitors",
+	"stats":         "/api/stats",
+	"incidents":     "/api/incidents",
+	"maintenance":   "/api/maintenance",
+	"status-pages":  "/api/status-pages",
+	"credentials":   "/api/credentials",
+	"notifications": "/api/notifications",
+	"smtp_settings": "/api/smtp_settings",
+	"logs":          "/logs",
+}
+
+type profilePathKey struct{}
+
+// WithProfilePath serves /api/profiles/:pid/<resource>/... through the routes of
+// <resource> with the profile taken from the path, e.g.
+// /api/profiles/:pid/monitors/:id is /api/monitors/:id in profile :pid.
+func WithProfilePath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/profiles/")
+		if ok {
+			segments := strings.SplitN(rest, "/", 3)
+			if len(segments) >= 2 && segments[0] != "" {
+				if route, ok := profilePathResources[segments[1]]; ok {
+					r = r.WithContext(context.WithValue(r.Context(), profilePathKey{}, segments[0]))
+					r.URL.Path = route
+					if len(segments) == 3 {
+						r.URL.Path += "/" + segments[2]
+					}
+					r.URL.RawPath = ""
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// pathProfileID returns the profile named in the request path, if any
+func pathProfileID(r *http.Request) string {
+	profileID, _ := r.Context().Value(profilePathKey{}).(string)
+	return profileID
+}
+
+// requiredProfileRole returns the role a request needs in the request profile,
+// or "" for requests that do not touch profile data (or check it themselves)
+func requiredProfileRole(method, path string) string {
+	read := method == http.MethodGet || method == http.MethodHead
+	hasPrefix := func(prefix string) bool {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+
+	switch {
+	case hasPrefix("/api/credentials"), hasPrefix("/api/notifications"),
+		hasPrefix("/api/smtp_settings"), path == "/save_smtp":
+		// Settings that hold secrets are not visible to viewers
+		return types.RoleEditor
+	case hasPrefix("/api/monitors"), hasPrefix("/api/stats"), hasPrefix("/api/incidents"),
+		hasPrefix("/api/maintenance"), hasPrefix("/api/status-pages"), hasPrefix("/logs"):
+		if read {
+			return types.RoleViewer
+		}
+		return types.RoleEditor
+	case path == "/api/profiles/active":
+		return types.RoleViewer
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"uptime-monitor/models"
+	"uptime-monitor/repository"
+	"uptime-monitor/secrets"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens a fresh database with the schema config.InitConfig migrates
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	keyring, err := secrets.NewKeyring(bytes.Repeat([]byte{1}, secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := secrets.Default()
+	secrets.SetDefault(keyring)
+	t.Cleanup(func() { secrets.SetDefault(previous) })
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&types.Profile{}, &types.ProfileMembership{}, &types.Monitor{}, &types.Log{},
+		&types.SMTPSettings{}, &types.NotificationSettings{}, &types.NotificationMethod{},
+		&types.NotificationDelivery{}, &types.NotificationAttempt{}, &types.NotificationRule{},
+		&types.EscalationPolicy{}, &types.EscalationLevel{}, &types.Escalation{},
+		&types.Incident{}, &types.IncidentEvent{}, &types.MaintenanceWindow{},
+		&types.StatusPage{}, &types.StatusPageComponent{},
+		&models.User{}, &models.Session{}, &models.APIToken{}, &models.APITokenUsage{},
+		&services.Credential{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestProfiles stores profiles p1 and p2, where alice owns p1, bob edits p1
+// and carol views p1 and owns p2
+func newTestProfiles(t *testing.T) *repository.ProfileRepository {
+	t.Helper()
+	db := newTestDB(t)
+	rows := []interface{}{
+		&types.Profile{ID: "p1", Name: "production"},
+		&types.Profile{ID: "p2", Name: "staging"},
+		&types.ProfileMembership{ID: "m1", ProfileID: "p1", UserID: "alice", Role: types.RoleOwner},
+		&types.ProfileMembership{ID: "m2", ProfileID: "p1", UserID: "bob", Role: types.RoleEditor},
+		&types.ProfileMembership{ID: "m3", ProfileID: "p1", UserID: "carol", Role: types.RoleViewer},
+		&types.ProfileMembership{ID: "m4", ProfileID: "p2", UserID: "carol", Role: types.RoleOwner},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repository.NewProfileRepository(db)
+}
+
+// caller authenticates requests the way RequireAuth does for a user or a token
+type caller struct {
+	user  *models.User
+	token *models.APIToken
+}
+
+func (c caller) authenticate(ctx *gin.Context) {
+	if c.user != nil {
+		ctx.Set(contextUserKey, c.user)
+	}
+	if c.token != nil {
+		ctx.Set(contextTokenKey, c.token)
+	}
+}
+
+func TestRequireProfileRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	profiles := newTestProfiles(t)
+
+	alice := caller{user: &models.User{ID: "alice"}}
+	bob := caller{user: &models.User{ID: "bob"}}
+	carol := caller{user: &models.User{ID: "carol"}}
+	admin := caller{user: &models.User{ID: "root", IsAdmin: true}}
+	service := caller{token: &models.APIToken{Kind: models.APITokenService}}
+	restricted := caller{token: &models.APIToken{Kind: models.APITokenService, ProfileID: "p2"}}
+	personal := caller{user: alice.user, token: &models.APIToken{Kind: models.APITokenPersonal, UserID: "alice", ProfileID: "p2"}}
+
+	tests := []struct {
+		name     string
+		caller   caller
+		profile  string
+		required string
+		want     int
+	}{
+		{"owner", alice, "p1", types.RoleOwner, http.StatusOK},
+		{"editor edits", bob, "p1", types.RoleEditor, http.StatusOK},
+		{"editor does not own", bob, "p1", types.RoleOwner, http.StatusForbidden},
+		{"viewer views", carol, "p1", types.RoleViewer, http.StatusOK},
+		{"viewer does not edit", carol, "p1", types.RoleEditor, http.StatusForbidden},
+		{"roles are per profile", carol, "p2", types.RoleOwner, http.StatusOK},
+		{"not a member", bob, "p2", types.RoleViewer, http.StatusForbidden},
+		{"admin owns every profile", admin, "p2", types.RoleOwner, http.StatusOK},
+		{"service token edits", service, "p1", types.RoleEditor, http.StatusOK},
+		{"service token does not own", service, "p1", types.RoleOwner, http.StatusForbidden},
+		{"token restricted to another profile", restricted, "p1", types.RoleViewer, http.StatusForbidden},
+		{"personal token restricted to another profile", personal, "p1", types.RoleViewer, http.StatusForbidden},
+		{"anonymous", caller{}, "p1", types.RoleViewer, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.caller.authenticate(ctx)
+
+			allowed := requireProfileRole(ctx, profiles, tt.profile, tt.required)
+			if allowed != (tt.want == http.StatusOK) || (!allowed && w.Code != tt.want) {
+				t.Errorf("requireProfileRole() = %v with status %d, want %d", allowed, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireProfileAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := &AuthController{profiles: newTestProfiles(t)}
+
+	// serve handles a request as the given user behind RequireProfileAccess and
+	// returns the status and the profile the request was resolved to
+	serve := func(user *models.User, method, target, profileHeader string) (int, string) {
+		router := gin.New()
+		router.Use(caller{user: user}.authenticate, c.RequireProfileAccess())
+		handler := func(ctx *gin.Context) { ctx.String(http.StatusOK, RequestProfileID(ctx)) }
+		router.GET("/api/monitors", handler)
+		router.POST("/api/monitors", handler)
+		router.GET("/api/credentials", handler)
+		router.GET("/api/users", handler)
+
+		req := httptest.NewRequest(method, target, nil)
+		if profileHeader != "" {
+			req.Header.Set(ProfileHeader, profileHeader)
+		}
+		w := httptest.NewRecorder()
+		WithProfilePath(router).ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	bob := &models.User{ID: "bob"}
+	carol := &models.User{ID: "carol", DefaultProfileID: "p2"}
+	dave := &models.User{ID: "dave"}
+
+	tests := []struct {
+		name        string
+		user        *models.User
+		method      string
+		target      string
+		header      string // X-Profile-ID
+		wantStatus  int
+		wantProfile string
+	}{
+		{"viewer reads monitors", carol, http.MethodGet, "/api/monitors", "p1", http.StatusOK, "p1"},
+		{"viewer cannot change monitors", carol, http.MethodPost, "/api/monitors", "p1", http.StatusForbidden, ""},
+		{"viewer cannot read credentials", carol, http.MethodGet, "/api/credentials", "p1", http.StatusForbidden, ""},
+		{"editor changes monitors", bob, http.MethodPost, "/api/monitors", "p1", http.StatusOK, "p1"},
+		{"editor reads credentials", bob, http.MethodGet, "/api/credentials", "p1", http.StatusOK, "p1"},
+		{"profile from the query", bob, http.MethodGet, "/api/monitors?profileId=p1", "", http.StatusOK, "p1"},
+		{"profile from the path", carol, http.MethodGet, "/api/profiles/p2/monitors", "", http.StatusOK, "p2"},
+		{"profile the user is not a member of", bob, http.MethodGet, "/api/profiles/p2/monitors", "", http.StatusForbidden, ""},
+		{"conflicting profiles", carol, http.MethodGet, "/api/monitors?profileId=p2", "p1", http.StatusBadRequest, ""},
+		{"default profile", carol, http.MethodGet, "/api/monitors", "", http.StatusOK, "p2"},
+		{"first profile of the user", bob, http.MethodGet, "/api/monitors", "", http.StatusOK, "p1"},
+		{"user without profiles", dave, http.MethodGet, "/api/monitors", "", http.StatusNotFound, ""},
+		{"routes outside profiles", dave, http.MethodGet, "/api/users", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, profile := serve(tt.user, tt.method, tt.target, tt.header)
+			if status != tt.wantStatus {
+				t.Fatalf("%s %s = %d, want %d", tt.method, tt.target, status, tt.wantStatus)
+			}
+			if status == http.StatusOK && profile != tt.wantProfile {
+				t.Errorf("%s %s resolved profile %q, want %q", tt.method, tt.target, profile, tt.wantProfile)
+			}
+		})
+	}
+}
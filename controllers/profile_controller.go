@@ -1,22 +1,28 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"uptime-monitor/repository"
+	"uptime-monitor/services"
 	"uptime-monitor/types"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type ProfileController struct {
-	repo *repository.ProfileRepository
+	repo      *repository.ProfileRepository
+	users     *repository.UserRepository
+	notifiers *services.NotifierRegistry
 }
 
-func NewProfileController(repo *repository.ProfileRepository) *ProfileController {
-	return &ProfileController{repo: repo}
+func NewProfileController(repo *repository.ProfileRepository, users *repository.UserRepository, notifiers *services.NotifierRegistry) *ProfileController {
+	return &ProfileController{repo: repo, users: users, notifiers: notifiers}
 }
 
+// CreateProfile creates a profile owned by the caller
 func (c *ProfileController) CreateProfile(ctx *gin.Context) {
 	var profile types.Profile
 	if err := ctx.ShouldBindJSON(&profile); err != nil {
@@ -24,7 +30,12 @@ func (c *ProfileController) CreateProfile(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.repo.CreateProfile(&profile); err != nil {
+	user := CurrentUser(ctx)
+	if user == nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only users can create profiles"})
+		return
+	}
+	if err := c.repo.CreateProfileWithOwner(&profile, user.ID); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
 		return
 	}
@@ -39,16 +50,13 @@ func (c *ProfileController) CreateNotificationSettings(ctx *gin.Context) {
 		return
 	}
 
-	// Get active profile
-	activeProfile, err := c.repo.GetActiveProfile()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active profile"})
+	if !bodyProfileMatches(ctx, settings.ProfileID) {
 		return
 	}
 
 	// Set profile ID for the settings
 	settings.ID = uuid.New().String()
-	settings.ProfileID = activeProfile.ID
+	settings.ProfileID = RequestProfileID(ctx)
 
 	if err := c.repo.CreateNotificationSettings(&settings); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification settings"})
@@ -58,26 +66,58 @@ func (c *ProfileController) CreateNotificationSettings(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, settings)
 }
 
+// GetAllProfiles lists the profiles the caller can access, marking the one
+// their requests use when they name no profile as active
 func (c *ProfileController) GetAllProfiles(ctx *gin.Context) {
-	profiles, err := c.repo.GetAllProfiles()
+	var profiles []types.Profile
+	var err error
+	user := CurrentUser(ctx)
+	if user != nil && !user.IsAdmin {
+		profiles, err = c.repo.GetProfilesForUser(user.ID)
+	} else if token := CurrentToken(ctx); token != nil && token.ProfileID != "" {
+		var profile *types.Profile
+		if profile, err = c.repo.GetProfileByID(token.ProfileID); err == nil {
+			profiles = []types.Profile{*profile}
+		}
+	} else {
+		profiles, err = c.repo.GetAllProfiles()
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
 		return
 	}
+
+	active := -1
+	for i := range profiles {
+		if user != nil && profiles[i].ID == user.DefaultProfileID {
+			active = i
+		}
+	}
+	if active < 0 && len(profiles) > 0 {
+		active = 0
+	}
+	if active >= 0 {
+		profiles[active].IsActive = true
+	}
 	ctx.JSON(http.StatusOK, profiles)
 }
 
+// GetActiveProfile returns the profile the request works on
 func (c *ProfileController) GetActiveProfile(ctx *gin.Context) {
-	profile, err := c.repo.GetActiveProfile()
+	profile, err := c.repo.GetProfileByID(RequestProfileID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "No active profile found"})
 		return
 	}
+	profile.IsActive = true
 	ctx.JSON(http.StatusOK, profile)
 }
 
 func (c *ProfileController) GetProfileByID(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleViewer) {
+		return
+	}
 	profile, err := c.repo.GetProfileByID(id)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
@@ -88,6 +128,9 @@ func (c *ProfileController) GetProfileByID(ctx *gin.Context) {
 
 func (c *ProfileController) UpdateProfile(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleOwner) {
+		return
+	}
 	var profile types.Profile
 	if err := ctx.ShouldBindJSON(&profile); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -105,8 +148,12 @@ func (c *ProfileController) UpdateProfile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, profile)
 }
 
+// DeleteProfile deletes a profile with its monitors; only owners may do this
 func (c *ProfileController) DeleteProfile(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleOwner) {
+		return
+	}
 
 	if err := c.repo.DeleteProfile(id); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile"})
@@ -116,10 +163,20 @@ func (c *ProfileController) DeleteProfile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Profile deleted successfully", "id": id})
 }
 
+// SetActiveProfile makes a profile the caller's default, used by requests that
+// name no profile
 func (c *ProfileController) SetActiveProfile(ctx *gin.Context) {
 	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleViewer) {
+		return
+	}
 
-	if err := c.repo.SetActiveProfile(id); err != nil {
+	user := CurrentUser(ctx)
+	if user == nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only users have a default profile"})
+		return
+	}
+	if err := c.users.SetDefaultProfile(user.ID, id); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set active profile"})
 		return
 	}
@@ -128,14 +185,7 @@ func (c *ProfileController) SetActiveProfile(ctx *gin.Context) {
 }
 
 func (c *ProfileController) GetNotificationMethods(ctx *gin.Context) {
-	// Get active profile
-	activeProfile, err := c.repo.GetActiveProfile()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active profile"})
-		return
-	}
-
-	methods, err := c.repo.GetNotificationMethods(activeProfile.ID)
+	methods, err := c.repo.GetNotificationMethods(RequestProfileID(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification methods"})
 		return
@@ -151,16 +201,17 @@ func (c *ProfileController) CreateNotificationMethod(ctx *gin.Context) {
 		return
 	}
 
-	// Get active profile
-	activeProfile, err := c.repo.GetActiveProfile()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active profile"})
+	if !bodyProfileMatches(ctx, method.ProfileID) {
+		return
+	}
+	if err := c.notifiers.Validate(&method); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Set profile ID and generate ID
 	method.ID = uuid.New().String()
-	method.ProfileID = activeProfile.ID
+	method.ProfileID = RequestProfileID(ctx)
 
 	if err := c.repo.CreateNotificationMethod(&method); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification method"})
@@ -178,24 +229,161 @@ func (c *ProfileController) UpdateNotificationMethod(ctx *gin.Context) {
 		return
 	}
 
+	if !bodyProfileMatches(ctx, method.ProfileID) {
+		return
+	}
+	if err := c.notifiers.Validate(&method); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Set the ID from the URL parameter
 	method.ID = id
+	method.ProfileID = RequestProfileID(ctx)
 
 	if err := c.repo.UpdateNotificationMethod(&method); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification method"})
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification method not found"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, method)
 }
 
+// templatePreviewRequest is the body of the template preview endpoint
+type templatePreviewRequest struct {
+	Type            string `json:"type" binding:"required"`
+	Status          string `json:"status"` // Status of the sample event, down if empty
+	SubjectTemplate string `json:"subject_template"`
+	BodyTemplate    string `json:"body_template"`
+	HTMLTemplate    string `json:"html_template"`
+}
+
+// GetDefaultTemplates returns the templates a notification method type uses
+// when a method doesn't set its own
+func (c *ProfileController) GetDefaultTemplates(ctx *gin.Context) {
+	methodType := ctx.Param("type")
+	if _, ok := c.notifiers.Get(methodType); !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Unsupported notification type"})
+		return
+	}
+	ctx.JSON(http.StatusOK, services.DefaultTemplates(methodType))
+}
+
+// PreviewTemplates renders notification templates against a sample event.
+// Templates left empty are previewed with the type's defaults.
+func (c *ProfileController) PreviewTemplates(ctx *gin.Context) {
+	var req templatePreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := c.notifiers.Get(req.Type); !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported notification type"})
+		return
+	}
+	if req.Status == "" {
+		req.Status = "down"
+	}
+
+	method := &types.NotificationMethod{
+		Type:            req.Type,
+		SubjectTemplate: req.SubjectTemplate,
+		BodyTemplate:    req.BodyTemplate,
+		HTMLTemplate:    req.HTMLTemplate,
+	}
+	if err := services.ValidateTemplates(method); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rendered, err := services.RenderNotification(method, services.SampleNotification(req.Status))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"subject": rendered.Subject,
+		"body":    rendered.Body,
+		"html":    rendered.HTML,
+	})
+}
+
 func (c *ProfileController) DeleteNotificationMethod(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	if err := c.repo.DeleteNotificationMethod(id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification method"})
+	if err := c.repo.DeleteNotificationMethod(RequestProfileID(ctx), id); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Notification method not found"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Notification method deleted successfully", "id": id})
 }
+
+// GetMembers lists the members of a profile
+func (c *ProfileController) GetMembers(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleViewer) {
+		return
+	}
+
+	members, err := c.repo.GetMembers(id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	ctx.JSON(http.StatusOK, members)
+}
+
+// SetMember adds a user to a profile or changes their role
+func (c *ProfileController) SetMember(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleOwner) {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil || !types.IsValidRole(req.Role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Role must be owner, editor or viewer"})
+		return
+	}
+	user, err := c.users.GetUserByID(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	membership, err := c.repo.SetMemberRole(id, user.ID, req.Role)
+	if err != nil {
+		c.membershipError(ctx, err)
+		return
+	}
+	membership.Username = user.Username
+	ctx.JSON(http.StatusOK, membership)
+}
+
+// RemoveMember removes a user from a profile
+func (c *ProfileController) RemoveMember(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !requireProfileRole(ctx, c.repo, id, types.RoleOwner) {
+		return
+	}
+
+	if err := c.repo.RemoveMember(id, ctx.Param("user_id")); err != nil {
+		c.membershipError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+func (c *ProfileController) membershipError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrLastOwner):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update members"})
+	}
+}
@@ -21,7 +21,7 @@ func NewSMTPController(repo *repository.SMTPRepository) *SMTPController {
 
 // GetSMTPSettings retrieves all SMTP settings
 func (c *SMTPController) GetSMTPSettings(ctx *gin.Context) {
-	settings, err := c.repo.GetAllSMTPSettings()
+	settings, err := c.repo.GetAllSMTPSettings(RequestProfileID(ctx))
 	if err != nil {
 		log.Printf("Error fetching SMTP settings: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SMTP settings"})
@@ -38,6 +38,9 @@ func (c *SMTPController) UpdateSMTPSettings(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !bodyProfileMatches(ctx, settings.ProfileID) {
+		return
+	}
 
 	// Validate required fields
 	if settings.SMTPHost == "" || settings.SMTPPort == "" || settings.SMTPEmail == "" ||
@@ -54,7 +57,7 @@ func (c *SMTPController) UpdateSMTPSettings(ctx *gin.Context) {
 	log.Printf("Creating new SMTP settings: ID=%s, Host=%s, Email=%s",
 		settings.ID, settings.SMTPHost, settings.SMTPEmail)
 
-	if err := c.repo.UpdateSMTPSettings(&settings); err != nil {
+	if err := c.repo.UpdateSMTPSettings(RequestProfileID(ctx), &settings); err != nil {
 		log.Printf("Error saving SMTP settings: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save SMTP settings"})
 		return
@@ -78,7 +81,7 @@ func (c *SMTPController) DeleteSMTPSettings(ctx *gin.Context) {
 	}
 
 	log.Printf("Deleting SMTP settings with ID: %s", id)
-	if err := c.repo.DeleteSMTPSettingsByID(id); err != nil {
+	if err := c.repo.DeleteSMTPSettingsByID(RequestProfileID(ctx), id); err != nil {
 		log.Printf("Error deleting SMTP settings: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SMTP settings"})
 		return
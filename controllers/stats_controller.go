@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsController struct {
+	monitorRepo     *repository.MonitorRepository
+	logRepo         *repository.LogRepository
+	maintenanceRepo *repository.MaintenanceRepository
+}
+
+func NewStatsController(monitorRepo *repository.MonitorRepository, logRepo *repository.LogRepository, maintenanceRepo *repository.MaintenanceRepository) *StatsController {
+	return &StatsController{monitorRepo: monitorRepo, logRepo: logRepo, maintenanceRepo: maintenanceRepo}
+}
+
+// GetMonitorStats returns uptime, incident and latency statistics for a monitor.
+// The window query parameter selects 24h (default), 7d, 30d, month (with
+// month=YYYY-MM) or custom (with from and to in RFC 3339). format=csv returns
+// the stats as CSV.
+func (c *StatsController) GetMonitorStats(ctx *gin.Context) {
+	from, to, err := statsWindow(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	monitor, err := c.monitorRepo.GetMonitorByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
+	stats, err := c.monitorStats(monitor, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute stats"})
+		return
+	}
+
+	if ctx.Query("format") == "csv" {
+		writeStatsCSV(ctx, fmt.Sprintf("stats-%s", monitor.ID), []services.UptimeStats{stats})
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// GetSummary returns the stats of every monitor in the request profile. It takes
+// the same window and format parameters as GetMonitorStats.
+func (c *StatsController) GetSummary(ctx *gin.Context) {
+	from, to, err := statsWindow(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	monitors, err := c.monitorRepo.GetAllMonitors(RequestProfileID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch monitors"})
+		return
+	}
+
+	all := make([]services.UptimeStats, 0, len(monitors))
+	for i := range monitors {
+		stats, err := c.monitorStats(&monitors[i], from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute stats"})
+			return
+		}
+		all = append(all, stats)
+	}
+
+	if ctx.Query("format") == "csv" {
+		writeStatsCSV(ctx, "stats-summary", all)
+		return
+	}
+	ctx.JSON(http.StatusOK, services.SummarizeStats(from, to, all))
+}
+
+// monitorStats loads a monitor's history for the window and computes its stats
+func (c *StatsController) monitorStats(monitor *types.Monitor, from, to time.Time) (services.UptimeStats, error) {
+	logs, err := c.logRepo.GetLogsInRange([]string{monitor.ID}, from, to, 0)
+	if err != nil {
+		return services.UptimeStats{}, err
+	}
+
+	// The last earlier check gives the status at the start of the window
+	previous, err := c.logRepo.GetLastLogBefore(monitor.ID, from)
+	if err != nil {
+		return services.UptimeStats{}, err
+	}
+	if previous != nil {
+		logs = append([]types.Log{*previous}, logs...)
+	}
+
+	// Maintenance time does not count against uptime
+	windows, err := c.maintenanceRepo.GetWindowsForProfile(monitor.ProfileID, true)
+	if err != nil {
+		return services.UptimeStats{}, err
+	}
+	excluded := services.MaintenanceExclusions(windows, monitor, from, to)
+
+	return services.ComputeUptimeStats(monitor, logs, from, to, excluded), nil
+}
+
+// statsWindow reads the stats window from the query parameters
+func statsWindow(ctx *gin.Context) (time.Time, time.Time, error) {
+	return services.ParseStatsWindow(ctx.Query("window"), ctx.Query("month"), ctx.Query("from"), ctx.Query("to"), time.Now())
+}
+
+// writeStatsCSV writes stats rows as a CSV attachment
+func writeStatsCSV(ctx *gin.Context, name string, stats []services.UptimeStats) {
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	ctx.Status(http.StatusOK)
+
+	w := csv.NewWriter(ctx.Writer)
+	w.Write(services.StatsCSVHeader)
+	for _, s := range stats {
+		w.Write(s.CSVRecord())
+	}
+	w.Flush()
+}
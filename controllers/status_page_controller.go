@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusPageCacheTTL is how long a rendered public status page is reused
+const statusPageCacheTTL = time.Minute
+
+type StatusPageController struct {
+	repo            *repository.StatusPageRepository
+	monitorRepo     *repository.MonitorRepository
+	logRepo         *repository.LogRepository
+	incidentRepo    *repository.IncidentRepository
+	maintenanceRepo *repository.MaintenanceRepository
+
+	cacheMu sync.Mutex
+	cache   map[string]cachedStatusPage
+}
+
+type cachedStatusPage struct {
+	page    *services.PublicStatusPage
+	expires time.Time
+}
+
+func NewStatusPageController(
+	repo *repository.StatusPageRepository,
+	monitorRepo *repository.MonitorRepository,
+	logRepo *repository.LogRepository,
+	incidentRepo *repository.IncidentRepository,
+	maintenanceRepo *repository.MaintenanceRepository,
+) *StatusPageController {
+	return &StatusPageController{
+		repo:            repo,
+		monitorRepo:     monitorRepo,
+		logRepo:         logRepo,
+		incidentRepo:    incidentRepo,
+		maintenanceRepo: maintenanceRepo,
+		cache:           make(map[string]cachedStatusPage),
+	}
+}
+
+// ShowStatusPage serves a published status page to the public, as HTML or, when
+// requested with format=json or an Accept header preferring it, as JSON
+func (c *StatusPageController) ShowStatusPage(ctx *gin.Context) {
+	wantJSON := ctx.Query("format") == "json" ||
+		ctx.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+
+	page, err := c.publicPage(ctx.Param("slug"))
+	if err != nil {
+		if wantJSON {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		} else {
+			ctx.String(http.StatusNotFound, "Status page not found")
+		}
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=60")
+	if wantJSON {
+		ctx.JSON(http.StatusOK, page)
+		return
+	}
+	ctx.HTML(http.StatusOK, "status_page.html", page)
+}
+
+// publicPage returns the public view of a published status page, from the cache
+// when it is fresh
+func (c *StatusPageController) publicPage(slug string) (*services.PublicStatusPage, error) {
+	c.cacheMu.Lock()
+	cached, ok := c.cache[slug]
+	c.cacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.page, nil
+	}
+
+	statusPage, err := c.repo.GetPublishedStatusPage(slug)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.loadStatusPageData(statusPage)
+	if err != nil {
+		return nil, err
+	}
+	page := services.BuildPublicStatusPage(data, time.Now())
+
+	c.cacheMu.Lock()
+	c.cache[slug] = cachedStatusPage{page: page, expires: time.Now().Add(statusPageCacheTTL)}
+	c.cacheMu.Unlock()
+	return page, nil
+}
+
+// loadStatusPageData loads the monitors, check history, incidents and maintenance
+// windows a status page is built from
+func (c *StatusPageController) loadStatusPageData(page *types.StatusPage) (services.StatusPageData, error) {
+	data := services.StatusPageData{
+		Page:     page,
+		Monitors: make(map[string]*types.Monitor),
+		Logs:     make(map[string][]types.Log),
+	}
+
+	var ids []string
+	for _, component := range page.Components {
+		ids = append(ids, types.SplitList(component.MonitorIDs)...)
+	}
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	monitors, err := c.monitorRepo.GetProfileMonitorsByIDs(page.ProfileID, ids)
+	if err != nil {
+		return data, err
+	}
+	ids = ids[:0]
+	for i := range monitors {
+		data.Monitors[monitors[i].ID] = &monitors[i]
+		ids = append(ids, monitors[i].ID)
+	}
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(services.StatusPageDays - 1))
+	logs, err := c.logRepo.GetLogsInRange(ids, from, time.Time{}, 0)
+	if err != nil {
+		return data, err
+	}
+	for _, id := range ids {
+		previous, err := c.logRepo.GetLastLogBefore(id, from)
+		if err != nil {
+			return data, err
+		}
+		if previous != nil {
+			data.Logs[id] = append(data.Logs[id], *previous)
+		}
+	}
+	for _, l := range logs {
+		data.Logs[l.MonitorID] = append(data.Logs[l.MonitorID], l)
+	}
+
+	if data.Incidents, err = c.incidentRepo.GetUnresolvedIncidents(ids); err != nil {
+		return data, err
+	}
+	if data.Windows, err = c.maintenanceRepo.GetWindowsForProfile(page.ProfileID, true); err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+// GetStatusPages lists the status pages of the request profile
+func (c *StatusPageController) GetStatusPages(ctx *gin.Context) {
+	pages, err := c.repo.GetStatusPages(RequestProfileID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status pages"})
+		return
+	}
+	ctx.JSON(http.StatusOK, pages)
+}
+
+// GetStatusPage returns a status page of the request profile with its components
+func (c *StatusPageController) GetStatusPage(ctx *gin.Context) {
+	page, err := c.repo.GetStatusPageByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// CreateStatusPage creates a status page with its components
+func (c *StatusPageController) CreateStatusPage(ctx *gin.Context) {
+	var page types.StatusPage
+	if err := ctx.ShouldBindJSON(&page); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, page.ProfileID) {
+		return
+	}
+	if !c.validateStatusPage(ctx, &page) {
+		return
+	}
+
+	if err := c.repo.CreateStatusPage(RequestProfileID(ctx), &page); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, page)
+}
+
+// UpdateStatusPage replaces a status page's settings and components
+func (c *StatusPageController) UpdateStatusPage(ctx *gin.Context) {
+	existing, err := c.repo.GetStatusPageByID(RequestProfileID(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		return
+	}
+
+	var page types.StatusPage
+	if err := ctx.ShouldBindJSON(&page); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bodyProfileMatches(ctx, page.ProfileID) {
+		return
+	}
+	page.ID = existing.ID
+	page.ProfileID = existing.ProfileID
+	page.CreatedAt = existing.CreatedAt
+	if !c.validateStatusPage(ctx, &page) {
+		return
+	}
+
+	if err := c.repo.UpdateStatusPage(&page); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status page"})
+		return
+	}
+	c.invalidateCache()
+	ctx.JSON(http.StatusOK, page)
+}
+
+// DeleteStatusPage deletes a status page
+func (c *StatusPageController) DeleteStatusPage(ctx *gin.Context) {
+	if err := c.repo.DeleteStatusPage(RequestProfileID(ctx), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		return
+	}
+	c.invalidateCache()
+	ctx.JSON(http.StatusOK, gin.H{"message": "Status page deleted successfully"})
+}
+
+// validateStatusPage checks the page settings, that its slug is free and that
+// its components only reference monitors of the request profile
+func (c *StatusPageController) validateStatusPage(ctx *gin.Context, page *types.StatusPage) bool {
+	if err := services.ValidateStatusPage(page); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status page: " + err.Error()})
+		return false
+	}
+
+	taken, err := c.repo.SlugTaken(page.Slug, page.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check slug"})
+		return false
+	}
+	if taken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Slug is already in use"})
+		return false
+	}
+
+	for _, component := range page.Components {
+		for _, id := range types.SplitList(component.MonitorIDs) {
+			if _, err := c.monitorRepo.GetMonitorByID(RequestProfileID(ctx), id); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown monitor in component " + component.Name + ": " + id})
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *StatusPageController) invalidateCache() {
+	c.cacheMu.Lock()
+	c.cache = make(map[string]cachedStatusPage)
+	c.cacheMu.Unlock()
+}
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"net/http"
-	"strconv"
 	"uptime-monitor/models"
 	"uptime-monitor/repository"
 
@@ -10,40 +9,74 @@ import (
 )
 
 type UserController struct {
-	repo repository.UserRepository
+	repo *repository.UserRepository
 }
 
-func NewUserController(repo repository.UserRepository) *UserController {
+func NewUserController(repo *repository.UserRepository) *UserController {
 	return &UserController{repo: repo}
 }
 
+// GetUsers lists all user accounts
+func (c *UserController) GetUsers(ctx *gin.Context) {
+	users, err := c.repo.GetUsers()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
+
+// CreateUser creates a user account with the given password
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if user.Username == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
 
-	if err := c.repo.CreateUser(user); err != nil {
+	taken, err := c.repo.UsernameTaken(user.Username)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
+	if taken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Username is already in use"})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "User created successfully"})
+	if err := c.repo.CreateUser(&user); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create user: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, user)
 }
 
+// GetUserByID returns a user account
 func (c *UserController) GetUserByID(ctx *gin.Context) {
-	id := ctx.Param("id")
-	idInt, err := strconv.Atoi(id)
+	user, err := c.repo.GetUserByID(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-	user, err := c.repo.GetUserByID(idInt)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	ctx.JSON(http.StatusOK, user)
+}
+
+// DeleteUser deletes a user account. Admins cannot delete themselves, which
+// also keeps at least one admin around.
+func (c *UserController) DeleteUser(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if current := CurrentUser(ctx); current != nil && current.ID == id {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	if err := c.repo.DeleteUser(id); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
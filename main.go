@@ -3,9 +3,12 @@ package main
 
 import (
 	"log"
+	"net/http"
+	"os"
 	"strings"
 	"time"
 	"uptime-monitor/config"          // Configuration management
+	"uptime-monitor/controllers"     // Request handlers
 	"uptime-monitor/repository"      // Database operations
 	"uptime-monitor/routes"          // HTTP routing
 	"uptime-monitor/services"        // Application services
@@ -29,20 +32,49 @@ func main() {
 
 	// Initialize repositories for different data types
 	log.Println("Initializing repositories...")
-	monitorRepo := repository.NewMonitorRepository(config.DB) // Handles website monitoring data
-	logRepo := repository.NewLogRepository(config.DB)         // Handles monitoring logs
-	smtpRepo := repository.NewSMTPRepository(config.DB)       // Handles email notification settings
-	profileRepo := repository.NewProfileRepository(config.DB) // Handles user profiles
+	monitorRepo := repository.NewMonitorRepository(config.DB)         // Handles website monitoring data
+	logRepo := repository.NewLogRepository(config.DB)                 // Handles monitoring logs
+	smtpRepo := repository.NewSMTPRepository(config.DB)               // Handles email notification settings
+	profileRepo := repository.NewProfileRepository(config.DB)         // Handles user profiles
+	incidentRepo := repository.NewIncidentRepository(config.DB)       // Handles outage incidents
+	maintenanceRepo := repository.NewMaintenanceRepository(config.DB) // Handles maintenance windows
+	statusPageRepo := repository.NewStatusPageRepository(config.DB)   // Handles public status pages
+	userRepo := repository.NewUserRepository(config.DB)               // Handles user accounts and sessions
+	apiTokenRepo := repository.NewAPITokenRepository(config.DB)       // Handles API tokens for automation
+	deliveryRepo := repository.NewDeliveryRepository(config.DB)       // Handles the notification outbox and delivery log
+	ruleRepo := repository.NewNotificationRuleRepository(config.DB)   // Handles notification routing rules
+	escalationRepo := repository.NewEscalationRepository(config.DB)   // Handles escalation policies and their progress
 	log.Println("Repositories initialized successfully")
 
+	// Create the first admin account on a fresh install
+	generatedPassword, err := userRepo.EnsureAdminUser(os.Getenv("HEIMDALL_ADMIN_USERNAME"), os.Getenv("HEIMDALL_ADMIN_PASSWORD"), saveAdminPassword)
+	if err != nil {
+		log.Fatal("❌ Failed to create admin user: ", err)
+	}
+	if generatedPassword != "" {
+		log.Printf("🔑 Created admin user with a generated password, written to %s. Change it after signing in and delete the file.", adminPasswordFile)
+	}
+
 	// Initialize services
 	log.Println("Initializing services...")
 	services := services.NewServices(config.DB)
 	log.Println("Services initialized successfully")
 
+	// Start delivering queued notifications
+	log.Println("Starting notification dispatcher...")
+	dispatcher := scheduler.NewNotificationDispatcher(services.Notifiers, deliveryRepo, profileRepo)
+	dispatcher.Start()
+	log.Println("Notification dispatcher started")
+
+	// Start escalating unacknowledged outages, resuming the escalations stored before a restart
+	log.Println("Starting escalation worker...")
+	escalationWorker := scheduler.NewEscalationWorker(escalationRepo, incidentRepo, monitorRepo, profileRepo, dispatcher)
+	escalationWorker.Start()
+	log.Println("Escalation worker started")
+
 	// Start the background scheduler for monitoring websites
 	log.Println("Starting background scheduler...")
-	scheduler := scheduler.NewScheduler(services.Credentials, monitorRepo, logRepo)
+	scheduler := scheduler.NewScheduler(services.Checkers, dispatcher, escalationWorker, monitorRepo, logRepo, incidentRepo, maintenanceRepo, profileRepo, ruleRepo)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -70,20 +102,46 @@ func main() {
 	log.Println("Initializing web server...")
 	router := gin.Default()
 
-	// Enable CORS (Cross-Origin Resource Sharing) for web access
+	// Only take the client address from X-Forwarded-For and X-Real-IP when the
+	// request comes through one of the proxies listed in HEIMDALL_TRUSTED_PROXIES
+	// (comma separated addresses or CIDRs). The address limits failed logins,
+	// so by default the connection's own address is used.
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("HEIMDALL_TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid HEIMDALL_TRUSTED_PROXIES: ", err)
+	}
+
+	// Enable CORS (Cross-Origin Resource Sharing) for the origins listed in
+	// HEIMDALL_CORS_ORIGINS (comma separated). The UI itself is same-origin and
+	// needs no CORS, so by default no other origin may call the API.
+	allowedOrigins := make(map[string]bool)
+	for _, origin := range strings.Split(os.Getenv("HEIMDALL_CORS_ORIGINS"), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowedOrigins[origin] = true
+		}
+	}
 	router.Use(func(c *gin.Context) {
-		// Allow requests from any origin
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		// Allow specific HTTP methods
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		// Allow specific headers
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Profile-ID")
-		// Allow exposing specific headers to client
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
-		// Allow credentials (cookies, authorization headers)
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		// Cache preflight requests for 24 hours
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
+		origin := c.GetHeader("Origin")
+		c.Writer.Header().Add("Vary", "Origin")
+		if origin != "" && allowedOrigins[origin] {
+			// Only echo back origins that are explicitly allowed
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			// Allow specific HTTP methods
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			// Allow specific headers
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Profile-ID")
+			// Allow exposing specific headers to client
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+			// Allow credentials (cookies, authorization headers)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			// Cache preflight requests for 24 hours
+			c.Writer.Header().Set("Access-Control-Max-Age", "86400")
+		}
 
 		// Handle preflight requests
 		if c.Request.Method == "OPTIONS" {
@@ -111,12 +169,22 @@ func main() {
 
 	// Set up all application routes with their respective repositories
 	log.Println("Setting up routes...")
-	routes.SetupRoutes(router, monitorRepo, logRepo, smtpRepo, profileRepo, services.Credentials)
+	routes.SetupRoutes(router, monitorRepo, logRepo, smtpRepo, profileRepo, services.Credentials, services.Checkers, services.Notifiers, incidentRepo, maintenanceRepo, statusPageRepo, userRepo, apiTokenRepo, deliveryRepo, ruleRepo, escalationRepo)
 	log.Println("Routes set up successfully")
 
 	// Start the HTTP server on port 8080
 	log.Println("Starting HTTP server on port 8080...")
-	if err := router.Run(":8080"); err != nil {
+	if err := http.ListenAndServe(":8080", controllers.WithProfilePath(router)); err != nil {
 		log.Fatal("❌ Error starting server: ", err)
 	}
 }
+
+// adminPasswordFile receives the password generated for the first admin, next
+// to the database, as logs are often collected and kept.
+const adminPasswordFile = "admin_password.txt"
+
+// saveAdminPassword writes a generated admin password to adminPasswordFile,
+// readable by the owner only
+func saveAdminPassword(password string) error {
+	return os.WriteFile(adminPasswordFile, []byte(password+"\n"), 0o600)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API token kinds. Personal tokens act as the user that created them; service
+// tokens belong to no user and are managed by admins.
+const (
+	APITokenPersonal = "personal"
+	APITokenService  = "service"
+)
+
+// API token scopes
+const (
+	ScopeMonitorsRead        = "monitors:read"
+	ScopeMonitorsWrite       = "monitors:write"
+	ScopeCredentialsManage   = "credentials:manage"
+	ScopeNotificationsManage = "notifications:manage"
+)
+
+// APITokenScopes lists every valid scope
+var APITokenScopes = []string{ScopeMonitorsRead, ScopeMonitorsWrite, ScopeCredentialsManage, ScopeNotificationsManage}
+
+// APIToken is a bearer token for machine access to the API. Only a hash of the
+// token is stored; the token itself is shown once when it is created.
+type APIToken struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"not null"`
+	Kind       string     `json:"kind" gorm:"not null"`           // personal or service
+	UserID     string     `json:"user_id,omitempty" gorm:"index"` // Owner of a personal token
+	User       *User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedBy  string     `json:"created_by"`                    // User that created the token
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the token
+	Prefix     string     `json:"prefix"`                        // Start of the token, to recognise it
+	Scopes     string     `json:"scopes"`                        // Comma separated scopes
+	ProfileID  string     `json:"profile_id,omitempty"`          // Restricts the token to one profile when set
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope returns true if the token carries the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid returns true if the token is neither revoked nor expired at now
+func (t *APIToken) IsValid(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// APITokenUsage records one API request made with a token
+type APITokenUsage struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TokenID   string    `json:"token_id" gorm:"index:idx_token_usage_token_created"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_token_usage_token_created"`
+}
@@ -1,8 +1,30 @@
 package models
 
+import "time"
+
+// User is an account that can sign in to the web UI and API
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID               string     `json:"id" gorm:"primaryKey"`
+	Username         string     `json:"username" gorm:"uniqueIndex;not null"`
+	Email            string     `json:"email"`
+	Password         string     `json:"password,omitempty" gorm:"-"` // Plain password, only accepted on input
+	PasswordHash     string     `json:"-" gorm:"not null"`           // bcrypt hash of the password
+	IsAdmin          bool       `json:"is_admin"`                    // Admins can manage user accounts
+	DefaultProfileID string     `json:"default_profile_id"`          // Profile used when a request names none
+	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Session is a signed-in browser session. The session token itself is only
+// held by the client; ID is its SHA-256 hash.
+type Session struct {
+	ID         string    `json:"-" gorm:"primaryKey"`
+	UserID     string    `json:"user_id" gorm:"index;not null"`
+	User       *User     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
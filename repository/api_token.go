@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/models"
+	"uptime-monitor/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiTokenPrefix starts every API token so leaked tokens are easy to spot
+	apiTokenPrefix = "hmd_"
+	// apiTokenUsageRetention is how long token usage records are kept
+	apiTokenUsageRetention = 30 * 24 * time.Hour
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// CreateToken generates and stores a new token and returns the token itself,
+// which is not recoverable afterwards
+func (r *APITokenRepository) CreateToken(token *models.APIToken) (string, error) {
+	secret, err := services.NewSecretToken()
+	if err != nil {
+		return "", err
+	}
+	plain := apiTokenPrefix + secret
+
+	token.ID = uuid.New().String()
+	token.TokenHash = services.HashToken(plain)
+	token.Prefix = plain[:len(apiTokenPrefix)+6]
+	token.CreatedAt = time.Now()
+	if err := r.db.Create(token).Error; err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// GetTokens returns the tokens owned by a user, or every token if userID is empty
+func (r *APITokenRepository) GetTokens(userID string) ([]models.APIToken, error) {
+	query := r.db.Order("created_at DESC")
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var tokens []models.APIToken
+	err := query.Find(&tokens).Error
+	return tokens, err
+}
+
+// GetTokenByID returns a token by its ID
+func (r *APITokenRepository) GetTokenByID(id string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Where("id = ?", id).First(&token).Error
+	return &token, err
+}
+
+// GetValidToken looks up a presented token and returns it, with its owner for
+// personal tokens, if it is neither revoked nor expired
+func (r *APITokenRepository) GetValidToken(plain string) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := r.db.Preload("User").Where("token_hash = ?", services.HashToken(plain)).First(&token).Error; err != nil {
+		return nil, err
+	}
+	if !token.IsValid(time.Now()) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if token.Kind == models.APITokenPersonal && token.User == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// RevokeToken revokes a token; revoking it again is a no-op
+func (r *APITokenRepository) RevokeToken(token *models.APIToken) error {
+	if token.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return r.db.Model(token).Update("revoked_at", now).Error
+}
+
+// RecordUsage logs a request made with a token and updates its last use
+func (r *APITokenRepository) RecordUsage(token *models.APIToken, method, path string, status int, ip string) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		usage := &models.APITokenUsage{
+			TokenID:   token.ID,
+			Method:    method,
+			Path:      path,
+			Status:    status,
+			IP:        ip,
+			CreatedAt: now,
+		}
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		return tx.Model(token).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+	})
+}
+
+// GetUsage returns a token's most recent usage records, newest first
+func (r *APITokenRepository) GetUsage(tokenID string, limit int) ([]models.APITokenUsage, error) {
+	var usage []models.APITokenUsage
+	err := r.db.Where("token_id = ?", tokenID).Order("created_at DESC").Limit(limit).Find(&usage).Error
+	return usage, err
+}
+
+// PruneUsage deletes usage records past the retention period
+func (r *APITokenRepository) PruneUsage() error {
+	return r.db.Where("created_at < ?", time.Now().Add(-apiTokenUsageRetention)).Delete(&models.APITokenUsage{}).Error
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EscalationRepository struct {
+	db *gorm.DB
+}
+
+func NewEscalationRepository(db *gorm.DB) *EscalationRepository {
+	return &EscalationRepository{db: db}
+}
+
+// CreatePolicy adds an escalation policy and its levels to a profile
+func (r *EscalationRepository) CreatePolicy(profileID string, policy *types.EscalationPolicy) error {
+	policy.ID = uuid.New().String()
+	policy.ProfileID = profileID
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+	prepareLevels(policy)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearOtherDefaults(tx, policy); err != nil {
+			return err
+		}
+		return tx.Create(policy).Error
+	})
+}
+
+// GetPolicies returns the escalation policies of a profile
+func (r *EscalationRepository) GetPolicies(profileID string) ([]types.EscalationPolicy, error) {
+	var policies []types.EscalationPolicy
+	err := r.db.Preload("Levels", orderLevels).
+		Where("profile_id = ?", profileID).Order("created_at").Find(&policies).Error
+	return policies, err
+}
+
+// GetPolicyByID returns an escalation policy of a profile
+func (r *EscalationRepository) GetPolicyByID(profileID, id string) (*types.EscalationPolicy, error) {
+	var policy types.EscalationPolicy
+	err := r.db.Preload("Levels", orderLevels).
+		Where("id = ? AND profile_id = ?", id, profileID).First(&policy).Error
+	return &policy, err
+}
+
+// GetMonitorPolicy returns the escalation policy of a monitor's outages: its
+// own, or else its profile's default. It returns nil if neither is set.
+func (r *EscalationRepository) GetMonitorPolicy(monitor *types.Monitor) (*types.EscalationPolicy, error) {
+	query := r.db.Preload("Levels", orderLevels).Where("profile_id = ?", monitor.ProfileID)
+	if monitor.EscalationPolicyID != "" {
+		query = query.Where("id = ?", monitor.EscalationPolicyID)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+
+	var policies []types.EscalationPolicy
+	if err := query.Limit(1).Find(&policies).Error; err != nil || len(policies) == 0 {
+		return nil, err
+	}
+	return &policies[0], nil
+}
+
+// UpdatePolicy saves an escalation policy and replaces its levels
+func (r *EscalationRepository) UpdatePolicy(policy *types.EscalationPolicy) error {
+	policy.UpdatedAt = time.Now()
+	prepareLevels(policy)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearOtherDefaults(tx, policy); err != nil {
+			return err
+		}
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&types.EscalationLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Levels").Save(policy).Error; err != nil {
+			return err
+		}
+		return tx.Create(&policy.Levels).Error
+	})
+}
+
+// DeletePolicy removes an escalation policy of a profile and its levels. Its
+// monitors fall back to the profile's default policy, and its running
+// escalations stop when they next come up.
+func (r *EscalationRepository) DeletePolicy(profileID, id string) error {
+	policy, err := r.GetPolicyByID(profileID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.Monitor{}).Where("profile_id = ? AND escalation_policy_id = ?", profileID, policy.ID).
+			UpdateColumn("escalation_policy_id", "").Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&types.EscalationLevel{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Levels").Delete(policy).Error
+	})
+}
+
+// StartEscalation stores a new escalation, unless the incident already has one.
+// It returns the incident's escalation and whether it was created.
+func (r *EscalationRepository) StartEscalation(escalation *types.Escalation) (*types.Escalation, bool, error) {
+	existing, err := r.GetIncidentEscalation(escalation.IncidentID)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+
+	escalation.ID = uuid.New().String()
+	escalation.Status = types.EscalationStatusActive
+	escalation.CreatedAt = time.Now()
+	escalation.UpdatedAt = time.Now()
+	if err := r.db.Create(escalation).Error; err != nil {
+		return nil, false, err
+	}
+	return escalation, true, nil
+}
+
+// GetIncidentEscalation returns the escalation of an incident, or nil if it has none
+func (r *EscalationRepository) GetIncidentEscalation(incidentID string) (*types.Escalation, error) {
+	var escalations []types.Escalation
+	err := r.db.Where("incident_id = ?", incidentID).Limit(1).Find(&escalations).Error
+	if err != nil || len(escalations) == 0 {
+		return nil, err
+	}
+	return &escalations[0], nil
+}
+
+// GetActiveEscalations returns the escalations that have levels left to notify, of all profiles
+func (r *EscalationRepository) GetActiveEscalations() ([]types.Escalation, error) {
+	var escalations []types.Escalation
+	err := r.db.Where("status = ?", types.EscalationStatusActive).Order("next_level_at").Find(&escalations).Error
+	return escalations, err
+}
+
+// GetEscalations returns the latest escalations of a profile, optionally
+// filtered by status and incident
+func (r *EscalationRepository) GetEscalations(profileID, status, incidentID string, limit int) ([]types.Escalation, error) {
+	query := r.db.Where("profile_id = ?", profileID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if incidentID != "" {
+		query = query.Where("incident_id = ?", incidentID)
+	}
+
+	escalations := []types.Escalation{}
+	err := query.Order("created_at DESC").Limit(limit).Find(&escalations).Error
+	return escalations, err
+}
+
+// SaveEscalation stores an escalation's progress
+func (r *EscalationRepository) SaveEscalation(escalation *types.Escalation) error {
+	escalation.UpdatedAt = time.Now()
+	return r.db.Save(escalation).Error
+}
+
+// StopEscalation ends an active escalation
+func (r *EscalationRepository) StopEscalation(escalation *types.Escalation, reason string) error {
+	escalation.Status = types.EscalationStatusStopped
+	escalation.StopReason = reason
+	return r.SaveEscalation(escalation)
+}
+
+// prepareLevels assigns IDs and positions to a policy's levels
+func prepareLevels(policy *types.EscalationPolicy) {
+	for i := range policy.Levels {
+		policy.Levels[i].ID = uuid.New().String()
+		policy.Levels[i].PolicyID = policy.ID
+		policy.Levels[i].Position = i
+	}
+}
+
+// clearOtherDefaults keeps a single default policy per profile
+func clearOtherDefaults(tx *gorm.DB, policy *types.EscalationPolicy) error {
+	if !policy.IsDefault {
+		return nil
+	}
+	return tx.Model(&types.EscalationPolicy{}).Where("profile_id = ? AND id <> ?", policy.ProfileID, policy.ID).
+		UpdateColumn("is_default", false).Error
+}
+
+func orderLevels(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IncidentRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentRepository(db *gorm.DB) *IncidentRepository {
+	return &IncidentRepository{db: db}
+}
+
+// OpenIncident creates an incident for a monitor together with its opened event
+func (r *IncidentRepository) OpenIncident(monitor *types.Monitor, cause string, at time.Time) (*types.Incident, error) {
+	incident := &types.Incident{
+		ID:            uuid.New().String(),
+		MonitorID:     monitor.ID,
+		ProfileID:     monitor.ProfileID,
+		MonitorName:   monitor.Name,
+		Status:        types.IncidentStatusOpen,
+		Cause:         cause,
+		FailedChecks:  1,
+		LastFailureAt: at,
+		StartedAt:     at,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Events").Create(incident).Error; err != nil {
+			return err
+		}
+		return tx.Create(newIncidentEvent(incident.ID, types.IncidentEventOpened, cause, "", at)).Error
+	})
+	return incident, err
+}
+
+// GetOpenIncident returns the unresolved incident of a monitor, or nil if it has none
+func (r *IncidentRepository) GetOpenIncident(monitorID string) (*types.Incident, error) {
+	var incidents []types.Incident
+	err := r.db.Where("monitor_id = ? AND status <> ?", monitorID, types.IncidentStatusResolved).
+		Order("started_at DESC").Limit(1).Find(&incidents).Error
+	if err != nil || len(incidents) == 0 {
+		return nil, err
+	}
+	return &incidents[0], nil
+}
+
+// GetUnresolvedIncidents returns the open and acknowledged incidents of the given monitors
+func (r *IncidentRepository) GetUnresolvedIncidents(monitorIDs []string) ([]types.Incident, error) {
+	var incidents []types.Incident
+	err := r.db.Where("monitor_id IN ? AND status <> ?", monitorIDs, types.IncidentStatusResolved).
+		Order("started_at DESC").Find(&incidents).Error
+	return incidents, err
+}
+
+// RecordFailure adds a failing check to an unresolved incident
+func (r *IncidentRepository) RecordFailure(incident *types.Incident, message string, at time.Time) error {
+	incident.FailedChecks++
+	incident.LastFailureAt = at
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.Incident{}).Where("id = ?", incident.ID).Updates(map[string]interface{}{
+			"failed_checks":   gorm.Expr("failed_checks + 1"),
+			"last_failure_at": at,
+			"updated_at":      at,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(newIncidentEvent(incident.ID, types.IncidentEventCheckFailed, message, "", at)).Error
+	})
+}
+
+// Acknowledge marks an incident as being worked on
+func (r *IncidentRepository) Acknowledge(incident *types.Incident, author, message string) error {
+	now := time.Now()
+	incident.Status = types.IncidentStatusAcknowledged
+	incident.AcknowledgedAt = &now
+	incident.AcknowledgedBy = author
+	return r.transition(incident, types.IncidentEventAcknowledged, author, message, now, map[string]interface{}{
+		"status":          incident.Status,
+		"acknowledged_at": now,
+		"acknowledged_by": author,
+	})
+}
+
+// Resolve closes an incident. eventType is resolved for a manual resolution and
+// recovered when the monitor came back up.
+func (r *IncidentRepository) Resolve(incident *types.Incident, eventType, author, message string) error {
+	now := time.Now()
+	incident.Status = types.IncidentStatusResolved
+	incident.ResolvedAt = &now
+	incident.ResolvedBy = author
+	return r.transition(incident, eventType, author, message, now, map[string]interface{}{
+		"status":      incident.Status,
+		"resolved_at": now,
+		"resolved_by": author,
+	})
+}
+
+// AddComment adds a comment to an incident's timeline
+func (r *IncidentRepository) AddComment(incidentID, author, message string) (*types.IncidentEvent, error) {
+	event := newIncidentEvent(incidentID, types.IncidentEventComment, message, author, time.Now())
+	return event, r.db.Create(event).Error
+}
+
+// RecordEscalation adds the notification of an escalation level to an incident's timeline
+func (r *IncidentRepository) RecordEscalation(incidentID, message string) error {
+	return r.db.Create(newIncidentEvent(incidentID, types.IncidentEventEscalated, message, "system", time.Now())).Error
+}
+
+// GetIncidents returns the incidents of a profile, newest first, optionally
+// filtered by status and monitor
+func (r *IncidentRepository) GetIncidents(profileID, status, monitorID string) ([]types.Incident, error) {
+	query := r.db.Where("profile_id = ?", profileID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if monitorID != "" {
+		query = query.Where("monitor_id = ?", monitorID)
+	}
+
+	var incidents []types.Incident
+	err := query.Order("started_at DESC").Find(&incidents).Error
+	return incidents, err
+}
+
+// GetIncidentByID returns an incident of a profile with its timeline
+func (r *IncidentRepository) GetIncidentByID(profileID, id string) (*types.Incident, error) {
+	var incident types.Incident
+	err := r.db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ? AND profile_id = ?", id, profileID).First(&incident).Error
+	return &incident, err
+}
+
+// transition applies a state change to an incident and records it in the timeline
+func (r *IncidentRepository) transition(incident *types.Incident, eventType, author, message string, at time.Time, updates map[string]interface{}) error {
+	updates["updated_at"] = at
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Incident{}).Where("id = ?", incident.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(newIncidentEvent(incident.ID, eventType, message, author, at)).Error
+	})
+}
+
+func newIncidentEvent(incidentID, eventType, message, author string, at time.Time) *types.IncidentEvent {
+	return &types.IncidentEvent{
+		ID:         uuid.New().String(),
+		IncidentID: incidentID,
+		Type:       eventType,
+		Message:    message,
+		Author:     author,
+		CreatedAt:  at,
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"time"
 	"uptime-monitor/types"
 
 	"gorm.io/gorm"
@@ -18,22 +19,96 @@ func (r *LogRepository) CreateLog(log *types.Log) error {
 	return r.db.Create(log).Error
 }
 
-func (r *LogRepository) GetLogsByMonitorID(monitorID string) ([]types.Log, error) {
+// GetLogsByMonitorID returns all logs of a monitor in a profile
+func (r *LogRepository) GetLogsByMonitorID(profileID, monitorID string) ([]types.Log, error) {
 	var logs []types.Log
 
-	// Get the active profile first
-	var activeProfile types.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
+	// Verify the monitor belongs to the profile
+	if err := r.VerifyProfileMonitor(profileID, monitorID); err != nil {
 		return nil, err
 	}
 
-	// Verify the monitor belongs to the active profile
-	var monitor types.Monitor
-	if err := r.db.Where("id = ? AND profile_id = ?", monitorID, activeProfile.ID).First(&monitor).Error; err != nil {
+	// Get logs for the monitor
+	err := r.db.Where("monitor_id = ?", monitorID).Find(&logs).Error
+	return logs, err
+}
+
+// GetLogsByMonitorIDInRange returns the logs of a monitor in a profile created
+// in [from, to), oldest first. A zero from or to leaves that side of the range
+// open, and a limit of 0 returns every matching log.
+func (r *LogRepository) GetLogsByMonitorIDInRange(profileID, monitorID string, from, to time.Time, limit int) ([]types.Log, error) {
+	if err := r.VerifyProfileMonitor(profileID, monitorID); err != nil {
 		return nil, err
 	}
+	return r.GetLogsInRange([]string{monitorID}, from, to, limit)
+}
 
-	// Get logs for the monitor
-	err := r.db.Where("monitor_id = ?", monitorID).Find(&logs).Error
+// GetLogsInRange returns the logs of the given monitors created in [from, to),
+// oldest first, regardless of profile. A zero from or to leaves that side of the
+// range open, and a limit of 0 returns every matching log.
+func (r *LogRepository) GetLogsInRange(monitorIDs []string, from, to time.Time, limit int) ([]types.Log, error) {
+	var logs []types.Log
+	err := r.rangeQuery(monitorIDs, from, to).Order("created_at ASC").Limit(limitOrAll(limit)).Find(&logs).Error
 	return logs, err
 }
+
+// GetLastLogBefore returns the most recent log of a monitor created before t, or
+// nil if there is none
+func (r *LogRepository) GetLastLogBefore(monitorID string, t time.Time) (*types.Log, error) {
+	var logs []types.Log
+	err := r.db.Where("monitor_id = ? AND created_at < ?", monitorID, t).
+		Order("created_at DESC").Limit(1).Find(&logs).Error
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return &logs[0], nil
+}
+
+// ResponseCodeCount is the number of checks that returned a response code
+type ResponseCodeCount struct {
+	ResponseCode int   `json:"response_code"`
+	Count        int64 `json:"count"`
+}
+
+// CountResponseCodes counts the logs of a monitor in a profile created in
+// [from, to) by response code
+func (r *LogRepository) CountResponseCodes(profileID, monitorID string, from, to time.Time) ([]ResponseCodeCount, error) {
+	if err := r.VerifyProfileMonitor(profileID, monitorID); err != nil {
+		return nil, err
+	}
+
+	var counts []ResponseCodeCount
+	err := r.rangeQuery([]string{monitorID}, from, to).
+		Select("response_code, COUNT(*) AS count").
+		Group("response_code").
+		Order("response_code").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// VerifyProfileMonitor returns an error unless the monitor belongs to the profile
+func (r *LogRepository) VerifyProfileMonitor(profileID, monitorID string) error {
+	var monitor types.Monitor
+	return r.db.Where("id = ? AND profile_id = ?", monitorID, profileID).First(&monitor).Error
+}
+
+// rangeQuery scopes a log query to monitors and a time range, using the
+// monitor_id + created_at index
+func (r *LogRepository) rangeQuery(monitorIDs []string, from, to time.Time) *gorm.DB {
+	query := r.db.Model(&types.Log{}).Where("monitor_id IN ?", monitorIDs)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+	return query
+}
+
+// limitOrAll converts a limit of 0 to gorm's "no limit"
+func limitOrAll(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
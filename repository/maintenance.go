@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MaintenanceRepository struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// CreateWindow adds a maintenance window to a profile
+func (r *MaintenanceRepository) CreateWindow(profileID string, window *types.MaintenanceWindow) error {
+	window.ID = uuid.New().String()
+	window.ProfileID = profileID
+	window.CreatedAt = time.Now()
+	window.UpdatedAt = time.Now()
+	return r.db.Create(window).Error
+}
+
+// GetWindowsForProfile returns the windows of a profile, only the enabled ones
+// if enabledOnly is set
+func (r *MaintenanceRepository) GetWindowsForProfile(profileID string, enabledOnly bool) ([]types.MaintenanceWindow, error) {
+	query := r.db.Where("profile_id = ?", profileID)
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+
+	var windows []types.MaintenanceWindow
+	err := query.Order("created_at").Find(&windows).Error
+	return windows, err
+}
+
+// GetWindowByID returns a maintenance window of a profile
+func (r *MaintenanceRepository) GetWindowByID(profileID, id string) (*types.MaintenanceWindow, error) {
+	var window types.MaintenanceWindow
+	err := r.db.Where("id = ? AND profile_id = ?", id, profileID).First(&window).Error
+	return &window, err
+}
+
+// UpdateWindow saves a maintenance window
+func (r *MaintenanceRepository) UpdateWindow(window *types.MaintenanceWindow) error {
+	window.UpdatedAt = time.Now()
+	return r.db.Save(window).Error
+}
+
+// DeleteWindow removes a maintenance window of a profile
+func (r *MaintenanceRepository) DeleteWindow(profileID, id string) error {
+	window, err := r.GetWindowByID(profileID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.Delete(window).Error
+}
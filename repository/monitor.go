@@ -3,10 +3,8 @@ package repository
 import (
 	"fmt"
 	"log"
-	"time"
 	"uptime-monitor/types"
 
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -18,59 +16,54 @@ func NewMonitorRepository(db *gorm.DB) *MonitorRepository {
 	return &MonitorRepository{db: db}
 }
 
+// CreateMonitor creates a monitor in the profile set on monitor.ProfileID
 func (r *MonitorRepository) CreateMonitor(monitor *types.Monitor) error {
-	// Try to get the active profile first
-	var activeProfile types.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		// If no active profile exists, create a default one
-		activeProfile = types.Profile{
-			ID:          uuid.New().String(),
-			Name:        "Default Profile",
-			Description: "Automatically created default profile",
-			IsActive:    true,
-			CreatedAt:   time.Now(),
-		}
-
-		// Create the default profile
-		if err := r.db.Create(&activeProfile).Error; err != nil {
-			return fmt.Errorf("failed to create default profile: %v", err)
-		}
+	if monitor.ProfileID == "" {
+		return fmt.Errorf("monitor has no profile")
 	}
-
-	// Set the profile ID for the new monitor
-	monitor.ProfileID = activeProfile.ID
 	return r.db.Create(monitor).Error
 }
 
-func (r *MonitorRepository) GetAllMonitors() ([]types.Monitor, error) {
+// GetAllMonitors returns the monitors of a profile
+func (r *MonitorRepository) GetAllMonitors(profileID string) ([]types.Monitor, error) {
 	var monitors []types.Monitor
 
-	// Get the active profile first
-	var activeProfile types.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		return nil, err
-	}
-
-	// Get monitors for the active profile
 	// The monitors table doesn't have a deleted_at column, so we don't need to check for it
-	log.Printf("Getting all monitors for profile %s", activeProfile.ID)
-	err := r.db.Where("profile_id = ?", activeProfile.ID).Find(&monitors).Error
+	log.Printf("Getting all monitors for profile %s", profileID)
+	err := r.db.Where("profile_id = ?", profileID).Find(&monitors).Error
+
+	log.Printf("Found %d monitors for profile %s", len(monitors), profileID)
+	return monitors, err
+}
+
+// GetAllMonitorsAcrossProfiles returns the monitors of every profile, for the
+// scheduler.
+func (r *MonitorRepository) GetAllMonitorsAcrossProfiles() ([]types.Monitor, error) {
+	var monitors []types.Monitor
+	err := r.db.Find(&monitors).Error
 
-	log.Printf("Found %d monitors for profile %s", len(monitors), activeProfile.ID)
+	log.Printf("Found %d monitors across all profiles", len(monitors))
 	return monitors, err
 }
 
-func (r *MonitorRepository) GetMonitorByID(id string) (*types.Monitor, error) {
+// GetMonitorByIDAcrossProfiles retrieves a monitor by its ID regardless of profile
+func (r *MonitorRepository) GetMonitorByIDAcrossProfiles(id string) (*types.Monitor, error) {
 	var monitor types.Monitor
+	err := r.db.Where("id = ?", id).First(&monitor).Error
+	return &monitor, err
+}
 
-	// Get the active profile first
-	var activeProfile types.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		return nil, err
-	}
+// GetProfileMonitorsByIDs returns the monitors of a profile with the given IDs
+func (r *MonitorRepository) GetProfileMonitorsByIDs(profileID string, ids []string) ([]types.Monitor, error) {
+	var monitors []types.Monitor
+	err := r.db.Where("profile_id = ? AND id IN ?", profileID, ids).Find(&monitors).Error
+	return monitors, err
+}
 
-	// Get monitor for the active profile
-	err := r.db.Where("id = ? AND profile_id = ?", id, activeProfile.ID).First(&monitor).Error
+// GetMonitorByID retrieves a monitor of a profile by its ID
+func (r *MonitorRepository) GetMonitorByID(profileID, id string) (*types.Monitor, error) {
+	var monitor types.Monitor
+	err := r.db.Where("id = ? AND profile_id = ?", id, profileID).First(&monitor).Error
 	return &monitor, err
 }
 
@@ -91,17 +84,36 @@ func (r *MonitorRepository) UpdateMonitor(monitor *types.Monitor) error {
 	return nil
 }
 
-func (r *MonitorRepository) DeleteMonitor(id string) error {
-	// Get the active profile first
-	var activeProfile types.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		log.Printf("Error finding active profile for monitor deletion: %v", err)
-		return err
+// UpdateMonitorStatus persists only the fields written by a check, leaving the
+// monitor's configuration and UpdatedAt untouched
+func (r *MonitorRepository) UpdateMonitorStatus(monitor *types.Monitor) error {
+	result := r.db.Model(&types.Monitor{}).Where("id = ?", monitor.ID).UpdateColumns(map[string]interface{}{
+		"status":        monitor.Status,
+		"response_code": monitor.ResponseCode,
+		"response_time": monitor.ResponseTime,
+		"failure_count": monitor.FailureCount,
+		"last_checked":  monitor.LastChecked,
+		"degraded":      monitor.Degraded,
+
+		"tls_cert_expiry": monitor.TLSCertExpiry,
+		"tls_cert_issuer": monitor.TLSCertIssuer,
+		"tls_cert_sans":   monitor.TLSCertSANs,
+		"tls_chain_valid": monitor.TLSChainValid,
+		"tls_chain_error": monitor.TLSChainError,
+		"tls_alert_level": monitor.TLSAlertLevel,
+	})
+	if result.Error != nil {
+		log.Printf("ERROR updating status of monitor %s: %v", monitor.Name, result.Error)
+		return result.Error
 	}
+	return nil
+}
 
+// DeleteMonitor deletes a monitor of a profile
+func (r *MonitorRepository) DeleteMonitor(profileID, id string) error {
 	// First try the standard GORM delete
-	log.Printf("Deleting monitor with ID %s for profile %s", id, activeProfile.ID)
-	result := r.db.Where("id = ? AND profile_id = ?", id, activeProfile.ID).Delete(&types.Monitor{})
+	log.Printf("Deleting monitor with ID %s for profile %s", id, profileID)
+	result := r.db.Where("id = ? AND profile_id = ?", id, profileID).Delete(&types.Monitor{})
 
 	if result.Error != nil {
 		log.Printf("Error deleting monitor %s: %v", id, result.Error)
@@ -110,10 +122,10 @@ func (r *MonitorRepository) DeleteMonitor(id string) error {
 
 	// If no rows were affected, try a direct SQL delete as a fallback
 	if result.RowsAffected == 0 {
-		log.Printf("No monitor found with ID %s for profile %s using GORM delete, trying direct SQL", id, activeProfile.ID)
+		log.Printf("No monitor found with ID %s for profile %s using GORM delete, trying direct SQL", id, profileID)
 
 		// Execute a direct SQL DELETE statement
-		sqlResult := r.db.Exec("DELETE FROM monitors WHERE id = ? AND profile_id = ?", id, activeProfile.ID)
+		sqlResult := r.db.Exec("DELETE FROM monitors WHERE id = ? AND profile_id = ?", id, profileID)
 
 		if sqlResult.Error != nil {
 			log.Printf("Error with direct SQL delete for monitor %s: %v", id, sqlResult.Error)
@@ -121,7 +133,7 @@ func (r *MonitorRepository) DeleteMonitor(id string) error {
 		}
 
 		if sqlResult.RowsAffected == 0 {
-			log.Printf("No monitor found with ID %s for profile %s using direct SQL", id, activeProfile.ID)
+			log.Printf("No monitor found with ID %s for profile %s using direct SQL", id, profileID)
 			return fmt.Errorf("monitor not found")
 		}
 
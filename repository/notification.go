@@ -128,18 +128,12 @@ func (r *NotificationRepository) TestSlackConnection(profileID string) error {
 		return errors.New("no Slack notification method found")
 	}
 
-	// Parse config
-	var config map[string]interface{}
-	if err := json.Unmarshal(slackMethod.Config, &config); err != nil {
-		return fmt.Errorf("error parsing Slack config: %v", err)
-	}
-
 	// Create a test notification
-	notificationService := services.NewNotificationService([]types.NotificationMethod{*slackMethod})
-	return notificationService.SendSlack(&types.Monitor{
+	notificationService := services.NewNotificationService(services.NewDefaultNotifierRegistry(), []types.NotificationMethod{*slackMethod})
+	return notificationService.SendNotification(&types.Monitor{
 		Name: "Test Monitor",
 		URL:  "http://test.com",
-	}, "test", "This is a test notification from the uptime monitor", config)
+	}, "test", "This is a test notification from the uptime monitor")
 }
 
 // TestTeamsConnection tests the Teams webhook
@@ -163,18 +157,12 @@ func (r *NotificationRepository) TestTeamsConnection(profileID string) error {
 		return errors.New("no Teams notification method found")
 	}
 
-	// Parse config
-	var config map[string]interface{}
-	if err := json.Unmarshal(teamsMethod.Config, &config); err != nil {
-		return fmt.Errorf("error parsing Teams config: %v", err)
-	}
-
 	// Create a test notification
-	notificationService := services.NewNotificationService([]types.NotificationMethod{*teamsMethod})
-	return notificationService.SendTeams(&types.Monitor{
+	notificationService := services.NewNotificationService(services.NewDefaultNotifierRegistry(), []types.NotificationMethod{*teamsMethod})
+	return notificationService.SendNotification(&types.Monitor{
 		Name: "Test Monitor",
 		URL:  "http://test.com",
-	}, "test", "This is a test notification from the uptime monitor", config)
+	}, "test", "This is a test notification from the uptime monitor")
 }
 
 // GetNotificationMethodByID retrieves a notification method by its ID
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// deliveryRetention is how long delivered and dead notifications are kept
+const deliveryRetention = 30 * 24 * time.Hour
+
+// DeliveryRepository is the outbox of notifications waiting to be delivered,
+// and the history of those that were
+type DeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+// Enqueue stores notifications to be delivered as soon as possible
+func (r *DeliveryRepository) Enqueue(deliveries []types.NotificationDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range deliveries {
+		deliveries[i].ID = uuid.New().String()
+		deliveries[i].Status = types.DeliveryStatusPending
+		deliveries[i].NextAttemptAt = now
+	}
+	return r.db.Omit("History").Create(&deliveries).Error
+}
+
+// ClaimDue marks up to limit pending notifications that are due as being sent
+// and returns them. A notification is only returned to one caller.
+func (r *DeliveryRepository) ClaimDue(limit int) ([]types.NotificationDelivery, error) {
+	var due []types.NotificationDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", types.DeliveryStatusPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, delivery := range due {
+		result := r.db.Model(&types.NotificationDelivery{}).
+			Where("id = ? AND status = ?", delivery.ID, types.DeliveryStatusPending).
+			Updates(map[string]interface{}{"status": types.DeliveryStatusSending, "updated_at": time.Now()})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			delivery.Status = types.DeliveryStatusSending
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+// ReleaseStale puts notifications claimed before the given time back in the
+// queue, as the process sending them stopped before recording the attempt
+func (r *DeliveryRepository) ReleaseStale(before time.Time) (int64, error) {
+	result := r.db.Model(&types.NotificationDelivery{}).
+		Where("status = ? AND updated_at < ?", types.DeliveryStatusSending, before).
+		Updates(map[string]interface{}{"status": types.DeliveryStatusPending, "updated_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+// RecordAttempt saves the outcome of an attempt together with the delivery
+// state it led to
+func (r *DeliveryRepository) RecordAttempt(delivery *types.NotificationDelivery, attempt *types.NotificationAttempt) error {
+	attempt.ID = uuid.New().String()
+	attempt.DeliveryID = delivery.ID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.NotificationDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_error":      delivery.LastError,
+			"delivered_at":    delivery.DeliveredAt,
+			"updated_at":      time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(attempt).Error
+	})
+}
+
+// GetDeliveries returns the latest notifications of a profile, newest first,
+// optionally filtered by status, monitor and notification method
+func (r *DeliveryRepository) GetDeliveries(profileID, status, monitorID, methodID string, limit int) ([]types.NotificationDelivery, error) {
+	query := r.db.Where("profile_id = ?", profileID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if monitorID != "" {
+		query = query.Where("monitor_id = ?", monitorID)
+	}
+	if methodID != "" {
+		query = query.Where("method_id = ?", methodID)
+	}
+
+	var deliveries []types.NotificationDelivery
+	err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDeliveryByID returns a notification of a profile with its attempts
+func (r *DeliveryRepository) GetDeliveryByID(profileID, id string) (*types.NotificationDelivery, error) {
+	var delivery types.NotificationDelivery
+	err := r.db.Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).Where("id = ? AND profile_id = ?", id, profileID).First(&delivery).Error
+	return &delivery, err
+}
+
+// Resend queues a copy of a notification with a fresh set of attempts. The
+// original keeps its state and history.
+func (r *DeliveryRepository) Resend(original *types.NotificationDelivery) (*types.NotificationDelivery, error) {
+	resent := *original
+	resent.ResendOf = original.ID
+	resent.Attempts = 0
+	resent.LastError = ""
+	resent.DeliveredAt = nil
+	resent.History = nil
+	resent.CreatedAt = time.Time{}
+	resent.UpdatedAt = time.Time{}
+
+	deliveries := []types.NotificationDelivery{resent}
+	if err := r.Enqueue(deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// Prune deletes the delivered and dead notifications past the retention period
+func (r *DeliveryRepository) Prune() error {
+	old := r.db.Model(&types.NotificationDelivery{}).Select("id").
+		Where("status IN ? AND updated_at < ?", []string{types.DeliveryStatusDelivered, types.DeliveryStatusDead}, time.Now().Add(-deliveryRetention))
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("delivery_id IN (?)", old).Delete(&types.NotificationAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN (?)", old).Delete(&types.NotificationDelivery{}).Error
+	})
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRuleRepository(db *gorm.DB) *NotificationRuleRepository {
+	return &NotificationRuleRepository{db: db}
+}
+
+// CreateRule adds a notification rule to a profile
+func (r *NotificationRuleRepository) CreateRule(profileID string, rule *types.NotificationRule) error {
+	rule.ID = uuid.New().String()
+	rule.ProfileID = profileID
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+	return r.db.Create(rule).Error
+}
+
+// GetRules returns the notification rules of a profile, only the ones of a
+// monitor if monitorID is set
+func (r *NotificationRuleRepository) GetRules(profileID, monitorID string) ([]types.NotificationRule, error) {
+	query := r.db.Where("profile_id = ?", profileID)
+	if monitorID != "" {
+		query = query.Where("monitor_id = ?", monitorID)
+	}
+
+	var rules []types.NotificationRule
+	err := query.Order("created_at").Find(&rules).Error
+	return rules, err
+}
+
+// GetRuleByID returns a notification rule of a profile
+func (r *NotificationRuleRepository) GetRuleByID(profileID, id string) (*types.NotificationRule, error) {
+	var rule types.NotificationRule
+	err := r.db.Where("id = ? AND profile_id = ?", id, profileID).First(&rule).Error
+	return &rule, err
+}
+
+// UpdateRule saves a notification rule
+func (r *NotificationRuleRepository) UpdateRule(rule *types.NotificationRule) error {
+	rule.UpdatedAt = time.Now()
+	return r.db.Save(rule).Error
+}
+
+// DeleteRule removes a notification rule of a profile
+func (r *NotificationRuleRepository) DeleteRule(profileID, id string) error {
+	rule, err := r.GetRuleByID(profileID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.Delete(rule).Error
+}
+
+// DeleteMonitorRules removes the notification rules of a deleted monitor
+func (r *NotificationRuleRepository) DeleteMonitorRules(profileID, monitorID string) error {
+	return r.db.Where("profile_id = ? AND monitor_id = ?", profileID, monitorID).Delete(&types.NotificationRule{}).Error
+}
@@ -1,9 +1,10 @@
 package repository
 
 import (
-	"fmt"
-	"log"
+	"errors"
 	"time"
+	"uptime-monitor/models"
+	"uptime-monitor/services"
 	"uptime-monitor/types"
 
 	"github.com/google/uuid"
@@ -21,18 +22,13 @@ func NewProfileRepository(db *gorm.DB) *ProfileRepository {
 func (r *ProfileRepository) CreateProfile(profile *types.Profile) error {
 	profile.ID = uuid.New().String()
 	profile.CreatedAt = time.Now()
-	// Set all profiles to inactive first
-	if err := r.db.Model(&types.Profile{}).Where("1=1").Update("is_active", false).Error; err != nil {
-		return err
-	}
-	// Set the new profile as active
-	profile.IsActive = true
 	return r.db.Create(profile).Error
 }
 
+// GetAllProfiles returns every profile, oldest first
 func (r *ProfileRepository) GetAllProfiles() ([]types.Profile, error) {
 	var profiles []types.Profile
-	err := r.db.Find(&profiles).Error
+	err := r.db.Order("created_at").Find(&profiles).Error
 	return profiles, err
 }
 
@@ -43,66 +39,78 @@ func (r *ProfileRepository) GetProfileByID(id string) (*types.Profile, error) {
 }
 
 func (r *ProfileRepository) UpdateProfile(profile *types.Profile) error {
-	// Don't allow changing active status through update
 	existingProfile := &types.Profile{}
 	if err := r.db.Where("id = ?", profile.ID).First(existingProfile).Error; err != nil {
 		return err
 	}
-	profile.IsActive = existingProfile.IsActive
+	profile.CreatedAt = existingProfile.CreatedAt
 	return r.db.Save(profile).Error
 }
 
+// DeleteProfile deletes a profile and, in the same transaction, everything it owns
 func (r *ProfileRepository) DeleteProfile(id string) error {
-	// Don't allow deleting the active profile
 	var profile types.Profile
 	if err := r.db.Where("id = ?", id).First(&profile).Error; err != nil {
 		return err
 	}
-	if profile.IsActive {
-		return fmt.Errorf("cannot delete active profile")
-	}
 
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Delete associated monitors
-		if err := tx.Where("profile_id = ?", id).Delete(&types.Monitor{}).Error; err != nil {
-			return err
+		// Delete the rows that hang off the profile's monitors, deliveries,
+		// incidents, escalation policies and status pages first
+		children := []struct {
+			column string
+			parent interface{}
+			model  interface{}
+		}{
+			{"monitor_id", &types.Monitor{}, &types.Log{}},
+			{"delivery_id", &types.NotificationDelivery{}, &types.NotificationAttempt{}},
+			{"incident_id", &types.Incident{}, &types.IncidentEvent{}},
+			{"policy_id", &types.EscalationPolicy{}, &types.EscalationLevel{}},
+			{"status_page_id", &types.StatusPage{}, &types.StatusPageComponent{}},
 		}
-
-		// Delete associated SMTP settings
-		if err := tx.Where("profile_id = ?", id).Delete(&types.SMTPSettings{}).Error; err != nil {
-			return err
+		for _, child := range children {
+			parentIDs := tx.Model(child.parent).Select("id").Where("profile_id = ?", id)
+			if err := tx.Where(child.column+" IN (?)", parentIDs).Delete(child.model).Error; err != nil {
+				return err
+			}
 		}
 
-		// Delete the profile
-		return tx.Where("id = ?", id).Delete(&types.Profile{}).Error
-	})
-}
-
-func (r *ProfileRepository) GetActiveProfile() (*types.Profile, error) {
-	var profile types.Profile
-	result := r.db.Where("is_active = ?", true).First(&profile)
-	if result.Error != nil {
-		log.Printf("Error fetching active profile: %v", result.Error)
-		return nil, result.Error
-	}
-	return &profile, nil
-}
+		// Delete everything else the profile owns, including its memberships
+		owned := []interface{}{
+			&types.Monitor{},
+			&types.NotificationSettings{},
+			&types.NotificationMethod{},
+			&types.NotificationRule{},
+			&types.NotificationDelivery{},
+			&types.Escalation{},
+			&types.EscalationPolicy{},
+			&types.Incident{},
+			&types.MaintenanceWindow{},
+			&types.StatusPage{},
+			&services.Credential{},
+			&types.ProfileMembership{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("profile_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
 
-func (r *ProfileRepository) SetActiveProfile(id string) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// First check if the profile exists
-		var profile types.Profile
-		if err := tx.Where("id = ?", id).First(&profile).Error; err != nil {
-			return fmt.Errorf("profile not found")
+		// SMTP settings are only kept per profile in databases whose
+		// smtp_settings table was created from models.SMTPSettings
+		if tx.Migrator().HasColumn("smtp_settings", "profile_id") {
+			if err := tx.Where("profile_id = ?", id).Delete(&models.SMTPSettings{}).Error; err != nil {
+				return err
+			}
 		}
 
-		// Deactivate all profiles
-		if err := tx.Model(&types.Profile{}).Where("1=1").Update("is_active", false).Error; err != nil {
+		// Users who picked this profile fall back to their first one
+		if err := tx.Model(&models.User{}).Where("default_profile_id = ?", id).Update("default_profile_id", "").Error; err != nil {
 			return err
 		}
 
-		// Activate the selected profile
-		return tx.Model(&types.Profile{}).Where("id = ?", id).Update("is_active", true).Error
+		// Delete the profile
+		return tx.Where("id = ?", id).Delete(&types.Profile{}).Error
 	})
 }
 
@@ -114,18 +122,151 @@ func (r *ProfileRepository) GetNotificationMethods(profileID string) ([]types.No
 	return methods, nil
 }
 
+// GetNotificationMethod returns a notification method of a profile
+func (r *ProfileRepository) GetNotificationMethod(profileID, id string) (*types.NotificationMethod, error) {
+	var method types.NotificationMethod
+	err := r.db.Where("id = ? AND profile_id = ?", id, profileID).First(&method).Error
+	return &method, err
+}
+
 func (r *ProfileRepository) CreateNotificationMethod(method *types.NotificationMethod) error {
 	return r.db.Create(method).Error
 }
 
+// UpdateNotificationMethod saves a notification method that belongs to
+// method.ProfileID. Secrets sent back masked keep their stored values.
 func (r *ProfileRepository) UpdateNotificationMethod(method *types.NotificationMethod) error {
+	var existing types.NotificationMethod
+	if err := r.db.Where("id = ? AND profile_id = ?", method.ID, method.ProfileID).First(&existing).Error; err != nil {
+		return err
+	}
+	method.Config = method.Config.KeepMasked(existing.Config)
+	method.CreatedAt = existing.CreatedAt
 	return r.db.Save(method).Error
 }
 
-func (r *ProfileRepository) DeleteNotificationMethod(id string) error {
-	return r.db.Delete(&types.NotificationMethod{}, "id = ?", id).Error
+// DeleteNotificationMethod deletes a notification method of a profile
+func (r *ProfileRepository) DeleteNotificationMethod(profileID, id string) error {
+	result := r.db.Delete(&types.NotificationMethod{}, "id = ? AND profile_id = ?", id, profileID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *ProfileRepository) CreateNotificationSettings(settings *types.NotificationSettings) error {
 	return r.db.Create(settings).Error
 }
+
+// CreateProfileWithOwner creates a profile and makes the user its owner
+func (r *ProfileRepository) CreateProfileWithOwner(profile *types.Profile, ownerID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := NewProfileRepository(tx).CreateProfile(profile); err != nil {
+			return err
+		}
+		return tx.Create(newMembership(profile.ID, ownerID, types.RoleOwner)).Error
+	})
+}
+
+// GetProfilesForUser returns the profiles the user is a member of, oldest first
+func (r *ProfileRepository) GetProfilesForUser(userID string) ([]types.Profile, error) {
+	var profiles []types.Profile
+	err := r.db.Joins("JOIN profile_memberships ON profile_memberships.profile_id = profiles.id").
+		Where("profile_memberships.user_id = ?", userID).
+		Order("profiles.created_at").
+		Find(&profiles).Error
+	return profiles, err
+}
+
+// GetMemberRole returns the user's role in a profile, or "" if they are not a member
+func (r *ProfileRepository) GetMemberRole(profileID, userID string) (string, error) {
+	var membership types.ProfileMembership
+	err := r.db.Where("profile_id = ? AND user_id = ?", profileID, userID).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return membership.Role, err
+}
+
+// GetMembers returns the members of a profile with their usernames
+func (r *ProfileRepository) GetMembers(profileID string) ([]types.ProfileMembership, error) {
+	var members []types.ProfileMembership
+	err := r.db.Select("profile_memberships.*, users.username").
+		Joins("JOIN users ON users.id = profile_memberships.user_id").
+		Where("profile_memberships.profile_id = ?", profileID).
+		Order("users.username").
+		Find(&members).Error
+	return members, err
+}
+
+// SetMemberRole adds the user to a profile or changes their role. The last
+// owner of a profile cannot be demoted.
+func (r *ProfileRepository) SetMemberRole(profileID, userID, role string) (*types.ProfileMembership, error) {
+	var membership types.ProfileMembership
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("profile_id = ? AND user_id = ?", profileID, userID).First(&membership).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			membership = *newMembership(profileID, userID, role)
+			return tx.Create(&membership).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == types.RoleOwner && role != types.RoleOwner {
+			if err := ensureAnotherOwner(tx, profileID); err != nil {
+				return err
+			}
+		}
+		membership.Role = role
+		membership.UpdatedAt = time.Now()
+		return tx.Save(&membership).Error
+	})
+	return &membership, err
+}
+
+// RemoveMember removes the user from a profile. The last owner cannot be removed.
+func (r *ProfileRepository) RemoveMember(profileID, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var membership types.ProfileMembership
+		if err := tx.Where("profile_id = ? AND user_id = ?", profileID, userID).First(&membership).Error; err != nil {
+			return err
+		}
+		if membership.Role == types.RoleOwner {
+			if err := ensureAnotherOwner(tx, profileID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&membership).Error
+	})
+}
+
+// ErrLastOwner is returned when a change would leave a profile without an owner
+var ErrLastOwner = errors.New("a profile must keep at least one owner")
+
+func ensureAnotherOwner(tx *gorm.DB, profileID string) error {
+	var owners int64
+	if err := tx.Model(&types.ProfileMembership{}).
+		Where("profile_id = ? AND role = ?", profileID, types.RoleOwner).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func newMembership(profileID, userID, role string) *types.ProfileMembership {
+	return &types.ProfileMembership{
+		ID:        uuid.New().String(),
+		ProfileID: profileID,
+		UserID:    userID,
+		Role:      role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"uptime-monitor/models"
+	"uptime-monitor/secrets"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens a fresh database with the schema config.InitConfig migrates
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	keyring, err := secrets.NewKeyring(bytes.Repeat([]byte{1}, secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := secrets.Default()
+	secrets.SetDefault(keyring)
+	t.Cleanup(func() { secrets.SetDefault(previous) })
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&types.Profile{}, &types.ProfileMembership{}, &types.Monitor{}, &types.Log{},
+		&types.SMTPSettings{}, &types.NotificationSettings{}, &types.NotificationMethod{},
+		&types.NotificationDelivery{}, &types.NotificationAttempt{}, &types.NotificationRule{},
+		&types.EscalationPolicy{}, &types.EscalationLevel{}, &types.Escalation{},
+		&types.Incident{}, &types.IncidentEvent{}, &types.MaintenanceWindow{},
+		&types.StatusPage{}, &types.StatusPageComponent{},
+		&models.User{}, &models.Session{}, &models.APIToken{}, &models.APITokenUsage{},
+		&services.Credential{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDeleteProfile(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewProfileRepository(db)
+
+	// seed gives a profile one row in every table it owns
+	seed := func(p string) {
+		t.Helper()
+		rows := []interface{}{
+			&types.Profile{ID: p, Name: p},
+			&types.ProfileMembership{ID: p + "-member", ProfileID: p, UserID: p + "-user", Role: types.RoleOwner},
+			&types.Monitor{ID: p + "-monitor", ProfileID: p, Name: "api"},
+			&types.Log{ID: p + "-log", MonitorID: p + "-monitor"},
+			&types.NotificationSettings{ID: p + "-settings", ProfileID: p},
+			&types.NotificationMethod{ID: p + "-method", ProfileID: p, Type: "webhook", Config: secrets.JSON(`{"url":"https://example.com"}`)},
+			&types.NotificationRule{ID: p + "-rule", ProfileID: p, Name: "all"},
+			&types.NotificationDelivery{ID: p + "-delivery", ProfileID: p, MethodID: p + "-method"},
+			&types.NotificationAttempt{ID: p + "-attempt", DeliveryID: p + "-delivery"},
+			&types.EscalationPolicy{ID: p + "-policy", ProfileID: p, Name: "on-call"},
+			&types.EscalationLevel{ID: p + "-level", PolicyID: p + "-policy"},
+			&types.Incident{ID: p + "-incident", ProfileID: p, MonitorID: p + "-monitor"},
+			&types.IncidentEvent{ID: p + "-event", IncidentID: p + "-incident"},
+			&types.Escalation{ID: p + "-escalation", ProfileID: p, PolicyID: p + "-policy", IncidentID: p + "-incident"},
+			&types.MaintenanceWindow{ID: p + "-window", ProfileID: p, Name: "backups"},
+			&types.StatusPage{ID: p + "-page", ProfileID: p, Slug: p},
+			&types.StatusPageComponent{ID: p + "-component", StatusPageID: p + "-page"},
+			&services.Credential{ID: p + "-credential", ProfileID: p, Name: "api", Type: "bearer", Token: "t0k3n"},
+		}
+		for _, row := range rows {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatalf("Create(%T) error = %v", row, err)
+			}
+		}
+	}
+	seed("doomed")
+	seed("kept")
+
+	if err := repo.DeleteProfile("doomed"); err != nil {
+		t.Fatalf("DeleteProfile() error = %v", err)
+	}
+
+	for _, model := range []interface{}{
+		&types.Profile{}, &types.ProfileMembership{}, &types.Monitor{}, &types.Log{},
+		&types.NotificationSettings{}, &types.NotificationMethod{}, &types.NotificationRule{},
+		&types.NotificationDelivery{}, &types.NotificationAttempt{},
+		&types.EscalationPolicy{}, &types.EscalationLevel{}, &types.Escalation{},
+		&types.Incident{}, &types.IncidentEvent{}, &types.MaintenanceWindow{},
+		&types.StatusPage{}, &types.StatusPageComponent{}, &services.Credential{},
+	} {
+		var ids []string
+		if err := db.Model(model).Order("id").Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		// Only the rows of the other profile are left
+		if len(ids) != 1 || !strings.HasPrefix(ids[0], "kept") {
+			t.Errorf("%T rows after DeleteProfile() = %v, want only those of the kept profile", model, ids)
+		}
+	}
+
+	if err := repo.DeleteProfile("doomed"); err == nil {
+		t.Error("DeleteProfile() of a deleted profile succeeded")
+	}
+}
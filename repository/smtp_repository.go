@@ -17,49 +17,30 @@ func NewSMTPRepository(db *gorm.DB) *SMTPRepository {
 	return &SMTPRepository{db: db}
 }
 
-func (r *SMTPRepository) GetAllSMTPSettings() ([]models.SMTPSettings, error) {
+// GetAllSMTPSettings returns the SMTP settings of a profile
+func (r *SMTPRepository) GetAllSMTPSettings(profileID string) ([]models.SMTPSettings, error) {
 	var settings []models.SMTPSettings
-
-	// Get the active profile first
-	var activeProfile models.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		return nil, err
-	}
-
-	// Get SMTP settings for the active profile
-	err := r.db.Where("profile_id = ?", activeProfile.ID).Find(&settings).Error
+	err := r.db.Where("profile_id = ?", profileID).Find(&settings).Error
 	return settings, err
 }
 
-func (r *SMTPRepository) GetSMTPSettings() (*models.SMTPSettings, error) {
+// GetSMTPSettings returns the first SMTP settings of a profile
+func (r *SMTPRepository) GetSMTPSettings(profileID string) (*models.SMTPSettings, error) {
 	var settings models.SMTPSettings
-
-	// Get the active profile first
-	var activeProfile models.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		return nil, err
-	}
-
-	// Get SMTP settings for the active profile
-	err := r.db.Where("profile_id = ?", activeProfile.ID).First(&settings).Error
+	err := r.db.Where("profile_id = ?", profileID).First(&settings).Error
 	return &settings, err
 }
 
-func (r *SMTPRepository) UpdateSMTPSettings(settings *models.SMTPSettings) error {
-	// Get the active profile first
-	var activeProfile models.Profile
-	if err := r.db.Where("is_active = ?", true).First(&activeProfile).Error; err != nil {
-		return err
-	}
-
-	// Set the profile ID for the new settings
-	settings.ProfileID = activeProfile.ID
+// UpdateSMTPSettings stores new SMTP settings for a profile
+func (r *SMTPRepository) UpdateSMTPSettings(profileID string, settings *models.SMTPSettings) error {
+	settings.ProfileID = profileID
 	settings.CreatedAt = time.Now()
 	return r.db.Create(settings).Error
 }
 
-func (r *SMTPRepository) DeleteSMTPSettingsByID(id string) error {
-	return r.db.Where("id = ?", id).Delete(&models.SMTPSettings{}).Error
+// DeleteSMTPSettingsByID deletes SMTP settings of a profile
+func (r *SMTPRepository) DeleteSMTPSettingsByID(profileID, id string) error {
+	return r.db.Where("id = ? AND profile_id = ?", id, profileID).Delete(&models.SMTPSettings{}).Error
 }
 
 func (r *SMTPRepository) DeleteSMTPSettings() error {
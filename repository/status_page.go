@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StatusPageRepository struct {
+	db *gorm.DB
+}
+
+func NewStatusPageRepository(db *gorm.DB) *StatusPageRepository {
+	return &StatusPageRepository{db: db}
+}
+
+// CreateStatusPage adds a status page and its components to a profile
+func (r *StatusPageRepository) CreateStatusPage(profileID string, page *types.StatusPage) error {
+	page.ID = uuid.New().String()
+	page.ProfileID = profileID
+	page.CreatedAt = time.Now()
+	page.UpdatedAt = time.Now()
+	prepareComponents(page)
+	return r.db.Create(page).Error
+}
+
+// GetStatusPages returns the status pages of a profile
+func (r *StatusPageRepository) GetStatusPages(profileID string) ([]types.StatusPage, error) {
+	var pages []types.StatusPage
+	err := r.db.Preload("Components", orderComponents).
+		Where("profile_id = ?", profileID).Order("created_at").Find(&pages).Error
+	return pages, err
+}
+
+// GetStatusPageByID returns a status page of a profile
+func (r *StatusPageRepository) GetStatusPageByID(profileID, id string) (*types.StatusPage, error) {
+	var page types.StatusPage
+	err := r.db.Preload("Components", orderComponents).
+		Where("id = ? AND profile_id = ?", id, profileID).First(&page).Error
+	return &page, err
+}
+
+// GetPublishedStatusPage returns the published status page with the slug, from any profile
+func (r *StatusPageRepository) GetPublishedStatusPage(slug string) (*types.StatusPage, error) {
+	var page types.StatusPage
+	err := r.db.Preload("Components", orderComponents).
+		Where("slug = ? AND published = ?", slug, true).First(&page).Error
+	return &page, err
+}
+
+// SlugTaken reports whether another status page already uses the slug
+func (r *StatusPageRepository) SlugTaken(slug, exceptID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&types.StatusPage{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateStatusPage saves a status page and replaces its components
+func (r *StatusPageRepository) UpdateStatusPage(page *types.StatusPage) error {
+	page.UpdatedAt = time.Now()
+	prepareComponents(page)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status_page_id = ?", page.ID).Delete(&types.StatusPageComponent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Components").Save(page).Error; err != nil {
+			return err
+		}
+		if len(page.Components) == 0 {
+			return nil
+		}
+		return tx.Create(&page.Components).Error
+	})
+}
+
+// DeleteStatusPage removes a status page of a profile and its components
+func (r *StatusPageRepository) DeleteStatusPage(profileID, id string) error {
+	page, err := r.GetStatusPageByID(profileID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status_page_id = ?", page.ID).Delete(&types.StatusPageComponent{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Components").Delete(page).Error
+	})
+}
+
+// prepareComponents assigns IDs and positions to a page's components
+func prepareComponents(page *types.StatusPage) {
+	for i := range page.Components {
+		page.Components[i].ID = uuid.New().String()
+		page.Components[i].StatusPageID = page.ID
+		page.Components[i].Position = i
+	}
+}
+
+func orderComponents(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
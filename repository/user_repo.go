@@ -1,36 +1,219 @@
 package repository
 
 import (
-	"database/sql"
+	"errors"
+	"time"
 	"uptime-monitor/models"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// UserRepository handles database operations for users
+// ErrInvalidLogin is returned for an unknown username or a wrong password
+var ErrInvalidLogin = errors.New("invalid username or password")
+
+// dummyPasswordHash is a bcrypt hash compared against when the username is unknown
+const dummyPasswordHash = "$2a$10$TXmxccRGfadGP60/jjvYseO/.AlNkSh3bT.7c7CQpNw59o0fcW1PO"
+
+// UserRepository handles database operations for users and their sessions
 type UserRepository struct {
-	db *sql.DB
+	db *gorm.DB
 }
 
 // NewUserRepository creates a new UserRepository
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// CreateUser creates a new user record in the database
-func (r *UserRepository) CreateUser(user models.User) error {
-	query := "INSERT INTO users (username, email, password) VALUES ($1, $2, $3)"
-	_, err := r.db.Exec(query, user.Username, user.Email, user.Password)
-	return err
+// CreateUser hashes the user's password and creates the user record
+func (r *UserRepository) CreateUser(user *models.User) error {
+	hash, err := services.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.ID = uuid.New().String()
+	user.PasswordHash = hash
+	user.Password = ""
+	return r.db.Create(user).Error
+}
+
+// GetUsers returns all users ordered by username
+func (r *UserRepository) GetUsers() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("username").Find(&users).Error
+	return users, err
 }
 
 // GetUserByID retrieves a user by its ID
-func (r *UserRepository) GetUserByID(id int) (models.User, error) {
-	query := "SELECT id, username, email, password FROM users WHERE id = $1"
-	row := r.db.QueryRow(query, id)
+func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("id = ?", id).First(&user).Error
+	return &user, err
+}
 
+// GetUserByUsername retrieves a user by username
+func (r *UserRepository) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password); err != nil {
-		return user, err
+	err := r.db.Where("username = ?", username).First(&user).Error
+	return &user, err
+}
+
+// UsernameTaken reports whether another user already has the username
+func (r *UserRepository) UsernameTaken(username string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+// SetDefaultProfile sets the profile used for the user's requests that name none
+func (r *UserRepository) SetDefaultProfile(userID, profileID string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("default_profile_id", profileID).Error
+}
+
+// UpdatePassword sets a new password and signs the user out everywhere
+func (r *UserRepository) UpdatePassword(userID, password string) error {
+	hash, err := services.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", hash).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+	})
+}
+
+// DeleteUser deletes a user with their sessions, personal API tokens and
+// profile memberships
+func (r *UserRepository) DeleteUser(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.APIToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&types.ProfileMembership{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// EnsureAdminUser creates an admin account when there are no users yet. An
+// empty username defaults to "admin" and an empty password is generated. A
+// generated password is handed to savePassword before the account is created,
+// so that it is never lost, and returned.
+func (r *UserRepository) EnsureAdminUser(username, password string, savePassword func(string) error) (string, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil || count > 0 {
+		return "", err
+	}
+
+	if username == "" {
+		username = "admin"
+	}
+	generated := ""
+	if password == "" {
+		token, err := services.NewSecretToken()
+		if err != nil {
+			return "", err
+		}
+		password = token[:24]
+		generated = password
+		if err := savePassword(password); err != nil {
+			return "", err
+		}
 	}
 
+	admin := &models.User{Username: username, Password: password, IsAdmin: true}
+	return generated, r.CreateUser(admin)
+}
+
+// Authenticate checks a username and password and records the login
+func (r *UserRepository) Authenticate(username, password string) (*models.User, error) {
+	user, err := r.GetUserByUsername(username)
+	if err != nil {
+		// Compare against a dummy hash so unknown users take as long as wrong passwords
+		services.CheckPassword(dummyPasswordHash, password)
+		return nil, ErrInvalidLogin
+	}
+	if !services.CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidLogin
+	}
+
+	now := time.Now()
+	user.LastLoginAt = &now
+	if err := r.db.Model(user).Update("last_login_at", now).Error; err != nil {
+		return nil, err
+	}
 	return user, nil
 }
+
+// CreateSession starts a session for the user and returns its token
+func (r *UserRepository) CreateSession(userID, userAgent, ip string, ttl time.Duration) (string, *models.Session, error) {
+	token, err := services.NewSecretToken()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	session := &models.Session{
+		ID:         services.HashToken(token),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		ExpiresAt:  now.Add(ttl),
+		LastSeenAt: now,
+		CreatedAt:  now,
+	}
+	if err := r.db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// GetSession returns the unexpired session for a token together with its user
+func (r *UserRepository) GetSession(token string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Preload("User").
+		Where("id = ? AND expires_at > ?", services.HashToken(token), time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	if session.User == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+// TouchSession records activity on a session and slides its expiry
+func (r *UserRepository) TouchSession(session *models.Session, ttl time.Duration) error {
+	now := time.Now()
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(ttl)
+	return r.db.Model(session).Updates(map[string]interface{}{
+		"last_seen_at": session.LastSeenAt,
+		"expires_at":   session.ExpiresAt,
+	}).Error
+}
+
+// DeleteSession ends the session for a token
+func (r *UserRepository) DeleteSession(token string) error {
+	return r.db.Where("id = ?", services.HashToken(token)).Delete(&models.Session{}).Error
+}
+
+// DeleteExpiredSessions removes sessions that have expired
+func (r *UserRepository) DeleteExpiredSessions() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&models.Session{}).Error
+}
@@ -40,7 +40,7 @@ func SetupNotificationRoutes(router *gin.Engine, notificationRepo *repository.No
 				return
 			}
 
-			// Get active profile ID from context
+			// Get the request profile ID from context
 			profileID := c.GetString("profile_id")
 			if profileID == "" {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized - No profile ID found"})
@@ -72,7 +72,7 @@ func SetupNotificationRoutes(router *gin.Engine, notificationRepo *repository.No
 				return
 			}
 
-			// Get active profile ID from context
+			// Get the request profile ID from context
 			profileID := c.GetString("profile_id")
 			if profileID == "" {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized - No profile ID found"})
@@ -11,12 +11,52 @@ import (
 )
 
 // SetupRoutes initializes the API endpoints
-func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository, logRepo *repository.LogRepository, smtpRepo *repository.SMTPRepository, profileRepo *repository.ProfileRepository, credentialsService *services.CredentialsService) {
-	monitorController := controllers.NewMonitorController(monitorRepo)
+func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository, logRepo *repository.LogRepository, smtpRepo *repository.SMTPRepository, profileRepo *repository.ProfileRepository, credentialsService *services.CredentialsService, checkers *services.CheckerRegistry, notifiers *services.NotifierRegistry, incidentRepo *repository.IncidentRepository, maintenanceRepo *repository.MaintenanceRepository, statusPageRepo *repository.StatusPageRepository, userRepo *repository.UserRepository, apiTokenRepo *repository.APITokenRepository, deliveryRepo *repository.DeliveryRepository, ruleRepo *repository.NotificationRuleRepository, escalationRepo *repository.EscalationRepository) {
+	authController := controllers.NewAuthController(userRepo, apiTokenRepo, profileRepo)
+	apiTokenController := controllers.NewAPITokenController(apiTokenRepo, profileRepo)
+	userController := controllers.NewUserController(userRepo)
+	monitorController := controllers.NewMonitorController(monitorRepo, checkers)
 	logController := controllers.NewLogController(logRepo)
+	statsController := controllers.NewStatsController(monitorRepo, logRepo, maintenanceRepo)
+	incidentController := controllers.NewIncidentController(incidentRepo)
+	maintenanceController := controllers.NewMaintenanceController(maintenanceRepo)
+	statusPageController := controllers.NewStatusPageController(statusPageRepo, monitorRepo, logRepo, incidentRepo, maintenanceRepo)
 	smtpController := controllers.NewSMTPController(smtpRepo)
-	profileController := controllers.NewProfileController(profileRepo)
+	profileController := controllers.NewProfileController(profileRepo, userRepo, notifiers)
 	credentialsController := controllers.NewCredentialsController(credentialsService)
+	deliveryController := controllers.NewDeliveryController(deliveryRepo)
+	ruleController := controllers.NewNotificationRuleController(ruleRepo, profileRepo, monitorRepo)
+	escalationController := controllers.NewEscalationController(escalationRepo, profileRepo)
+
+	// Every route registered below requires a signed-in user unless it is public,
+	// and a role in the request's profile for the profile's data. Main serves the
+	// router through controllers.WithProfilePath, so the profile's routes are also
+	// reachable as /api/profiles/:pid/monitors and so on.
+	router.Use(authController.RequireAuth(), authController.RequireProfileAccess())
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// Authentication routes
+	router.POST("/api/auth/login", authController.Login)
+	router.POST("/api/auth/logout", authController.Logout)
+	router.GET("/api/auth/me", authController.Me)
+	router.POST("/api/auth/password", authController.ChangePassword)
+
+	// User management routes, admins only
+	users := router.Group("/api/users", authController.RequireAdmin())
+	users.GET("", userController.GetUsers)
+	users.POST("", userController.CreateUser)
+	users.GET("/:id", userController.GetUserByID)
+	users.DELETE("/:id", userController.DeleteUser)
+
+	// API token routes, only reachable with a session
+	router.GET("/api/tokens", apiTokenController.GetTokens)
+	router.POST("/api/tokens", apiTokenController.CreateToken)
+	router.DELETE("/api/tokens/:id", apiTokenController.RevokeToken)
+	router.GET("/api/tokens/:id/usage", apiTokenController.GetTokenUsage)
 
 	// Profile routes
 	router.GET("/api/profiles", profileController.GetAllProfiles)
@@ -26,6 +66,9 @@ func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository,
 	router.PUT("/api/profiles/:id", profileController.UpdateProfile)
 	router.DELETE("/api/profiles/:id", profileController.DeleteProfile)
 	router.POST("/api/profiles/:id/activate", profileController.SetActiveProfile)
+	router.GET("/api/profiles/:id/members", profileController.GetMembers)
+	router.PUT("/api/profiles/:id/members/:user_id", profileController.SetMember)
+	router.DELETE("/api/profiles/:id/members/:user_id", profileController.RemoveMember)
 
 	// Monitor routes
 	router.GET("/api/monitors", monitorController.GetAllMonitors)
@@ -33,10 +76,40 @@ func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository,
 	router.GET("/api/monitors/:id", monitorController.GetMonitor)
 	router.PUT("/api/monitors/:id", monitorController.UpdateMonitor)
 	router.DELETE("/api/monitors/:id", monitorController.DeleteMonitor)
+	router.POST("/api/monitors/:id/check", monitorController.CheckMonitor)
+
+	// Uptime and SLA stats routes
+	router.GET("/api/monitors/:id/stats", statsController.GetMonitorStats)
+	router.GET("/api/stats/summary", statsController.GetSummary)
+
+	// Incident routes
+	router.GET("/api/incidents", incidentController.GetIncidents)
+	router.GET("/api/incidents/:id", incidentController.GetIncident)
+	router.POST("/api/incidents/:id/acknowledge", incidentController.AcknowledgeIncident)
+	router.POST("/api/incidents/:id/resolve", incidentController.ResolveIncident)
+	router.POST("/api/incidents/:id/comments", incidentController.AddComment)
+
+	// Maintenance window routes
+	router.GET("/api/maintenance", maintenanceController.GetWindows)
+	router.POST("/api/maintenance", maintenanceController.CreateWindow)
+	router.GET("/api/maintenance/active", maintenanceController.GetActiveWindows)
+	router.POST("/api/maintenance/start", maintenanceController.StartWindow)
+	router.GET("/api/maintenance/:id", maintenanceController.GetWindow)
+	router.PUT("/api/maintenance/:id", maintenanceController.UpdateWindow)
+	router.DELETE("/api/maintenance/:id", maintenanceController.DeleteWindow)
+	router.POST("/api/maintenance/:id/end", maintenanceController.EndWindow)
+
+	// Status page routes
+	router.GET("/api/status-pages", statusPageController.GetStatusPages)
+	router.POST("/api/status-pages", statusPageController.CreateStatusPage)
+	router.GET("/api/status-pages/:id", statusPageController.GetStatusPage)
+	router.PUT("/api/status-pages/:id", statusPageController.UpdateStatusPage)
+	router.DELETE("/api/status-pages/:id", statusPageController.DeleteStatusPage)
 
 	// Log routes
 	router.POST("/logs", logController.CreateLog)
 	router.GET("/logs/:monitor_id", logController.GetLogsByMonitor)
+	router.GET("/logs/:monitor_id/response_codes", logController.GetResponseCodeCounts)
 
 	// SMTP routes
 	router.GET("/api/smtp_settings", smtpController.GetSMTPSettings)
@@ -56,6 +129,30 @@ func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository,
 	router.PUT("/api/notifications/methods/:id", profileController.UpdateNotificationMethod)
 	router.DELETE("/api/notifications/methods/:id", profileController.DeleteNotificationMethod)
 
+	// Notification routing rule routes
+	router.GET("/api/notifications/rules", ruleController.GetRules)
+	router.POST("/api/notifications/rules", ruleController.CreateRule)
+	router.GET("/api/notifications/rules/:id", ruleController.GetRule)
+	router.PUT("/api/notifications/rules/:id", ruleController.UpdateRule)
+	router.DELETE("/api/notifications/rules/:id", ruleController.DeleteRule)
+
+	// Escalation policy routes
+	router.GET("/api/notifications/escalation-policies", escalationController.GetPolicies)
+	router.POST("/api/notifications/escalation-policies", escalationController.CreatePolicy)
+	router.GET("/api/notifications/escalation-policies/:id", escalationController.GetPolicy)
+	router.PUT("/api/notifications/escalation-policies/:id", escalationController.UpdatePolicy)
+	router.DELETE("/api/notifications/escalation-policies/:id", escalationController.DeletePolicy)
+	router.GET("/api/notifications/escalations", escalationController.GetEscalations)
+
+	// Notification template routes
+	router.GET("/api/notifications/templates/:type", profileController.GetDefaultTemplates)
+	router.POST("/api/notifications/templates/preview", profileController.PreviewTemplates)
+
+	// Notification delivery log routes
+	router.GET("/api/notifications/deliveries", deliveryController.GetDeliveries)
+	router.GET("/api/notifications/deliveries/:id", deliveryController.GetDelivery)
+	router.POST("/api/notifications/deliveries/:id/resend", deliveryController.ResendDelivery)
+
 	// Static routes and pages
 	router.LoadHTMLGlob("static/*.html")
 	router.GET("/", func(c *gin.Context) {
@@ -70,12 +167,18 @@ func SetupRoutes(router *gin.Engine, monitorRepo *repository.MonitorRepository,
 	router.GET("/credentials", func(c *gin.Context) {
 		c.File("static/credentials.html")
 	})
+	router.GET("/login", func(c *gin.Context) {
+		c.File("static/login.html")
+	})
 	router.Static("/css", "static/css")
+
+	// Public, read-only status pages
+	router.GET("/status/:slug", statusPageController.ShowStatusPage)
 }
 
 func getMonitors(repo *repository.MonitorRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		monitors, err := repo.GetAllMonitors()
+		monitors, err := repo.GetAllMonitors(controllers.RequestProfileID(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -92,6 +195,7 @@ func createMonitor(repo *repository.MonitorRepository) gin.HandlerFunc {
 			return
 		}
 
+		monitor.ProfileID = controllers.RequestProfileID(c)
 		if err := repo.CreateMonitor(&monitor); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -123,7 +227,7 @@ func updateMonitor(repo *repository.MonitorRepository) gin.HandlerFunc {
 func deleteMonitor(repo *repository.MonitorRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		if err := repo.DeleteMonitor(id); err != nil {
+		if err := repo.DeleteMonitor(controllers.RequestProfileID(c), id); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -0,0 +1,274 @@
+package secrets
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errNoKeyring = errors.New("secrets: no master key loaded")
+
+// maskPrefix starts every masked hint
+const maskPrefix = "••••"
+
+// Mask returns a hint for a secret that doesn't reveal it: the last four
+// characters of long values, nothing of short ones
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if r := []rune(value); len(r) >= 16 {
+		return maskPrefix + string(r[len(r)-4:])
+	}
+	return maskPrefix + maskPrefix
+}
+
+// IsMasked reports whether a value is a hint returned by Mask, as sent back by
+// clients that edit a record without changing its secrets
+func IsMasked(value string) bool {
+	return strings.HasPrefix(value, maskPrefix)
+}
+
+// String is a string column stored encrypted with the default keyring. It is
+// masked when marshalled to JSON, so it never appears in API responses.
+type String string
+
+// Value encrypts the string for the database
+func (s String) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	k := Default()
+	if k == nil {
+		return nil, errNoKeyring
+	}
+	return k.Encrypt(string(s))
+}
+
+// Scan decrypts a value read from the database
+func (s *String) Scan(src interface{}) error {
+	value, err := scanString(src)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decrypt(value)
+	*s = String(plaintext)
+	return err
+}
+
+// MarshalJSON writes the masked hint of the string
+func (s String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Mask(string(s)))
+}
+
+// JSON is a JSON object column whose secret fields (see IsSecretField) are
+// stored encrypted. It is marshalled to JSON with those fields masked.
+type JSON []byte
+
+// Value encrypts the secret fields for the database
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	k := Default()
+	if k == nil {
+		return nil, errNoKeyring
+	}
+	sealed, err := transformSecrets(j, func(value string) (string, error) {
+		if IsEncrypted(value) {
+			return value, nil
+		}
+		return k.Encrypt(value)
+	})
+	return string(sealed), err
+}
+
+// Scan decrypts the secret fields of a value read from the database
+func (j *JSON) Scan(src interface{}) error {
+	value, err := scanString(src)
+	if err != nil {
+		return err
+	}
+	opened, err := transformSecrets([]byte(value), decrypt)
+	*j = opened
+	return err
+}
+
+// MarshalJSON writes the object with its secret fields masked
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return transformSecrets(j, func(value string) (string, error) {
+		return Mask(value), nil
+	})
+}
+
+// UnmarshalJSON stores the raw object, like json.RawMessage
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[0:0], data...)
+	return nil
+}
+
+// KeepMasked returns j with the secret fields a client sent back masked
+// replaced by their values in previous
+func (j JSON) KeepMasked(previous JSON) JSON {
+	var current, old map[string]json.RawMessage
+	if json.Unmarshal(j, &current) != nil || json.Unmarshal(previous, &old) != nil {
+		return j
+	}
+	for name, raw := range current {
+		var value string
+		if IsSecretField(name) && json.Unmarshal(raw, &value) == nil && IsMasked(value) {
+			if prev, ok := old[name]; ok {
+				current[name] = prev
+			} else {
+				delete(current, name)
+			}
+		} else if isObject(raw) && isObject(old[name]) {
+			current[name] = json.RawMessage(JSON(raw).KeepMasked(JSON(old[name])))
+		}
+	}
+	merged, err := json.Marshal(current)
+	if err != nil {
+		return j
+	}
+	return merged
+}
+
+// JSONText is a string column holding a JSON object, like request headers,
+// whose secret fields (see IsSecretField) are stored encrypted. It is
+// marshalled to JSON as a string, with those fields masked.
+type JSONText string
+
+// Value encrypts the secret fields for the database
+func (t JSONText) Value() (driver.Value, error) {
+	return JSON(t).Value()
+}
+
+// Scan decrypts the secret fields of a value read from the database
+func (t *JSONText) Scan(src interface{}) error {
+	var j JSON
+	err := j.Scan(src)
+	*t = JSONText(j)
+	return err
+}
+
+// MarshalJSON writes the object with its secret fields masked, as a string
+func (t JSONText) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Masked())
+}
+
+// Masked returns the object with its secret fields masked, to show or log
+func (t JSONText) Masked() string {
+	masked, err := transformSecrets([]byte(t), func(value string) (string, error) {
+		return Mask(value), nil
+	})
+	if err != nil {
+		return ""
+	}
+	return string(masked)
+}
+
+// KeepMasked returns t with the secret fields a client sent back masked
+// replaced by their values in previous
+func (t JSONText) KeepMasked(previous JSONText) JSONText {
+	if t == "" {
+		return t
+	}
+	return JSONText(JSON(t).KeepMasked(JSON(previous)))
+}
+
+// IsSecretField reports whether a JSON field holds a secret: passwords,
+// secrets, tokens, keys, webhook URLs, which embed their credentials, and
+// the authorization and cookie headers
+func IsSecretField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") ||
+		strings.Contains(name, "token") || strings.HasSuffix(name, "key") ||
+		name == "webhook_url" || strings.HasSuffix(name, "authorization") || name == "cookie"
+}
+
+// ResealJSON reseals the secret fields of a stored JSON object that are
+// plaintext or sealed with a previous master key, and reports whether any were
+func (k *Keyring) ResealJSON(raw []byte) ([]byte, bool, error) {
+	changed := false
+	resealed, err := transformSecrets(raw, func(value string) (string, error) {
+		if !k.NeedsReseal(value) {
+			return value, nil
+		}
+		changed = true
+		return k.Reseal(value)
+	})
+	return resealed, changed, err
+}
+
+// transformSecrets applies fn to the string values of the secret fields of a
+// JSON object, including nested objects. Other JSON is returned unchanged.
+func transformSecrets(raw []byte, fn func(string) (string, error)) ([]byte, error) {
+	if !isObject(raw) {
+		return raw, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return raw, nil
+	}
+
+	for name, value := range fields {
+		var s string
+		switch {
+		case IsSecretField(name) && json.Unmarshal(value, &s) == nil:
+			if s == "" {
+				continue
+			}
+			transformed, err := fn(s)
+			if err != nil {
+				return nil, err
+			}
+			if fields[name], err = json.Marshal(transformed); err != nil {
+				return nil, err
+			}
+		case isObject(value):
+			transformed, err := transformSecrets(value, fn)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = transformed
+		}
+	}
+	return json.Marshal(fields)
+}
+
+func isObject(raw []byte) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func scanString(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("secrets: cannot scan %T", src)
+	}
+}
+
+// decrypt opens a stored value with the default keyring; plaintext values
+// written before encryption was enabled are returned unchanged
+func decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	k := Default()
+	if k == nil {
+		return "", errNoKeyring
+	}
+	return k.Decrypt(value)
+}
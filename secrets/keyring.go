@@ -0,0 +1,197 @@
+// Package secrets encrypts secrets stored in the database with envelope
+// encryption: every value is sealed with its own random data key, and the data
+// key is sealed with the master key. Rotating the master key only re-seals the
+// data keys.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of master and data keys in bytes (AES-256)
+const KeySize = 32
+
+// prefix marks encrypted values, which look like
+// enc:v1:<master key id>:<sealed data key>:<sealed value>
+const prefix = "enc:v1:"
+
+var encoding = base64.RawURLEncoding
+
+// ErrUnknownKey is returned for values sealed with a master key that isn't loaded
+var ErrUnknownKey = errors.New("secrets: value was encrypted with an unknown master key")
+
+// Keyring holds the master keys. The first key seals new values, the others
+// only open values sealed before a key rotation.
+type Keyring struct {
+	keys []masterKey
+}
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewKeyring creates a keyring that seals with current and can still open
+// values sealed with the previous keys
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{}
+	for _, key := range append([][]byte{current}, previous...) {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		k.keys = append(k.keys, masterKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	return k, nil
+}
+
+// KeyID identifies the current master key without revealing it
+func (k *Keyring) KeyID() string {
+	return k.keys[0].id
+}
+
+// Encrypt seals a value with a new data key under the current master key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealedValue, err := seal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(dataKey, sealedValue)
+}
+
+// Decrypt opens a value sealed by Encrypt. Values without the encryption
+// prefix are returned unchanged, so rows written before encryption was
+// enabled stay readable until they are migrated.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	dataKey, sealedValue, err := k.open(value)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := unseal(aead, sealedValue, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets: failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReseal reports whether a stored value is plaintext or sealed with a
+// previous master key
+func (k *Keyring) NeedsReseal(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return id != k.keys[0].id
+}
+
+// Reseal encrypts a plaintext value, or re-seals the data key of a value sealed
+// with a previous master key under the current one. The value itself is not
+// re-encrypted.
+func (k *Keyring) Reseal(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return k.Encrypt(value)
+	}
+	dataKey, sealedValue, err := k.open(value)
+	if err != nil {
+		return "", err
+	}
+
+	return k.wrap(dataKey, sealedValue)
+}
+
+// wrap seals the data key under the current master key and formats the value
+func (k *Keyring) wrap(dataKey, sealedValue []byte) (string, error) {
+	current := k.keys[0]
+	sealedKey, err := seal(current.aead, dataKey, []byte(current.id))
+	if err != nil {
+		return "", err
+	}
+	return prefix + current.id + ":" + encoding.EncodeToString(sealedKey) + ":" + encoding.EncodeToString(sealedValue), nil
+}
+
+// open splits an encrypted value and unseals its data key
+func (k *Keyring) open(value string) (dataKey, sealedValue []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("secrets: malformed encrypted value")
+	}
+	sealedKey, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.New("secrets: malformed encrypted value")
+	}
+	if sealedValue, err = encoding.DecodeString(parts[2]); err != nil {
+		return nil, nil, errors.New("secrets: malformed encrypted value")
+	}
+
+	for _, key := range k.keys {
+		if key.id != parts[0] {
+			continue
+		}
+		if dataKey, err = unseal(key.aead, sealedKey, []byte(key.id)); err != nil {
+			return nil, nil, fmt.Errorf("secrets: failed to decrypt data key: %w", err)
+		}
+		return dataKey, sealedValue, nil
+	}
+	return nil, nil, ErrUnknownKey
+}
+
+// IsEncrypted reports whether a stored value was sealed by a keyring
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secrets: keys must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data with a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additional), nil
+}
+
+func unseal(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
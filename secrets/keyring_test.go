@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestKeyring(t *testing.T, current []byte, previous ...[]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(current, previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	if _, err := NewKeyring([]byte("short")); err == nil {
+		t.Error("NewKeyring() accepted a short key")
+	}
+	if _, err := NewKeyring(testKey(1), []byte("short")); err == nil {
+		t.Error("NewKeyring() accepted a short previous key")
+	}
+	if newTestKeyring(t, testKey(1)).KeyID() == newTestKeyring(t, testKey(2)).KeyID() {
+		t.Error("KeyID() is the same for different keys")
+	}
+}
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+	k := newTestKeyring(t, testKey(1))
+
+	for _, plaintext := range []string{"", "hunter2", "päss wörd:with:colons", strings.Repeat("x", 4096)} {
+		sealed, err := k.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(sealed) || (plaintext != "" && strings.Contains(sealed, plaintext)) {
+			t.Fatalf("Encrypt(%q) = %q is not sealed", plaintext, sealed)
+		}
+		opened, err := k.Decrypt(sealed)
+		if err != nil || opened != plaintext {
+			t.Fatalf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, opened, err)
+		}
+	}
+
+	// Every value gets its own data key
+	a, _ := k.Encrypt("same")
+	b, _ := k.Encrypt("same")
+	if a == b {
+		t.Error("Encrypt() returned the same ciphertext twice")
+	}
+}
+
+func TestKeyringDecrypt(t *testing.T) {
+	k := newTestKeyring(t, testKey(1))
+	sealed, err := k.Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Change the first character of the sealed value, which holds its nonce
+	i := strings.LastIndex(sealed, ":") + 1
+	replacement := "A"
+	if sealed[i] == 'A' {
+		replacement = "B"
+	}
+	tampered := sealed[:i] + replacement + sealed[i+1:]
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "plaintext is returned as is", keyring: k, value: "legacy", want: "legacy"},
+		{name: "sealed", keyring: k, value: sealed, want: "hunter2"},
+		{name: "unknown key", keyring: newTestKeyring(t, testKey(2)), value: sealed, wantErr: ErrUnknownKey.Error()},
+		{name: "malformed", keyring: k, value: prefix + "abc", wantErr: "malformed encrypted value"},
+		{name: "tampered", keyring: k, value: tampered, wantErr: "failed to decrypt value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Decrypt(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decrypt() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Decrypt() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyringReseal(t *testing.T) {
+	oldKeyring := newTestKeyring(t, testKey(1))
+	sealedOld, err := oldKeyring.Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := newTestKeyring(t, testKey(2), testKey(1))
+	sealedCurrent, err := rotated.Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		value           string
+		wantNeedsReseal bool
+	}{
+		{"empty", "", false},
+		{"plaintext", "hunter2", true},
+		{"previous key", sealedOld, true},
+		{"current key", sealedCurrent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rotated.NeedsReseal(tt.value); got != tt.wantNeedsReseal {
+				t.Fatalf("NeedsReseal() = %v, want %v", got, tt.wantNeedsReseal)
+			}
+			if tt.value == "" {
+				return
+			}
+			resealed, err := rotated.Reseal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rotated.NeedsReseal(resealed) {
+				t.Error("Reseal() returned a value that still needs resealing")
+			}
+			// Once resealed the previous key can be dropped
+			opened, err := newTestKeyring(t, testKey(2)).Decrypt(resealed)
+			if err != nil || opened != "hunter2" {
+				t.Errorf("Decrypt(Reseal()) = %q, %v, want hunter2", opened, err)
+			}
+		})
+	}
+
+	// The previous key alone cannot open resealed values
+	resealed, _ := rotated.Reseal(sealedOld)
+	if _, err := oldKeyring.Decrypt(resealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with the previous key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
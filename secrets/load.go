@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Environment variables that configure the master keys. Keys are base64
+// encoded. The key file holds one key per line, the current key first and
+// previous keys after it.
+const (
+	EnvMasterKey     = "HEIMDALL_MASTER_KEY"
+	EnvMasterKeyFile = "HEIMDALL_MASTER_KEY_FILE"
+	EnvPreviousKeys  = "HEIMDALL_PREVIOUS_MASTER_KEYS" // Comma separated
+)
+
+// DefaultKeyFile is the key file used, and generated if missing, when no
+// master key is configured
+const DefaultKeyFile = "master.key"
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault sets the keyring used by the String and JSON column types
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
+
+// Default returns the keyring set by SetDefault, or nil
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// LoadKeyring loads the master keys from HEIMDALL_MASTER_KEY or the key file.
+// Without either, a new key is generated into DefaultKeyFile and generated is
+// true: that file must be backed up with the database.
+func LoadKeyring() (k *Keyring, generated bool, err error) {
+	var keys []string
+	if key := os.Getenv(EnvMasterKey); key != "" {
+		keys = append(keys, key)
+	} else {
+		path := os.Getenv(EnvMasterKeyFile)
+		if path == "" {
+			path = DefaultKeyFile
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && os.Getenv(EnvMasterKeyFile) == "" {
+			key, err := generateKeyFile(path)
+			if err != nil {
+				return nil, false, err
+			}
+			data, generated = []byte(key), true
+		} else if err != nil {
+			return nil, false, fmt.Errorf("secrets: failed to read master key file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				keys = append(keys, line)
+			}
+		}
+	}
+	for _, key := range strings.Split(os.Getenv(EnvPreviousKeys), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, false, errors.New("secrets: master key file holds no keys")
+	}
+
+	decoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		if decoded[i], err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, false, fmt.Errorf("secrets: master key %d is not valid base64", i+1)
+		}
+	}
+	k, err = NewKeyring(decoded[0], decoded[1:]...)
+	return k, generated, err
+}
+
+// GenerateKey returns a new base64 encoded master key
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func generateKeyFile(path string) (string, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("secrets: failed to write master key file: %w", err)
+	}
+	return key, nil
+}
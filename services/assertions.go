@@ -0,0 +1,261 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"uptime-monitor/types"
+)
+
+const (
+	// defaultMaxBodyBytes is how much of a response body is read for assertions by default
+	defaultMaxBodyBytes int64 = 1 << 20
+	// maxBodyBytesLimit caps the per-monitor MaxBodyBytes setting
+	maxBodyBytesLimit int64 = 10 << 20
+)
+
+// bodyLimit returns the number of body bytes to read for a monitor
+func bodyLimit(monitor *types.Monitor) int64 {
+	switch {
+	case monitor.MaxBodyBytes <= 0:
+		return defaultMaxBodyBytes
+	case monitor.MaxBodyBytes > maxBodyBytesLimit:
+		return maxBodyBytesLimit
+	default:
+		return monitor.MaxBodyBytes
+	}
+}
+
+// readBody reads a response body up to the monitor's size cap
+func readBody(monitor *types.Monitor, r io.Reader) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r, bodyLimit(monitor)))
+}
+
+// ValidateBodyAssertions checks that a monitor's body assertions are well formed
+func ValidateBodyAssertions(monitor *types.Monitor) error {
+	if monitor.BodyRegex != "" {
+		if _, err := regexp.Compile(monitor.BodyRegex); err != nil {
+			return fmt.Errorf("invalid body regex: %v", err)
+		}
+	}
+
+	assertions, err := monitor.GetJSONPathAssertions()
+	if err != nil {
+		return fmt.Errorf("invalid JSONPath assertions: %v", err)
+	}
+	for _, a := range assertions {
+		if _, err := parseJSONPath(a.Path); err != nil {
+			return fmt.Errorf("invalid JSONPath %q: %v", a.Path, err)
+		}
+		switch a.Operator {
+		case "", "equals", "not_equals", "contains", "exists":
+		default:
+			return fmt.Errorf("invalid JSONPath operator %q", a.Operator)
+		}
+	}
+	return nil
+}
+
+// evaluateBodyAssertions runs every body assertion configured on the monitor and
+// returns a description of the first one that failed, or "" if all passed
+func evaluateBodyAssertions(monitor *types.Monitor, body []byte) string {
+	text := string(body)
+
+	if monitor.BodyContains != "" && !strings.Contains(text, monitor.BodyContains) {
+		return fmt.Sprintf("body does not contain %q", monitor.BodyContains)
+	}
+
+	if monitor.BodyNotContains != "" && strings.Contains(text, monitor.BodyNotContains) {
+		return fmt.Sprintf("body contains %q", monitor.BodyNotContains)
+	}
+
+	if monitor.BodyRegex != "" {
+		re, err := regexp.Compile(monitor.BodyRegex)
+		if err != nil {
+			return fmt.Sprintf("invalid body regex: %v", err)
+		}
+		if !re.Match(body) {
+			return fmt.Sprintf("body does not match regex %q", monitor.BodyRegex)
+		}
+	}
+
+	assertions, err := monitor.GetJSONPathAssertions()
+	if err != nil {
+		return fmt.Sprintf("invalid JSONPath assertions: %v", err)
+	}
+	if len(assertions) == 0 {
+		return ""
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Sprintf("body is not valid JSON: %v", err)
+	}
+
+	for _, a := range assertions {
+		if failure := evaluateJSONPathAssertion(doc, a); failure != "" {
+			return failure
+		}
+	}
+	return ""
+}
+
+// evaluateJSONPathAssertion checks a single JSONPath assertion against a decoded document
+func evaluateJSONPathAssertion(doc interface{}, a types.JSONPathAssertion) string {
+	value, found, err := lookupJSONPath(doc, a.Path)
+	if err != nil {
+		return fmt.Sprintf("JSONPath %s: %v", a.Path, err)
+	}
+
+	operator := a.Operator
+	if operator == "" {
+		operator = "equals"
+	}
+
+	if operator == "exists" {
+		if !found {
+			return fmt.Sprintf("JSONPath %s does not exist", a.Path)
+		}
+		return ""
+	}
+	if !found {
+		return fmt.Sprintf("JSONPath %s does not exist (expected %s %q)", a.Path, operator, a.Expected)
+	}
+
+	actual := jsonValueString(value)
+	switch operator {
+	case "equals":
+		if actual != a.Expected {
+			return fmt.Sprintf("JSONPath %s is %q, expected %q", a.Path, actual, a.Expected)
+		}
+	case "not_equals":
+		if actual == a.Expected {
+			return fmt.Sprintf("JSONPath %s is %q, expected it not to be", a.Path, actual)
+		}
+	case "contains":
+		if !strings.Contains(actual, a.Expected) {
+			return fmt.Sprintf("JSONPath %s is %q, expected it to contain %q", a.Path, actual, a.Expected)
+		}
+	default:
+		return fmt.Sprintf("JSONPath %s: unknown operator %q", a.Path, operator)
+	}
+	return ""
+}
+
+// jsonPathSegment is a single step in a JSON path: an object key or an array index
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses a JSONPath ($.a.b[0]) or jq-style (.a.b[0]) path into segments.
+// Quoted keys (["a.b"]) are supported; wildcards and filters are not.
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	if p == "" || p == "." {
+		return nil, nil
+	}
+
+	var segments []jsonPathSegment
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(p) && p[i] != '.' && p[i] != '[' {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("empty key at position %d", start)
+			}
+			segments = append(segments, jsonPathSegment{key: p[start:i]})
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ at position %d", i)
+			}
+			inner := strings.TrimSpace(p[i+1 : i+end])
+			i += end + 1
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, jsonPathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+			segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+		default:
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("unexpected %q at position %d", p[i], i)
+			}
+			// Allow a bare leading key such as "status.code"
+			p = "." + p[i:]
+			i = 0
+		}
+	}
+	return segments, nil
+}
+
+// lookupJSONPath resolves a path in a decoded JSON document. found is false if
+// any segment of the path is missing.
+func lookupJSONPath(doc interface{}, path string) (value interface{}, found bool, err error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	current := doc
+	for _, seg := range segments {
+		if seg.isIndex {
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false, nil
+			}
+			current = arr[idx]
+			continue
+		}
+
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		current, ok = obj[seg.key]
+		if !ok {
+			return nil, false, nil
+		}
+	}
+	return current, true, nil
+}
+
+// jsonValueString renders a decoded JSON value for comparison with an expected string
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
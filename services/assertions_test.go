@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"uptime-monitor/types"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"status": "ok",
+		"data": {"items": [{"id": 1, "healthy": true}, {"id": 2, "healthy": false}], "count": 2.5},
+		"odd key": {"a.b": null},
+		"list": [[10, 20], [30]]
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path      string
+		want      string // jsonValueString of the value
+		wantFound bool
+		wantErr   bool
+	}{
+		{path: "$.status", want: "ok", wantFound: true},
+		{path: ".status", want: "ok", wantFound: true},
+		{path: "status", want: "ok", wantFound: true},
+		{path: "data.count", want: "2.5", wantFound: true},
+		{path: "$.data.items[0].id", want: "1", wantFound: true},
+		{path: "$.data.items[1].healthy", want: "false", wantFound: true},
+		{path: "$.data.items[-1].id", want: "2", wantFound: true},
+		{path: "$.list[0][1]", want: "20", wantFound: true},
+		{path: `$["odd key"]['a.b']`, want: "null", wantFound: true},
+		{path: "$.data.items[0]", want: `{"healthy":true,"id":1}`, wantFound: true},
+		{path: "$", want: "", wantFound: true}, // The whole document
+		{path: "$.missing", wantFound: false},
+		{path: "$.data.items[5]", wantFound: false},
+		{path: "$.data.items[-3]", wantFound: false},
+		{path: "$.status.code", wantFound: false},
+		{path: "$.status[0]", wantFound: false},
+		{path: "$.data.items.id", wantFound: false},
+		{path: "$..status", wantErr: true},
+		{path: "$.data[", wantErr: true},
+		{path: "$.data[x]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, found, err := lookupJSONPath(doc, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupJSONPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("lookupJSONPath() found = %v, want %v", found, tt.wantFound)
+			}
+			if found && tt.want != "" && jsonValueString(value) != tt.want {
+				t.Errorf("lookupJSONPath() = %s, want %s", jsonValueString(value), tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckBodyAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok","version":"1.4.2","checks":{"db":"up"}}`))
+		case "/error":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/large":
+			// The marker comes after the first 2048 bytes
+			w.Write([]byte(strings.Repeat("x", 2048) + "END"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		monitor     types.Monitor
+		wantStatus  string
+		wantMessage string
+	}{
+		{name: "contains", monitor: types.Monitor{BodyContains: `"status":"ok"`}, wantStatus: "up"},
+		{name: "does not contain", monitor: types.Monitor{BodyContains: "maintenance"}, wantStatus: "down", wantMessage: `body does not contain "maintenance"`},
+		{name: "not contains", monitor: types.Monitor{BodyNotContains: "error"}, wantStatus: "up"},
+		{name: "contains what it must not", monitor: types.Monitor{BodyNotContains: `"db":"up"`}, wantStatus: "down", wantMessage: `body contains "\"db\":\"up\""`},
+		{name: "regex", monitor: types.Monitor{BodyRegex: `"version":"1\.\d+\.\d+"`}, wantStatus: "up"},
+		{name: "regex does not match", monitor: types.Monitor{BodyRegex: `"version":"2\.`}, wantStatus: "down", wantMessage: "body does not match regex"},
+		{name: "JSONPath", monitor: types.Monitor{JSONPathAssertions: `[{"path":"$.checks.db","operator":"equals","expected":"up"}]`}, wantStatus: "up"},
+		{name: "JSONPath mismatch", monitor: types.Monitor{JSONPathAssertions: `[{"path":"$.status","operator":"equals","expected":"degraded"}]`}, wantStatus: "down", wantMessage: "JSONPath $.status"},
+		{name: "status code is checked before the body", monitor: types.Monitor{URL: "/error", BodyContains: `"status":"ok"`}, wantStatus: "down", wantMessage: "returned status code 503"},
+		{name: "accepted status code", monitor: types.Monitor{URL: "/error", AcceptedStatusCodes: "503", BodyContains: `"status":"ok"`}, wantStatus: "up"},
+		{name: "body beyond the cap is not read", monitor: types.Monitor{URL: "/large", MaxBodyBytes: 1024, BodyContains: "END"}, wantStatus: "down", wantMessage: `body does not contain "END"`},
+		{name: "body within the cap", monitor: types.Monitor{URL: "/large", MaxBodyBytes: 4096, BodyContains: "END"}, wantStatus: "up"},
+		{name: "cap applies to not contains", monitor: types.Monitor{URL: "/large", MaxBodyBytes: 1024, BodyNotContains: "END"}, wantStatus: "up"},
+	}
+	checker := NewHTTPService(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := tt.monitor
+			monitor.Type = types.MonitorTypeHTTP
+			monitor.Method = "GET"
+			if monitor.URL == "" {
+				monitor.URL = "/health"
+			}
+			monitor.URL = server.URL + monitor.URL
+
+			result := checker.Check(context.Background(), &monitor)
+			if result.Status != tt.wantStatus || !strings.Contains(result.Message, tt.wantMessage) {
+				t.Fatalf("Check() = %s (%s), want %s with %q", result.Status, result.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if tt.wantStatus == "down" && tt.monitor.URL != "/error" && result.ErrorCategory != ErrorCategoryAssertion {
+				t.Errorf("Check() error category = %q, want %q", result.ErrorCategory, ErrorCategoryAssertion)
+			}
+		})
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		maxBodyBytes int64
+		want         int64
+	}{
+		{0, defaultMaxBodyBytes},
+		{-1, defaultMaxBodyBytes},
+		{512, 512},
+		{maxBodyBytesLimit + 1, maxBodyBytesLimit},
+	}
+	for _, tt := range tests {
+		if got := bodyLimit(&types.Monitor{MaxBodyBytes: tt.maxBodyBytes}); got != tt.want {
+			t.Errorf("bodyLimit(%d) = %d, want %d", tt.maxBodyBytes, got, tt.want)
+		}
+	}
+}
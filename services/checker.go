@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"uptime-monitor/types"
+)
+
+// CheckResult is the structured outcome of a single monitor check
+type CheckResult struct {
+	Status       string                 `json:"status"`        // up, down, unauthorized
+	ResponseCode int                    `json:"response_code"` // HTTP status code, 0 for non-HTTP checks
+	ResponseTime int64                  `json:"response_time"` // Latency in milliseconds
+	Message      string                 `json:"message"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"` // Checker-specific details
+	TLS          *TLSCertificateInfo    `json:"tls,omitempty"`      // Leaf certificate details for TLS connections
+
+	ErrorCategory string        `json:"error_category,omitempty"` // Why a failed check failed, see ErrorCategory constants
+	Timings       *CheckTimings `json:"timings,omitempty"`        // Duration breakdown of the check
+
+	Steps      []StepResult `json:"steps,omitempty"`       // Results of the steps a transaction check ran
+	FailedStep string       `json:"failed_step,omitempty"` // Name of the transaction step that failed
+}
+
+// Error categories reported for failed checks
+const (
+	ErrorCategoryDNS        = "dns"
+	ErrorCategoryConnect    = "connect"
+	ErrorCategoryTimeout    = "timeout"
+	ErrorCategoryTLS        = "tls"
+	ErrorCategoryAssertion  = "assertion"
+	ErrorCategoryCredential = "credential"
+)
+
+// CheckTimings breaks the duration of a check down into its network phases, in milliseconds
+type CheckTimings struct {
+	DNSMs     int64 `json:"dns_ms"`
+	ConnectMs int64 `json:"connect_ms"`
+	TLSMs     int64 `json:"tls_ms"`
+	TTFBMs    int64 `json:"ttfb_ms"`
+}
+
+// Checker probes a monitor of one type. Implementations must honour ctx
+// cancellation and report failures through the result rather than panicking.
+type Checker interface {
+	Check(ctx context.Context, monitor *types.Monitor) *CheckResult
+}
+
+// CheckerFunc adapts a plain function to the Checker interface
+type CheckerFunc func(ctx context.Context, monitor *types.Monitor) *CheckResult
+
+// Check calls f(ctx, monitor)
+func (f CheckerFunc) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	return f(ctx, monitor)
+}
+
+// CheckerRegistry maps monitor types to the checker that handles them
+type CheckerRegistry struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+}
+
+// NewCheckerRegistry creates an empty registry
+func NewCheckerRegistry() *CheckerRegistry {
+	return &CheckerRegistry{checkers: make(map[string]Checker)}
+}
+
+// NewDefaultCheckerRegistry creates a registry with all built-in monitor types registered
+func NewDefaultCheckerRegistry(credentials *CredentialsService) *CheckerRegistry {
+	r := NewCheckerRegistry()
+	httpService := NewHTTPService(credentials)
+	r.Register(types.MonitorTypeHTTP, httpService)
+	r.Register(types.MonitorTypeTransaction, NewTransactionChecker(httpService))
+	r.Register(RequestTypeCurl, NewCurlService(credentials))
+	r.Register(types.MonitorTypeTLS, &TLSChecker{})
+	r.Register(types.MonitorTypeTCP, &TCPChecker{})
+	r.Register(types.MonitorTypeDNS, &DNSChecker{})
+
+	databaseChecker := &DatabaseChecker{}
+	r.Register(types.MonitorTypeMySQL, databaseChecker)
+	r.Register(types.MonitorTypePostgres, databaseChecker)
+	r.Register(types.MonitorTypeMongoDB, databaseChecker)
+	r.Register(types.MonitorTypeRedis, databaseChecker)
+	return r
+}
+
+// Register adds or replaces the checker for a monitor type
+func (r *CheckerRegistry) Register(monitorType string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[monitorType] = checker
+}
+
+// Get returns the checker registered for a monitor type
+func (r *CheckerRegistry) Get(monitorType string) (Checker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checker, ok := r.checkers[monitorType]
+	return checker, ok
+}
+
+// Types returns the monitor types that have a registered checker
+func (r *CheckerRegistry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	monitorTypes := make([]string, 0, len(r.checkers))
+	for t := range r.checkers {
+		monitorTypes = append(monitorTypes, t)
+	}
+	return monitorTypes
+}
+
+// Check runs the checker for the monitor's type. An empty type is treated as
+// HTTP, and HTTP monitors with the curl request type use the curl checker.
+func (r *CheckerRegistry) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	key := checkerKey(monitor)
+	checker, ok := r.Get(key)
+	if !ok {
+		return &CheckResult{
+			Status:  "down",
+			Message: fmt.Sprintf("Unsupported monitor type: %s", key),
+		}
+	}
+
+	result := checker.Check(ctx, monitor)
+	if result == nil {
+		return &CheckResult{
+			Status:  "down",
+			Message: fmt.Sprintf("Checker for %s returned no result", key),
+		}
+	}
+	return result
+}
+
+// checkerKey returns the registry key used to look up the checker for a monitor
+func checkerKey(monitor *types.Monitor) string {
+	switch monitor.Type {
+	case "", types.MonitorTypeHTTP:
+		if monitor.IsCurlRequest() {
+			return RequestTypeCurl
+		}
+		return types.MonitorTypeHTTP
+	default:
+		return monitor.Type
+	}
+}
+
+// describeStatusCode builds a human readable message for an HTTP response code
+func describeStatusCode(url string, statusCode int) string {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return fmt.Sprintf("%s returned status code %d (Success)", url, statusCode)
+	case statusCode == 401:
+		return fmt.Sprintf("%s returned status code 401 (Unauthorized)", url)
+	case statusCode == 403:
+		return fmt.Sprintf("%s returned status code 403 (Forbidden)", url)
+	case statusCode == 404:
+		return fmt.Sprintf("%s returned status code 404 (Not Found)", url)
+	case statusCode == 500:
+		return fmt.Sprintf("%s returned status code 500 (Internal Server Error)", url)
+	case statusCode == 502:
+		return fmt.Sprintf("%s returned status code 502 (Bad Gateway)", url)
+	case statusCode == 503:
+		return fmt.Sprintf("%s returned status code 503 (Service Unavailable)", url)
+	case statusCode == 504:
+		return fmt.Sprintf("%s returned status code 504 (Gateway Timeout)", url)
+	default:
+		return fmt.Sprintf("%s returned status code %d", url, statusCode)
+	}
+}
+
+// classifyError returns the error category of a network error, or "" if it
+// does not fall into a known category
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var credErr *credentialError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &credErr):
+		return ErrorCategoryCredential
+	case errors.As(err, &dnsErr):
+		return ErrorCategoryDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorCategoryTimeout
+	case isTLSError(err):
+		return ErrorCategoryTLS
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return ErrorCategoryConnect
+	default:
+		return ErrorCategoryConnect
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"uptime-monitor/types"
+)
+
+func TestCheckerRegistryCheck(t *testing.T) {
+	registry := NewCheckerRegistry()
+	for _, key := range []string{types.MonitorTypeHTTP, RequestTypeCurl, types.MonitorTypeMySQL} {
+		key := key
+		registry.Register(key, CheckerFunc(func(ctx context.Context, monitor *types.Monitor) *CheckResult {
+			return &CheckResult{Status: "up", Message: key}
+		}))
+	}
+	registry.Register("broken", CheckerFunc(func(ctx context.Context, monitor *types.Monitor) *CheckResult {
+		return nil
+	}))
+
+	tests := []struct {
+		name        string
+		monitor     types.Monitor
+		wantStatus  string
+		wantMessage string
+	}{
+		{"empty type is http", types.Monitor{}, "up", types.MonitorTypeHTTP},
+		{"http", types.Monitor{Type: types.MonitorTypeHTTP}, "up", types.MonitorTypeHTTP},
+		{"curl request", types.Monitor{Type: types.MonitorTypeHTTP, RequestType: RequestTypeCurl}, "up", RequestTypeCurl},
+		{"other type", types.Monitor{Type: types.MonitorTypeMySQL}, "up", types.MonitorTypeMySQL},
+		{"curl request type ignored for other types", types.Monitor{Type: types.MonitorTypeMySQL, RequestType: RequestTypeCurl}, "up", types.MonitorTypeMySQL},
+		{"unsupported type", types.Monitor{Type: "gopher"}, "down", "Unsupported monitor type: gopher"},
+		{"no result", types.Monitor{Type: "broken"}, "down", "returned no result"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := registry.Check(context.Background(), &tt.monitor)
+			if result.Status != tt.wantStatus || !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Check() = %s %q, want %s %q", result.Status, result.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestDefaultCheckerRegistryTypes(t *testing.T) {
+	got := NewDefaultCheckerRegistry(nil).Types()
+	sort.Strings(got)
+	want := []string{
+		RequestTypeCurl, types.MonitorTypeDNS, types.MonitorTypeHTTP, types.MonitorTypeMongoDB, types.MonitorTypeMySQL,
+		types.MonitorTypePostgres, types.MonitorTypeRedis, types.MonitorTypeTCP, types.MonitorTypeTLS, types.MonitorTypeTransaction,
+	}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Types() = %v, want %v", got, want)
+	}
+}
@@ -1,99 +1,60 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"uptime-monitor/secrets"
 
 	"gorm.io/gorm"
 )
 
 type CredentialsService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	client *http.Client
+
+	// refreshLocks holds a *sync.Mutex per credential ID, so that concurrent
+	// checks refresh an OAuth2 token only once
+	refreshLocks sync.Map
 }
 
 func NewCredentialsService(db *gorm.DB) *CredentialsService {
-	return &CredentialsService{db: db}
+	return &CredentialsService{
+		db:     db,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
 }
 
 type Credential struct {
-	ID          string `json:"id"`
-	ProfileID   string `json:"profile_id"`
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Token       string `json:"token,omitempty"`
-	Username    string `json:"username,omitempty"`
-	Password    string `json:"password,omitempty"`
-	HeaderName  string `json:"header_name"`
-	HeaderValue string `json:"header_value"`
+	ID          string         `json:"id"`
+	ProfileID   string         `json:"profile_id"`
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Token       secrets.String `json:"token,omitempty"`
+	Username    string         `json:"username,omitempty"`
+	Password    secrets.String `json:"password,omitempty"`
+	HeaderName  string         `json:"header_name"`
+	HeaderValue string         `json:"header_value"`
 
 	// OAuth2 Specific Fields
-	ClientID     string    `json:"client_id,omitempty"`
-	ClientSecret string    `json:"client_secret,omitempty"`
-	RedirectURI  string    `json:"redirect_uri,omitempty"`
-	RefreshToken string    `json:"refresh_token,omitempty"`
-	TokenExpiry  time.Time `json:"token_expiry,omitempty"`
+	ClientID     string         `json:"client_id,omitempty"`
+	ClientSecret secrets.String `json:"client_secret,omitempty"`
+	RedirectURI  string         `json:"redirect_uri,omitempty"`
+	RefreshToken secrets.String `json:"refresh_token,omitempty"`
+	TokenExpiry  time.Time      `json:"token_expiry,omitempty"`
+	TokenURL     string         `json:"token_url,omitempty"`
+	GrantType    string         `json:"grant_type,omitempty"` // client_credentials or refresh_token
+	Scopes       string         `json:"scopes,omitempty"`     // Space separated
+	Audience     string         `json:"audience,omitempty"`
+	RefreshError string         `json:"refresh_error,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (s *CredentialsService) RefreshOAuth2Token(cred *Credential) error {
-	// Validate OAuth2 credential
-	if cred.Type != "oauth2" ||
-		cred.ClientID == "" ||
-		cred.ClientSecret == "" ||
-		cred.RefreshToken == "" {
-		return fmt.Errorf("invalid OAuth2 credential")
-	}
-
-	// Prepare token refresh request
-	data := url.Values{
-		"client_id":     {cred.ClientID},
-		"client_secret": {cred.ClientSecret},
-		"refresh_token": {cred.RefreshToken},
-		"grant_type":    {"refresh_token"},
-	}
-
-	// Make token refresh request
-	resp, err := http.PostForm("https://accounts.zoho.com/oauth/v2/token", data)
-	if err != nil {
-		return fmt.Errorf("failed to refresh token: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse response
-	var tokenResp struct {
-		AccessToken  string `json:"access_token"`
-		RefreshToken string `json:"refresh_token,omitempty"`
-		ExpiresIn    int    `json:"expires_in"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return fmt.Errorf("failed to parse token response: %v", err)
-	}
-
-	// Prepare update
-	updateData := map[string]interface{}{
-		"token":        tokenResp.AccessToken,
-		"updated_at":   time.Now(),
-		"token_expiry": time.Now().Add(time.Second * time.Duration(tokenResp.ExpiresIn)),
-	}
-
-	// Update refresh token if provided
-	if tokenResp.RefreshToken != "" {
-		updateData["refresh_token"] = tokenResp.RefreshToken
-	}
-
-	// Update in database
-	result := s.db.Model(&Credential{}).Where("id = ?", cred.ID).Updates(updateData)
-	return result.Error
-}
-
 // GetCredential retrieves a credential by its ID
 func (s *CredentialsService) GetCredential(credentialID string) (*Credential, error) {
 	var cred Credential
@@ -106,23 +67,24 @@ func (s *CredentialsService) GetCredential(credentialID string) (*Credential, er
 
 // GetHeaderValue returns the appropriate header value based on credential type
 func (s *CredentialsService) GetHeaderValue(cred *Credential) string {
+	token := string(cred.Token)
 	switch cred.Type {
 	case "basic":
-		return fmt.Sprintf("Basic %s", cred.Token)
+		return fmt.Sprintf("Basic %s", token)
 	case "bearer":
 		// Ensure the token doesn't already have "Bearer " prefix
-		if strings.HasPrefix(cred.Token, "Bearer ") {
-			return cred.Token
+		if strings.HasPrefix(token, "Bearer ") {
+			return token
 		}
-		return fmt.Sprintf("Bearer %s", cred.Token)
+		return fmt.Sprintf("Bearer %s", token)
 	case "oauth2":
 		// Ensure the token doesn't already have "Bearer " prefix
-		if strings.HasPrefix(cred.Token, "Bearer ") {
-			return cred.Token
+		if strings.HasPrefix(token, "Bearer ") {
+			return token
 		}
-		return fmt.Sprintf("Bearer %s", cred.Token)
+		return fmt.Sprintf("Bearer %s", token)
 	case "api_key":
-		return cred.Token
+		return token
 	default:
 		return cred.HeaderValue
 	}
@@ -142,14 +104,14 @@ func (s *CredentialsService) GetCredentials(profileID string) ([]Credential, err
 	// Fetch credentials for the specific profile with more detailed query
 	var credentials []Credential
 	result := s.db.Where("profile_id = ?", profileID).
-		Select("id", "profile_id", "name", "type", "header_name", "header_value").
+		Select("id", "profile_id", "name", "type", "header_name", "header_value", "refresh_error", "created_at").
 		Find(&credentials)
 
 	// Log the database query details
 	log.Printf("Database Query Details:")
 	log.Printf("  Profile ID: %s", profileID)
 	log.Printf("  Query Conditions: profile_id = %s", profileID)
-	log.Printf("  Selected Fields: id, profile_id, name, type, header_name, header_value")
+	log.Printf("  Selected Fields: id, profile_id, name, type, header_name, header_value, refresh_error, created_at")
 
 	// Check for database query errors
 	if result.Error != nil {
@@ -182,9 +144,27 @@ func (s *CredentialsService) CreateCredential(cred *Credential) error {
 	return s.db.Create(cred).Error
 }
 
-// UpdateCredential updates an existing credential
+// UpdateCredential updates an existing credential. Empty secrets, and secrets
+// sent back masked, keep their stored values. The access token of an oauth2
+// credential is dropped, so the next check fetches one with the new settings.
+// gorm.ErrRecordNotFound is returned when the profile has no such credential.
 func (s *CredentialsService) UpdateCredential(cred *Credential) error {
-	return s.db.Model(&Credential{}).Where("id = ? AND profile_id = ?", cred.ID, cred.ProfileID).Updates(cred).Error
+	for _, secret := range []*secrets.String{&cred.Token, &cred.Password, &cred.ClientSecret, &cred.RefreshToken} {
+		if secrets.IsMasked(string(*secret)) {
+			*secret = ""
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Credential{}).Where("id = ? AND profile_id = ?", cred.ID, cred.ProfileID).Updates(cred)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&Credential{}).Where("id = ? AND profile_id = ? AND type = ?", cred.ID, cred.ProfileID, "oauth2").
+			Updates(map[string]interface{}{"token": "", "refresh_error": ""}).Error
+	})
 }
 
 // DeleteCredential removes a credential by its ID and profile ID
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression (minute hour day-of-month
+// month day-of-week). Fields accept *, lists, ranges and steps such as
+// "*/15", "1-5" and "0,30". The @hourly, @daily, @weekly and @monthly
+// shortcuts are also accepted.
+type CronSchedule struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+	// Like classic cron, a restricted day-of-month and day-of-week match either
+	anyDay     bool
+	anyWeekday bool
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseCron parses a cron expression
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[expr]; ok {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	c := &CronSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	if err := parseCronField(fields[0], 0, 59, c.minutes[:]); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if err := parseCronField(fields[1], 0, 23, c.hours[:]); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if err := parseCronField(fields[2], 1, 31, c.days[:]); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if err := parseCronField(fields[3], 1, 12, c.months[:]); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	// Sunday may be written as 0 or 7
+	var weekdays [8]bool
+	if err := parseCronField(fields[4], 0, 7, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+	copy(c.weekdays[:], weekdays[:7])
+	c.weekdays[0] = c.weekdays[0] || weekdays[7]
+	return c, nil
+}
+
+// parseCronField sets set[v] for every value v matched by a cron field
+func parseCronField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// Next returns the first time strictly after t that matches the schedule, in
+// t's location, or the zero time if there is none within five years. Like cron,
+// matches in wall clock times skipped by a daylight saving change run when the
+// gap ends. In an hour repeated by the change, schedules that run every hour
+// run again, others only run in the first pass.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		// want is the wall clock time to move on to, as a UTC time
+		var want, next time.Time
+		switch {
+		case !c.months[t.Month()]:
+			want = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			want = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hours[t.Hour()]:
+			want = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		case !c.minutes[t.Minute()]:
+			want = wallClock(t).Add(time.Minute)
+			next = t.Add(time.Minute)
+			if back := want.Sub(wallClock(next)); back > 0 && !c.everyHour() {
+				// Skip the second pass through the hour the clock went back by
+				next = next.Add(back)
+			}
+		default:
+			return t
+		}
+		if next.IsZero() {
+			next = atWallClock(want, t.Location())
+		}
+		if c.skippedMatch(want, next) {
+			return next
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// skippedMatch reports whether one of the wall clock times from want up to
+// next's, which a daylight saving change left out, matches the schedule
+func (c *CronSchedule) skippedMatch(want, next time.Time) bool {
+	for skipped := want; skipped.Before(wallClock(next)); skipped = skipped.Add(time.Minute) {
+		if c.months[skipped.Month()] && c.dayMatches(skipped) && c.hours[skipped.Hour()] && c.minutes[skipped.Minute()] {
+			return true
+		}
+	}
+	return false
+}
+
+// everyHour reports whether the schedule matches every hour of the day
+func (c *CronSchedule) everyHour() bool {
+	for _, match := range c.hours {
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// wallClock returns the date and time of day t shows in its location, as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// atWallClock returns the first time in loc showing the wall clock time want,
+// given as a UTC time. A wall clock time skipped by a daylight saving change
+// gives the end of the gap. time.Date leaves both cases unspecified.
+func atWallClock(want time.Time, loc *time.Location) time.Time {
+	t := time.Date(want.Year(), want.Month(), want.Day(), want.Hour(), want.Minute(), 0, 0, loc)
+	start, end := t.ZoneBounds()
+	switch {
+	case wallClock(t).Before(want):
+		// Skipped, and resolved with the offset from before the gap
+		return end.In(loc)
+	case wallClock(t).After(want):
+		// Skipped, and resolved with the offset from after the gap
+		return start.In(loc)
+	case !start.IsZero():
+		// Repeated if the clock went back when t's zone started, in which
+		// case the first pass is before start
+		_, offset := t.Zone()
+		_, previousOffset := start.Add(-time.Second).Zone()
+		earlier := t.Add(-time.Duration(previousOffset-offset) * time.Second)
+		if previousOffset > offset && wallClock(earlier).Equal(want) {
+			return earlier
+		}
+	}
+	return t
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	day := c.days[t.Day()]
+	weekday := c.weekdays[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
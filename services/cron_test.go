@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "* * * * *"},
+		{expr: "*/15 9-17 * * 1-5"},
+		{expr: "0,30 0 1,15 * *"},
+		{expr: "0 0 * * 7"},
+		{expr: "5/10 * * * *"},
+		{expr: "@daily"},
+		{expr: " @weekly "},
+		{expr: "* * * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "* * * 13 *", wantErr: true},
+		{expr: "* * * * 8", wantErr: true},
+		{expr: "5-1 * * * *", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "a * * * *", wantErr: true},
+		{expr: "@yearly", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("ParseCron(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	var berlin, newYork, asuncion, havana *time.Location
+	for name, loc := range map[string]**time.Location{
+		"Europe/Berlin":    &berlin,
+		"America/New_York": &newYork,
+		"America/Asuncion": &asuncion,
+		"America/Havana":   &havana,
+	} {
+		var err error
+		if *loc, err = time.LoadLocation(name); err != nil {
+			t.Skip("timezone data is not available")
+		}
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	at := func(loc *time.Location, s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	local := func(s string) time.Time { return at(berlin, s) }
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"strictly after", "30 10 * * *", utc("2024-03-05 10:30"), utc("2024-03-06 10:30")},
+		{"seconds are dropped", "* * * * *", utc("2024-03-05 10:30").Add(15 * time.Second), utc("2024-03-05 10:31")},
+		{"step", "*/15 * * * *", utc("2024-03-05 10:16"), utc("2024-03-05 10:30")},
+		{"end of month", "0 0 31 * *", utc("2024-04-01 00:00"), utc("2024-05-31 00:00")},
+		{"leap day", "0 12 29 2 *", utc("2024-03-01 00:00"), utc("2028-02-29 12:00")},
+		{"weekday", "0 9 * * 1", utc("2024-03-05 10:00"), utc("2024-03-11 09:00")},
+		{"sunday as 7", "0 9 * * 7", utc("2024-03-05 10:00"), utc("2024-03-10 09:00")},
+		{"day of month or weekday", "0 0 15 * 1", utc("2024-03-12 00:00"), utc("2024-03-15 00:00")},
+		{"never", "0 0 30 2 *", utc("2024-01-01 00:00"), time.Time{}},
+
+		// Berlin moves from 02:00 CET to 03:00 CEST on 2024-03-31 and back from
+		// 03:00 CEST to 02:00 CET on 2024-10-27
+		{"in the location of from", "0 9 * * *", local("2024-03-05 10:00"), local("2024-03-06 09:00")},
+		{"daily across spring forward", "0 1 * * *", local("2024-03-30 01:00"), local("2024-03-31 01:00")},
+		{"hour skipped by spring forward", "30 2 * * *", local("2024-03-31 00:00"), local("2024-03-31 03:00")},
+		{"minutes skipped by spring forward", "30 1,2 * * *", local("2024-03-31 01:30"), local("2024-03-31 03:00")},
+		{"after the skipped hour", "30 2 * * *", local("2024-03-31 03:00"), local("2024-04-01 02:30")},
+		{"hourly across spring forward", "0 * * * *", local("2024-03-31 01:00"), local("2024-03-31 03:00")},
+		{"repeated hour", "0 * * * *", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).In(berlin), time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC)}, // 02:30 CEST to 02:00 CET
+		{"daily across fall back", "0 9 * * *", local("2024-10-26 09:00"), local("2024-10-27 09:00")},
+
+		// New York moves from 02:00 EST to 03:00 EDT on 2024-03-10 and back from
+		// 02:00 EDT to 01:00 EST on 2024-11-03
+		{"new york hour skipped by spring forward", "30 2 * * *", at(newYork, "2024-03-10 00:00"), at(newYork, "2024-03-10 03:00")},
+		{"new york minutes skipped by spring forward", "* * * * *", at(newYork, "2024-03-10 01:59"), at(newYork, "2024-03-10 03:00")},
+		{"new york weekly on the day of spring forward", "30 2 * * 0", at(newYork, "2024-03-09 12:00"), at(newYork, "2024-03-10 03:00")},
+		{"new york hourly across spring forward", "0 * * * *", at(newYork, "2024-03-10 01:00"), at(newYork, "2024-03-10 03:00")},
+		{"new york first pass of the repeated hour", "30 1 * * *", at(newYork, "2024-11-03 00:00"), utc("2024-11-03 05:30")},             // 01:30 EDT
+		{"new york daily runs once in the repeated hour", "30 1 * * *", utc("2024-11-03 05:30").In(newYork), utc("2024-11-04 06:30")},    // 01:30 EDT to 01:30 EST the next day
+		{"new york minutes run once in the repeated hour", "*/20 1 * * *", utc("2024-11-03 05:40").In(newYork), utc("2024-11-04 06:00")}, // 01:40 EDT to 01:00 EST the next day
+		{"new york hourly runs in both passes", "0 * * * *", utc("2024-11-03 05:00").In(newYork), utc("2024-11-03 06:00")},               // 01:00 EDT to 01:00 EST
+		{"new york hourly after the repeated hour", "0 * * * *", utc("2024-11-03 06:00").In(newYork), at(newYork, "2024-11-03 02:00")},
+		{"new york daily across fall back", "0 9 * * *", at(newYork, "2024-11-02 09:00"), at(newYork, "2024-11-03 09:00")},
+
+		// Asuncion moved from 00:00 -04 to 01:00 -03 on 2023-10-01 and Havana from
+		// 00:00 CST to 01:00 CDT on 2024-03-10, skipping the start of the day.
+		// Havana moves back from 01:00 CDT to 00:00 CST on 2024-11-03
+		{"first of the month skipped", "0 0 1 * *", at(asuncion, "2023-09-15 00:00"), at(asuncion, "2023-10-01 01:00")},
+		{"midnight skipped", "0 0 * * *", at(asuncion, "2023-09-30 12:00"), at(asuncion, "2023-10-01 01:00")},
+		{"minutes after midnight skipped", "30 0 * * *", at(asuncion, "2023-09-30 23:45"), at(asuncion, "2023-10-01 01:00")},
+		{"every minute across the day boundary", "* * * * *", at(asuncion, "2023-09-30 23:59"), at(asuncion, "2023-10-01 01:00")},
+		{"midnight after the skipped one", "0 0 * * *", at(asuncion, "2023-10-01 01:00"), at(asuncion, "2023-10-02 00:00")},
+		{"weekday whose midnight is skipped", "0 0 * * 0", at(havana, "2024-03-09 12:00"), at(havana, "2024-03-10 01:00")},
+		{"repeated midnight", "0 0 * * *", at(havana, "2024-11-02 12:00"), utc("2024-11-03 04:00")},                    // 00:00 CDT
+		{"midnight runs once when repeated", "0 0 * * *", utc("2024-11-03 04:00").In(havana), utc("2024-11-04 05:00")}, // 00:00 CDT to 00:00 CST the next day
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
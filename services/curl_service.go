@@ -2,124 +2,105 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os/exec"
 	"strings"
 	"time"
 	"uptime-monitor/types"
 )
 
-// CurlService handles HTTP requests using Go's native HTTP client or the curl command
+// RequestTypeCurl is the request type of HTTP monitors that are checked with the curl command
+const RequestTypeCurl = "curl"
+
+// CurlService handles HTTP requests using the curl command
 type CurlService struct {
 	credentials *CredentialsService
-	client      *http.Client
 }
 
 func NewCurlService(credentials *CredentialsService) *CurlService {
 	return &CurlService{
 		credentials: credentials,
-		client: &http.Client{
-			Timeout: 10 * time.Second, // Default timeout
-		},
 	}
 }
 
-// ExecuteCurlRequest executes a request using either Go's HTTP client or the curl command
-func (s *CurlService) ExecuteCurlRequest(monitor *types.Monitor) (int, string, error) {
-	// If the request type is "curl", use the actual curl command
-	if strings.ToLower(monitor.RequestType) == "curl" {
-		return s.executeCurlCommand(monitor)
-	}
-
-	// Otherwise, use the Go HTTP client
-	return s.executeHttpRequest(monitor)
+// ExecuteCurlRequest executes a request using the curl command and returns the
+// status code, a status message and the response body
+func (s *CurlService) ExecuteCurlRequest(ctx context.Context, monitor *types.Monitor) (int, string, []byte, error) {
+	return s.executeCurlCommand(ctx, monitor)
 }
 
-// executeHttpRequest executes a request using Go's HTTP client
-func (s *CurlService) executeHttpRequest(monitor *types.Monitor) (int, string, error) {
-	// Create request
-	var body io.Reader
-	if monitor.Body != "" {
-		body = strings.NewReader(monitor.Body)
+// Check implements Checker for monitors using the curl request type
+func (s *CurlService) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	timeout := defaultHTTPTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	req, err := http.NewRequest(monitor.Method, monitor.URL, body)
-	if err != nil {
-		return 0, fmt.Sprintf("Request creation failed: %v", err), err
+	metadata := map[string]interface{}{
+		"url":          monitor.URL,
+		"method":       monitor.Method,
+		"request_type": RequestTypeCurl,
 	}
 
-	// Add headers
-	if headers := parseHeaders(monitor.Headers); headers != nil {
-		for key, value := range headers {
-			req.Header.Add(key, value)
+	startTime := time.Now()
+	code, msg, body, err := s.ExecuteCurlRequest(ctx, monitor)
+	responseTime := time.Since(startTime).Milliseconds()
+	if err != nil {
+		category := curlErrorCategory(err)
+		if ctx.Err() != nil {
+			// curl was killed when the check timed out
+			category = ErrorCategoryTimeout
 		}
-	}
-
-	// Add credential headers if specified
-	if monitor.CredentialID != "" {
-		cred, err := s.credentials.GetCredential(monitor.CredentialID)
-		if err != nil {
-			return 0, fmt.Sprintf("Credential retrieval failed: %v", err), err
+		return &CheckResult{
+			Status:        "down",
+			ResponseTime:  responseTime,
+			Message:       fmt.Sprintf("CURL check failed: %v", err),
+			Metadata:      metadata,
+			ErrorCategory: category,
 		}
-
-		headerValue := s.credentials.GetHeaderValue(cred)
-		req.Header.Add(cred.HeaderName, headerValue)
 	}
 
-	// Execute request
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return 0, fmt.Sprintf("Connection failed to %s: %v", monitor.URL, err), err
+	result := &CheckResult{
+		Status:       statusFromCode(monitor, code),
+		ResponseCode: code,
+		ResponseTime: responseTime,
+		Message:      msg,
+		Metadata:     metadata,
 	}
-	defer resp.Body.Close()
 
-	// Debug logging
-	fmt.Printf("Monitor URL: %s\n", monitor.URL)
-	fmt.Printf("Monitor Method: %s\n", monitor.Method)
-	fmt.Printf("Request Headers: %v\n", req.Header)
-	fmt.Printf("Response Status Code: %d\n", resp.StatusCode)
+	// curl does not expose the peer certificate, so HTTPS targets get a separate handshake
+	if addr, serverName, ok := httpsAddress(monitor.URL); ok {
+		if info, err := InspectTLS(ctx, addr, serverName); err == nil {
+			result.TLS = info
+		}
+	}
 
-	// Generate appropriate message based on status code
-	statusCode := resp.StatusCode
-	var message string
-	switch {
-	case statusCode >= 200 && statusCode < 300:
-		message = fmt.Sprintf("%s returned status code %d (Success)", monitor.URL, statusCode)
-	case statusCode == 401:
-		message = fmt.Sprintf("%s returned status code 401 (Unauthorized)", monitor.URL)
-	case statusCode == 403:
-		message = fmt.Sprintf("%s returned status code 403 (Forbidden)", monitor.URL)
-	case statusCode == 404:
-		message = fmt.Sprintf("%s returned status code 404 (Not Found)", monitor.URL)
-	case statusCode == 500:
-		message = fmt.Sprintf("%s returned status code 500 (Internal Server Error)", monitor.URL)
-	case statusCode == 502:
-		message = fmt.Sprintf("%s returned status code 502 (Bad Gateway)", monitor.URL)
-	case statusCode == 503:
-		message = fmt.Sprintf("%s returned status code 503 (Service Unavailable)", monitor.URL)
-	case statusCode == 504:
-		message = fmt.Sprintf("%s returned status code 504 (Gateway Timeout)", monitor.URL)
-	default:
-		message = fmt.Sprintf("%s returned status code %d", monitor.URL, statusCode)
+	// Only an otherwise healthy response is checked against the body assertions
+	if result.Status == "up" && monitor.HasBodyAssertions() {
+		if int64(len(body)) > bodyLimit(monitor) {
+			body = body[:bodyLimit(monitor)]
+		}
+		applyBodyAssertions(monitor, body, result)
 	}
 
-	return statusCode, message, nil
+	return result
 }
 
 // executeCurlCommand executes a request using the curl command-line tool
-func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, error) {
+func (s *CurlService) executeCurlCommand(ctx context.Context, monitor *types.Monitor) (int, string, []byte, error) {
 	// Debug logging
 	fmt.Printf("Executing curl request for URL: %s\n", monitor.URL)
 	fmt.Printf("Method: %s\n", monitor.Method)
-	fmt.Printf("Headers: %s\n", monitor.Headers)
+	fmt.Printf("Headers: %s\n", monitor.Headers.Masked())
 	fmt.Printf("Body: %s\n", monitor.Body)
 
 	// Build the curl command
 	args := []string{
-		"--location",
 		"--silent",
 		"--show-error",
 		"-i",                      // Include headers in output
@@ -127,6 +108,11 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 		"-v", // Verbose output for debugging
 	}
 
+	// Follow redirects unless the monitor checks the redirect response itself
+	if monitor.ShouldFollowRedirects() {
+		args = append(args, "--location")
+	}
+
 	// Add method if not GET
 	if monitor.Method != "GET" {
 		args = append(args, "-X", monitor.Method)
@@ -146,7 +132,7 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 	}
 
 	// Add headers
-	if headers := parseHeaders(monitor.Headers); headers != nil {
+	if headers := parseHeaders(string(monitor.Headers)); headers != nil {
 		for key, value := range headers {
 			args = append(args, "-H", fmt.Sprintf("%s: %s", key, value))
 		}
@@ -154,9 +140,9 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 
 	// Add credential headers if specified
 	if monitor.CredentialID != "" {
-		cred, err := s.credentials.GetCredential(monitor.CredentialID)
+		cred, err := s.credentials.ResolveCredential(ctx, monitor.CredentialID)
 		if err != nil {
-			return 0, fmt.Sprintf("Credential retrieval failed: %v", err), err
+			return 0, fmt.Sprintf("Credential retrieval failed: %v", err), nil, &credentialError{err: err}
 		}
 
 		// Get the header value based on credential type
@@ -190,7 +176,7 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 	fmt.Printf("Executing curl command: curl %s\n", strings.Join(args, " "))
 
 	// Execute the curl command
-	cmd := exec.Command("curl", args...)
+	cmd := exec.CommandContext(ctx, "curl", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -202,7 +188,7 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 			errMsg = err.Error()
 		}
 		fmt.Printf("Curl command failed: %s\n", errMsg)
-		return 0, fmt.Sprintf("Curl command failed: %s", errMsg), err
+		return 0, fmt.Sprintf("Curl command failed: %s", errMsg), nil, err
 	}
 
 	// Get the output
@@ -213,14 +199,14 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 	statusCode := 0
 	lines := strings.Split(output, "\n")
 
-	// Look for the status line (HTTP/1.1 200 OK)
+	// Look for the status line (HTTP/1.1 200 OK). When redirects are followed
+	// there is one status line per hop and the last one is the final response.
 	for _, line := range lines {
 		if strings.HasPrefix(line, "HTTP/") {
 			parts := strings.Split(line, " ")
 			if len(parts) >= 2 {
 				fmt.Sscanf(parts[1], "%d", &statusCode)
 				fmt.Printf("Found status code: %d from line: %s\n", statusCode, line)
-				break
 			}
 		}
 	}
@@ -228,37 +214,55 @@ func (s *CurlService) executeCurlCommand(monitor *types.Monitor) (int, string, e
 	// If we couldn't parse a status code, return an error
 	if statusCode == 0 {
 		fmt.Printf("Failed to parse status code from curl output\n")
-		return 0, "Failed to parse status code from curl output", fmt.Errorf("failed to parse status code")
+		return 0, "Failed to parse status code from curl output", nil, fmt.Errorf("failed to parse status code")
 	}
 
 	// Generate appropriate message based on status code
-	var message string
-	switch {
-	case statusCode >= 200 && statusCode < 300:
-		message = fmt.Sprintf("%s returned status code %d (Success)", monitor.URL, statusCode)
-	case statusCode == 401:
-		message = fmt.Sprintf("%s returned status code 401 (Unauthorized)", monitor.URL)
-	case statusCode == 403:
-		message = fmt.Sprintf("%s returned status code 403 (Forbidden)", monitor.URL)
-	case statusCode == 404:
-		message = fmt.Sprintf("%s returned status code 404 (Not Found)", monitor.URL)
-	case statusCode == 500:
-		message = fmt.Sprintf("%s returned status code 500 (Internal Server Error)", monitor.URL)
-	case statusCode == 502:
-		message = fmt.Sprintf("%s returned status code 502 (Bad Gateway)", monitor.URL)
-	case statusCode == 503:
-		message = fmt.Sprintf("%s returned status code 503 (Service Unavailable)", monitor.URL)
-	case statusCode == 504:
-		message = fmt.Sprintf("%s returned status code 504 (Gateway Timeout)", monitor.URL)
-	default:
-		message = fmt.Sprintf("%s returned status code %d", monitor.URL, statusCode)
-	}
+	message := describeStatusCode(monitor.URL, statusCode)
 
 	// Debug logging
 	fmt.Printf("Status code: %d\n", statusCode)
 	fmt.Printf("Message: %s\n", message)
 
-	return statusCode, message, nil
+	return statusCode, message, curlResponseBody(output), nil
+}
+
+// curlErrorCategory maps a failed curl invocation to an error category using curl's exit code
+func curlErrorCategory(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return classifyError(err)
+	}
+	switch exitErr.ExitCode() {
+	case 6: // Couldn't resolve host
+		return ErrorCategoryDNS
+	case 7: // Failed to connect
+		return ErrorCategoryConnect
+	case 28: // Operation timed out
+		return ErrorCategoryTimeout
+	case 35, 51, 53, 54, 58, 59, 60, 66, 77, 80, 82, 83, 90, 91: // SSL/TLS failures
+		return ErrorCategoryTLS
+	default:
+		return ErrorCategoryConnect
+	}
+}
+
+// curlResponseBody strips the header blocks that "curl -i" prints before the body.
+// With --location there is one header block per redirect.
+func curlResponseBody(output string) []byte {
+	for strings.HasPrefix(output, "HTTP/") {
+		idx := strings.Index(output, "\r\n\r\n")
+		sep := 4
+		if idx < 0 {
+			idx = strings.Index(output, "\n\n")
+			sep = 2
+		}
+		if idx < 0 {
+			return nil
+		}
+		output = output[idx+sep:]
+	}
+	return []byte(output)
 }
 
 // parseHeaders parses the headers string into a map
@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
-	"uptime-monitor/models"
+	"uptime-monitor/types"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
@@ -15,32 +15,42 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// defaultDatabaseTimeout bounds a database check when the monitor has no timeout set
+const defaultDatabaseTimeout = 10 * time.Second
+
 // DatabaseMonitor handles monitoring different types of databases
 type DatabaseMonitor struct {
-	monitor *models.Monitor
+	monitor *types.Monitor
 }
 
 // NewDatabaseMonitor creates a new database monitor instance
-func NewDatabaseMonitor(monitor *models.Monitor) *DatabaseMonitor {
+func NewDatabaseMonitor(monitor *types.Monitor) *DatabaseMonitor {
 	return &DatabaseMonitor{monitor: monitor}
 }
 
-// Check performs the database health check
-func (d *DatabaseMonitor) Check() (bool, string, int64, error) {
+// Check performs the database health check, bounded by the monitor's timeout
+func (d *DatabaseMonitor) Check(ctx context.Context) (bool, string, int64, error) {
+	timeout := defaultDatabaseTimeout
+	if d.monitor.Timeout > 0 {
+		timeout = time.Duration(d.monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	start := time.Now()
 	var isUp bool
 	var message string
 	var err error
 
 	switch d.monitor.Type {
-	case "mysql":
-		isUp, message, err = d.checkMySQL()
-	case "postgres":
-		isUp, message, err = d.checkPostgres()
-	case "mongodb":
-		isUp, message, err = d.checkMongoDB()
-	case "redis":
-		isUp, message, err = d.checkRedis()
+	case types.MonitorTypeMySQL:
+		isUp, message, err = d.checkMySQL(ctx)
+	case types.MonitorTypePostgres:
+		isUp, message, err = d.checkPostgres(ctx)
+	case types.MonitorTypeMongoDB:
+		isUp, message, err = d.checkMongoDB(ctx)
+	case types.MonitorTypeRedis:
+		isUp, message, err = d.checkRedis(ctx)
 	default:
 		return false, "Unsupported database type", 0, fmt.Errorf("unsupported database type: %s", d.monitor.Type)
 	}
@@ -49,11 +59,39 @@ func (d *DatabaseMonitor) Check() (bool, string, int64, error) {
 	return isUp, message, responseTime, err
 }
 
+// DatabaseChecker implements Checker for the MySQL, PostgreSQL, MongoDB and Redis monitor types
+type DatabaseChecker struct{}
+
+// Check runs the database health check for the monitor's type
+func (c *DatabaseChecker) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	isUp, message, responseTime, err := NewDatabaseMonitor(monitor).Check(ctx)
+	result := &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Message:      message,
+		Metadata: map[string]interface{}{
+			"db_type": monitor.Type,
+			"db_host": monitor.DBHost,
+			"db_port": monitor.DBPort,
+		},
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Message = fmt.Sprintf("%s: %v", message, err)
+		result.ErrorCategory = classifyError(err)
+	} else if !isUp {
+		// The query ran but did not return the expected value
+		result.Status = "down"
+		result.ErrorCategory = ErrorCategoryAssertion
+	}
+	return result
+}
+
 // checkMySQL checks MySQL database health
-func (d *DatabaseMonitor) checkMySQL() (bool, string, error) {
+func (d *DatabaseMonitor) checkMySQL(ctx context.Context) (bool, string, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
 		d.monitor.DBUsername,
-		d.monitor.DBPassword,
+		string(d.monitor.DBPassword),
 		d.monitor.DBHost,
 		d.monitor.DBPort,
 		d.monitor.DBName,
@@ -66,14 +104,14 @@ func (d *DatabaseMonitor) checkMySQL() (bool, string, error) {
 	defer db.Close()
 
 	// Test connection
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return false, "Failed to ping MySQL", err
 	}
 
 	// Execute test query if provided
 	if d.monitor.DBQuery != "" {
 		var result string
-		err := db.QueryRow(d.monitor.DBQuery).Scan(&result)
+		err := db.QueryRowContext(ctx, d.monitor.DBQuery).Scan(&result)
 		if err != nil {
 			return false, "Failed to execute test query", err
 		}
@@ -87,12 +125,12 @@ func (d *DatabaseMonitor) checkMySQL() (bool, string, error) {
 }
 
 // checkPostgres checks PostgreSQL database health
-func (d *DatabaseMonitor) checkPostgres() (bool, string, error) {
+func (d *DatabaseMonitor) checkPostgres(ctx context.Context) (bool, string, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		d.monitor.DBHost,
 		d.monitor.DBPort,
 		d.monitor.DBUsername,
-		d.monitor.DBPassword,
+		string(d.monitor.DBPassword),
 		d.monitor.DBName,
 	)
 
@@ -102,13 +140,13 @@ func (d *DatabaseMonitor) checkPostgres() (bool, string, error) {
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return false, "Failed to ping PostgreSQL", err
 	}
 
 	if d.monitor.DBQuery != "" {
 		var result string
-		err := db.QueryRow(d.monitor.DBQuery).Scan(&result)
+		err := db.QueryRowContext(ctx, d.monitor.DBQuery).Scan(&result)
 		if err != nil {
 			return false, "Failed to execute test query", err
 		}
@@ -122,22 +160,22 @@ func (d *DatabaseMonitor) checkPostgres() (bool, string, error) {
 }
 
 // checkMongoDB checks MongoDB database health
-func (d *DatabaseMonitor) checkMongoDB() (bool, string, error) {
+func (d *DatabaseMonitor) checkMongoDB(ctx context.Context) (bool, string, error) {
 	uri := fmt.Sprintf("mongodb://%s:%s@%s:%s/%s",
 		d.monitor.DBUsername,
-		d.monitor.DBPassword,
+		string(d.monitor.DBPassword),
 		d.monitor.DBHost,
 		d.monitor.DBPort,
 		d.monitor.DBName,
 	)
 
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
 	if err != nil {
 		return false, "Failed to connect to MongoDB", err
 	}
 	defer client.Disconnect(context.Background())
 
-	if err := client.Ping(context.Background(), nil); err != nil {
+	if err := client.Ping(ctx, nil); err != nil {
 		return false, "Failed to ping MongoDB", err
 	}
 
@@ -145,7 +183,7 @@ func (d *DatabaseMonitor) checkMongoDB() (bool, string, error) {
 		// Execute test command
 		var result bson.M
 		cmd := bson.D{{Key: "eval", Value: d.monitor.DBQuery}}
-		err := client.Database(d.monitor.DBName).RunCommand(context.Background(), cmd).Decode(&result)
+		err := client.Database(d.monitor.DBName).RunCommand(ctx, cmd).Decode(&result)
 		if err != nil {
 			return false, "Failed to execute test command", err
 		}
@@ -159,14 +197,14 @@ func (d *DatabaseMonitor) checkMongoDB() (bool, string, error) {
 }
 
 // checkRedis checks Redis database health
-func (d *DatabaseMonitor) checkRedis() (bool, string, error) {
+func (d *DatabaseMonitor) checkRedis(ctx context.Context) (bool, string, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", d.monitor.DBHost, d.monitor.DBPort),
-		Password: d.monitor.DBPassword,
+		Password: string(d.monitor.DBPassword),
 		DB:       0,
 	})
+	defer client.Close()
 
-	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return false, "Failed to ping Redis", err
 	}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/types"
+)
+
+// defaultDNSTimeout bounds a DNS lookup when the monitor has no timeout set
+const defaultDNSTimeout = 10 * time.Second
+
+// DNSRecordTypes lists the record types supported by dns monitors
+var DNSRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT"}
+
+// DNSChecker implements Checker for the dns monitor type. It resolves Hostname
+// with the monitor's resolver and checks the returned records against DNSExpected.
+type DNSChecker struct{}
+
+// Check resolves the monitor's record and compares it with the expected values
+func (c *DNSChecker) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	timeout := defaultDNSTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	recordType := monitor.GetDNSRecordType()
+	metadata := map[string]interface{}{
+		"name":        monitor.Hostname,
+		"record_type": recordType,
+		"resolver":    monitor.DNSResolver,
+	}
+
+	start := time.Now()
+	records, err := lookupDNSRecords(ctx, newDNSResolver(monitor.DNSResolver), recordType, monitor.Hostname)
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return &CheckResult{
+			Status:        "down",
+			ResponseTime:  responseTime,
+			Message:       fmt.Sprintf("DNS lookup of %s %s failed: %v", recordType, monitor.Hostname, err),
+			Metadata:      metadata,
+			ErrorCategory: ErrorCategoryDNS,
+		}
+	}
+	metadata["records"] = records
+
+	result := &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Message:      fmt.Sprintf("%s %s resolved to %s", recordType, monitor.Hostname, strings.Join(records, ", ")),
+		Metadata:     metadata,
+		Timings:      &CheckTimings{DNSMs: responseTime},
+	}
+	if missing := missingDNSValues(records, monitor.GetDNSExpectedValues()); len(missing) > 0 {
+		result.Status = "down"
+		result.Message = fmt.Sprintf("%s %s resolved to %s, missing expected %s",
+			recordType, monitor.Hostname, strings.Join(records, ", "), strings.Join(missing, ", "))
+		result.ErrorCategory = ErrorCategoryAssertion
+	}
+	return result
+}
+
+// newDNSResolver returns a resolver that queries the given host:port, or the
+// system resolver when no address is set
+func newDNSResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// lookupDNSRecords resolves a name and returns the records of the given type as strings.
+// MX records are rendered as "host" so they can be matched without their preference.
+func lookupDNSRecords(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var records []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, normalizeDNSName(cname))
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			records = append(records, normalizeDNSName(mx.Host))
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, txts...)
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no %s records found", recordType)
+	}
+	return records, nil
+}
+
+// missingDNSValues returns the expected values that are not among the resolved records.
+// Host names are compared case-insensitively and without the trailing dot.
+func missingDNSValues(records, expected []string) []string {
+	found := make(map[string]bool, len(records))
+	for _, r := range records {
+		found[normalizeDNSName(r)] = true
+	}
+
+	var missing []string
+	for _, e := range expected {
+		if !found[normalizeDNSName(e)] {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// normalizeDNSName lowercases a name and strips its trailing dot
+func normalizeDNSName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// ValidateDNSResolver checks that a resolver address is a host or host:port
+func ValidateDNSResolver(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "53"
+	}
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
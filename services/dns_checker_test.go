@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"uptime-monitor/types"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer answers UDP queries from a fixed zone. Queries for a name
+// return its records of the asked type along with any CNAME, and unknown names
+// return NXDOMAIN. It returns the host:port to use as a resolver.
+func startDNSServer(t *testing.T, zone map[string][]dnsmessage.Resource) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RecursionDesired: query.RecursionDesired},
+				Questions: query.Questions,
+			}
+			records, ok := zone[strings.ToLower(question.Name.String())]
+			if !ok {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			for _, record := range records {
+				if record.Header.Type == question.Type || record.Header.Type == dnsmessage.TypeCNAME {
+					response.Answers = append(response.Answers, record)
+				}
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func dnsHeader(name string, recordType dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: recordType, Class: dnsmessage.ClassINET, TTL: 60}
+}
+
+func TestDNSCheckerCheck(t *testing.T) {
+	resolver := startDNSServer(t, map[string][]dnsmessage.Resource{
+		"app.example.test.": {
+			{Header: dnsHeader("app.example.test.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}},
+			{Header: dnsHeader("app.example.test.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 11}}},
+			{Header: dnsHeader("app.example.test.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+		"www.example.test.": {
+			{Header: dnsHeader("www.example.test.", dnsmessage.TypeCNAME), Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("app.example.test.")}},
+		},
+		"example.test.": {
+			{Header: dnsHeader("example.test.", dnsmessage.TypeMX), Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("Mail.Example.Test.")}},
+		},
+	})
+
+	tests := []struct {
+		name         string
+		hostname     string
+		recordType   string
+		expected     string
+		wantStatus   string
+		wantCategory string
+		wantMessage  string
+	}{
+		{"A records", "app.example.test", "A", "", "up", "", "192.0.2.10, 192.0.2.11"},
+		{"expected A record", "app.example.test", "A", "192.0.2.11", "up", "", ""},
+		{"missing expected A record", "app.example.test", "A", "192.0.2.10, 192.0.2.99", "down", ErrorCategoryAssertion, "missing expected 192.0.2.99"},
+		{"CNAME", "www.example.test", "CNAME", "APP.example.test.", "up", "", "resolved to app.example.test"},
+		{"MX host only", "example.test", "MX", "mail.example.test", "up", "", "resolved to mail.example.test"},
+		{"TXT", "app.example.test", "TXT", "v=spf1 -all", "up", "", ""},
+		{"no such name", "nope.example.test", "A", "", "down", ErrorCategoryDNS, "DNS lookup of A nope.example.test failed"},
+		{"no records of type", "example.test", "TXT", "", "down", ErrorCategoryDNS, "DNS lookup of TXT example.test failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &types.Monitor{
+				Type:          types.MonitorTypeDNS,
+				Hostname:      tt.hostname,
+				DNSRecordType: tt.recordType,
+				DNSResolver:   resolver,
+				DNSExpected:   tt.expected,
+				Timeout:       5,
+			}
+			result := (&DNSChecker{}).Check(context.Background(), monitor)
+			if result.Status != tt.wantStatus || result.ErrorCategory != tt.wantCategory || !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Check() = %s %s %q, want %s %s %q", result.Status, result.ErrorCategory, result.Message, tt.wantStatus, tt.wantCategory, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestValidateDNSResolver(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"1.1.1.1", false},
+		{"1.1.1.1:5353", false},
+		{"[2606:4700:4700::1111]:53", false},
+		{"dns.example.com", false},
+		{":53", true},
+		{"1.1.1.1:0", true},
+		{"1.1.1.1:dns", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := ValidateDNSResolver(tt.address); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDNSResolver(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
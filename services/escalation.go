@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+	"uptime-monitor/types"
+)
+
+const (
+	maxEscalationLevels       = 10
+	maxEscalationDelayMinutes = 7 * 24 * 60
+)
+
+// ValidateEscalationPolicy checks that a policy has levels, each notifying at
+// least one method no sooner than the level before it. Whether the methods
+// belong to the profile is checked by the caller.
+func ValidateEscalationPolicy(policy *types.EscalationPolicy) error {
+	if policy.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(policy.Levels) == 0 {
+		return fmt.Errorf("at least one level is required")
+	}
+	if len(policy.Levels) > maxEscalationLevels {
+		return fmt.Errorf("at most %d levels are allowed", maxEscalationLevels)
+	}
+
+	previousDelay := 0
+	for i, level := range policy.Levels {
+		if level.DelayMinutes < 0 || level.DelayMinutes > maxEscalationDelayMinutes {
+			return fmt.Errorf("level %d: delay must be between 0 and %d minutes", i+1, maxEscalationDelayMinutes)
+		}
+		if level.DelayMinutes < previousDelay {
+			return fmt.Errorf("level %d: delay cannot be shorter than the previous level's", i+1)
+		}
+		if len(types.SplitList(level.MethodIDs)) == 0 {
+			return fmt.Errorf("level %d: at least one notification method is required", i+1)
+		}
+		previousDelay = level.DelayMinutes
+	}
+	return nil
+}
@@ -2,29 +2,57 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"sync"
 	"time"
 	"uptime-monitor/types"
 )
 
+// defaultHTTPTimeout bounds an HTTP check when the monitor has no timeout set
+const defaultHTTPTimeout = 30 * time.Second
+
 type HTTPService struct {
-	client      *http.Client
-	credentials *CredentialsService
+	client           *http.Client
+	noRedirectClient *http.Client
+	credentials      *CredentialsService
 }
 
 func NewHTTPService(credentials *CredentialsService) *HTTPService {
 	return &HTTPService{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
+		client: &http.Client{},
+		noRedirectClient: &http.Client{
+			// Return the redirect response itself so its status code is checked
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
 		credentials: credentials,
 	}
 }
 
-func (s *HTTPService) ExecuteRequest(monitor *types.Monitor) (*http.Response, error) {
+// credentialError marks failures to load or apply a monitor's credential
+type credentialError struct {
+	err error
+}
+
+func (e *credentialError) Error() string {
+	return fmt.Sprintf("Credential error: %v", e.err)
+}
+
+func (e *credentialError) Unwrap() error {
+	return e.err
+}
+
+// BuildRequest creates the HTTP request for a monitor, including its body,
+// headers and credential header
+func (s *HTTPService) BuildRequest(ctx context.Context, monitor *types.Monitor) (*http.Request, error) {
 	// Create request
 	var body io.Reader
 	if bodyMap := monitor.GetBodyMap(); bodyMap != nil {
@@ -35,10 +63,13 @@ func (s *HTTPService) ExecuteRequest(monitor *types.Monitor) (*http.Response, er
 		body = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequest(monitor.Method, monitor.URL, body)
+	req, err := http.NewRequestWithContext(ctx, monitor.Method, monitor.URL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	// Add headers if specified
 	if headers := monitor.GetHeadersMap(); headers != nil {
@@ -49,9 +80,9 @@ func (s *HTTPService) ExecuteRequest(monitor *types.Monitor) (*http.Response, er
 
 	// Add credential headers if specified
 	if monitor.CredentialID != "" {
-		cred, err := s.credentials.GetCredential(monitor.CredentialID)
+		cred, err := s.credentials.ResolveCredential(ctx, monitor.CredentialID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get credential: %v", err)
+			return nil, &credentialError{err: err}
 		}
 
 		// Get the processed header value with placeholders replaced
@@ -59,8 +90,22 @@ func (s *HTTPService) ExecuteRequest(monitor *types.Monitor) (*http.Response, er
 		req.Header.Set(cred.HeaderName, headerValue)
 	}
 
+	return req, nil
+}
+
+// ExecuteRequest builds and sends the HTTP request for a monitor
+func (s *HTTPService) ExecuteRequest(ctx context.Context, monitor *types.Monitor) (*http.Response, error) {
+	req, err := s.BuildRequest(ctx, monitor)
+	if err != nil {
+		return nil, err
+	}
+
 	// Execute request
-	resp, err := s.client.Do(req)
+	client := s.client
+	if !monitor.ShouldFollowRedirects() {
+		client = s.noRedirectClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -68,64 +113,154 @@ func (s *HTTPService) ExecuteRequest(monitor *types.Monitor) (*http.Response, er
 	return resp, nil
 }
 
-func (s *HTTPService) CheckEndpoint(monitor *types.Monitor) (*types.Log, error) {
-	startTime := time.Now()
+// Check implements Checker for HTTP monitors
+func (s *HTTPService) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	timeout := defaultHTTPTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	// Create request
-	req, err := http.NewRequest(monitor.Method, monitor.URL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+	metadata := map[string]interface{}{
+		"url":    monitor.URL,
+		"method": monitor.Method,
 	}
 
-	// Add headers
-	if headers := monitor.GetHeadersMap(); headers != nil {
-		for key, value := range headers {
-			req.Header.Add(key, value)
+	trace, timings := newTimingTrace()
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
+	startTime := time.Now()
+	resp, err := s.ExecuteRequest(ctx, monitor)
+	responseTime := time.Since(startTime).Milliseconds()
+	if err != nil {
+		var credErr *credentialError
+		if errors.As(err, &credErr) {
+			return &CheckResult{
+				Status:        "down",
+				Message:       credErr.Error(),
+				Metadata:      metadata,
+				ErrorCategory: ErrorCategoryCredential,
+			}
+		}
+		if isTLSError(err) {
+			result := s.tlsFailureResult(ctx, monitor, err, responseTime, metadata)
+			result.Timings = timings()
+			return result
 		}
+		return &CheckResult{
+			Status:        "down",
+			ResponseTime:  responseTime,
+			Message:       fmt.Sprintf("Connection error: %v", err),
+			Metadata:      metadata,
+			ErrorCategory: classifyError(err),
+			Timings:       timings(),
+		}
+	}
+	defer resp.Body.Close()
+
+	metadata["status_text"] = resp.Status
+	result := &CheckResult{
+		Status:       statusFromCode(monitor, resp.StatusCode),
+		ResponseCode: resp.StatusCode,
+		ResponseTime: responseTime,
+		Message:      describeStatusCode(monitor.URL, resp.StatusCode),
+		Metadata:     metadata,
+		TLS:          tlsInfoFromState(resp.TLS),
+		Timings:      timings(),
 	}
 
-	// Add body for POST/PUT requests
-	if (monitor.Method == "POST" || monitor.Method == "PUT") && monitor.GetBodyMap() != nil {
-		jsonBody, err := json.Marshal(monitor.GetBodyMap())
+	// Only an otherwise healthy response is checked against the body assertions
+	if result.Status == "up" && monitor.HasBodyAssertions() {
+		body, err := readBody(monitor, resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal body: %w", err)
+			result.Status = "down"
+			result.Message = fmt.Sprintf("Failed to read response body: %v", err)
+			result.ErrorCategory = classifyError(err)
+			return result
 		}
-		req.Body = io.NopCloser(bytes.NewBuffer(jsonBody))
-		req.Header.Set("Content-Type", "application/json")
+		applyBodyAssertions(monitor, body, result)
 	}
 
-	// Send request
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	return result
+}
+
+// tlsFailureResult reports an HTTPS request rejected because of the server
+// certificate, re-inspecting the handshake so the certificate is still recorded
+func (s *HTTPService) tlsFailureResult(ctx context.Context, monitor *types.Monitor, err error, responseTime int64, metadata map[string]interface{}) *CheckResult {
+	result := &CheckResult{
+		Status:        "down",
+		ResponseTime:  responseTime,
+		Message:       fmt.Sprintf("TLS error: %v", err),
+		Metadata:      metadata,
+		ErrorCategory: ErrorCategoryTLS,
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return &types.Log{
-			ID:        monitor.ID,
-			MonitorID: monitor.ID,
-			Status:    "error",
-			Message:   fmt.Sprintf("Request failed: %v", err),
-			CreatedAt: time.Now(),
-		}, nil
+	if addr, serverName, ok := httpsAddress(monitor.URL); ok {
+		if info, inspectErr := InspectTLS(ctx, addr, serverName); inspectErr == nil {
+			result.TLS = info
+		}
 	}
-	defer resp.Body.Close()
-
-	// Calculate response time
-	responseTime := time.Since(startTime).Milliseconds()
+	return result
+}
 
-	// Create log entry
-	log := &types.Log{
-		ID:        monitor.ID,
-		MonitorID: monitor.ID,
-		Status:    "success",
-		Message:   fmt.Sprintf("Response status: %d", resp.StatusCode),
-		CreatedAt: time.Now(),
+// applyBodyAssertions marks the result as down if the body fails one of the monitor's assertions
+func applyBodyAssertions(monitor *types.Monitor, body []byte, result *CheckResult) {
+	failure := evaluateBodyAssertions(monitor, body)
+	if failure == "" {
+		return
 	}
+	result.Status = "down"
+	result.Message = fmt.Sprintf("Assertion failed: %s", failure)
+	result.Metadata["failed_assertion"] = failure
+	result.ErrorCategory = ErrorCategoryAssertion
+}
 
-	// Update monitor status
-	monitor.Status = "up"
-	monitor.ResponseTime = responseTime
-	monitor.LastChecked = time.Now()
+// newTimingTrace returns a client trace that records the phases of an HTTP
+// request, and a function returning a snapshot of the timings so far. With a
+// reused connection the DNS, connect and TLS phases stay zero; when redirects
+// are followed the last hop wins.
+func newTimingTrace() (*httptrace.ClientTrace, func() *CheckTimings) {
+	timings := &CheckTimings{}
+	// Dual-stack dials may report connect events concurrently
+	var mu sync.Mutex
+	var start, dnsStart, connectStart, tlsStart time.Time
+	record := func(f func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		f()
+	}
 
-	return log, nil
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			record(func() { start = time.Now() })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { timings.DNSMs = time.Since(dnsStart).Milliseconds() })
+		},
+		ConnectStart: func(string, string) {
+			record(func() { connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			record(func() { timings.ConnectMs = time.Since(connectStart).Milliseconds() })
+		},
+		TLSHandshakeStart: func() {
+			record(func() { tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { timings.TLSMs = time.Since(tlsStart).Milliseconds() })
+		},
+		GotFirstResponseByte: func() {
+			record(func() { timings.TTFBMs = time.Since(start).Milliseconds() })
+		},
+	}
+	snapshot := func() *CheckTimings {
+		mu.Lock()
+		defer mu.Unlock()
+		t := *timings
+		return &t
+	}
+	return trace, snapshot
 }
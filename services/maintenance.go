@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"uptime-monitor/types"
+)
+
+// maxMaintenanceMinutes caps the length of a single recurring window
+const maxMaintenanceMinutes = 7 * 24 * 60
+
+// ValidateMaintenanceWindow checks that a window's schedule and targets are well formed
+func ValidateMaintenanceWindow(w *types.MaintenanceWindow) error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := maintenanceLocation(w); err != nil {
+		return fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+
+	if w.IsRecurring() {
+		if _, err := ParseCron(w.Recurrence); err != nil {
+			return fmt.Errorf("invalid recurrence: %v", err)
+		}
+		if w.DurationMinutes <= 0 || w.DurationMinutes > maxMaintenanceMinutes {
+			return fmt.Errorf("duration must be between 1 and %d minutes", maxMaintenanceMinutes)
+		}
+		if !w.StartsAt.IsZero() && !w.EndsAt.IsZero() && !w.StartsAt.Before(w.EndsAt) {
+			return fmt.Errorf("recurrence start must be before its end")
+		}
+	} else {
+		if w.StartsAt.IsZero() || w.EndsAt.IsZero() {
+			return fmt.Errorf("start and end are required for one-off windows")
+		}
+		if !w.StartsAt.Before(w.EndsAt) {
+			return fmt.Errorf("start must be before end")
+		}
+	}
+
+	if !w.AllMonitors && len(types.SplitList(w.MonitorIDs)) == 0 && len(types.SplitList(w.Tags)) == 0 {
+		return fmt.Errorf("select all monitors, or at least one monitor or tag")
+	}
+	return nil
+}
+
+// MaintenanceIntervals returns the periods in which a window is in effect that
+// overlap [from, to), clipped to that range
+func MaintenanceIntervals(w *types.MaintenanceWindow, from, to time.Time) []TimeInterval {
+	if !w.Enabled {
+		return nil
+	}
+	if !w.IsRecurring() {
+		return clipInterval(TimeInterval{Start: w.StartsAt, End: w.EndsAt}, from, to)
+	}
+
+	schedule, err := ParseCron(w.Recurrence)
+	if err != nil {
+		return nil
+	}
+	loc, err := maintenanceLocation(w)
+	if err != nil {
+		return nil
+	}
+	duration := time.Duration(w.DurationMinutes) * time.Minute
+
+	// A window that started before from may still be running
+	cursor := from.Add(-duration).In(loc).Add(-time.Minute)
+	if !w.StartsAt.IsZero() && cursor.Before(w.StartsAt) {
+		cursor = w.StartsAt.In(loc).Add(-time.Minute)
+	}
+
+	var intervals []TimeInterval
+	for {
+		start := schedule.Next(cursor)
+		if start.IsZero() || !start.Before(to) || (!w.EndsAt.IsZero() && !start.Before(w.EndsAt)) {
+			break
+		}
+		intervals = append(intervals, clipInterval(TimeInterval{Start: start, End: start.Add(duration)}, from, to)...)
+		cursor = start
+	}
+	return intervals
+}
+
+// ActiveMaintenanceWindow returns the first window in effect for the monitor at
+// t, or nil if the monitor is not under maintenance
+func ActiveMaintenanceWindow(windows []types.MaintenanceWindow, monitor *types.Monitor, t time.Time) *types.MaintenanceWindow {
+	for i := range windows {
+		w := &windows[i]
+		if w.AppliesTo(monitor) && len(MaintenanceIntervals(w, t, t.Add(time.Second))) > 0 {
+			return w
+		}
+	}
+	return nil
+}
+
+// MaintenanceExclusions returns the merged maintenance periods of a monitor in [from, to)
+func MaintenanceExclusions(windows []types.MaintenanceWindow, monitor *types.Monitor, from, to time.Time) []TimeInterval {
+	var intervals []TimeInterval
+	for i := range windows {
+		if windows[i].AppliesTo(monitor) {
+			intervals = append(intervals, MaintenanceIntervals(&windows[i], from, to)...)
+		}
+	}
+	return MergeIntervals(intervals)
+}
+
+// MergeIntervals sorts intervals and merges the ones that overlap or touch
+func MergeIntervals(intervals []TimeInterval) []TimeInterval {
+	if len(intervals) < 2 {
+		return intervals
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+
+	merged := []TimeInterval{intervals[0]}
+	for _, in := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if in.Start.After(last.End) {
+			merged = append(merged, in)
+		} else if in.End.After(last.End) {
+			last.End = in.End
+		}
+	}
+	return merged
+}
+
+func clipInterval(in TimeInterval, from, to time.Time) []TimeInterval {
+	if in.Start.Before(from) {
+		in.Start = from
+	}
+	if in.End.After(to) {
+		in.End = to
+	}
+	if !in.Start.Before(in.End) {
+		return nil
+	}
+	return []TimeInterval{in}
+}
+
+func maintenanceLocation(w *types.MaintenanceWindow) (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/types"
+)
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		window  types.MaintenanceWindow
+		wantErr string
+	}{
+		{"one-off", types.MaintenanceWindow{Name: "upgrade", StartsAt: start, EndsAt: start.Add(time.Hour), AllMonitors: true}, ""},
+		{"recurring", types.MaintenanceWindow{Name: "backups", Recurrence: "0 3 * * *", DurationMinutes: 30, Timezone: "Europe/Berlin", Tags: "db"}, ""},
+		{"no name", types.MaintenanceWindow{StartsAt: start, EndsAt: start.Add(time.Hour), AllMonitors: true}, "name is required"},
+		{"bad timezone", types.MaintenanceWindow{Name: "x", Recurrence: "@daily", DurationMinutes: 5, Timezone: "Mars/Olympus", AllMonitors: true}, "invalid timezone"},
+		{"bad recurrence", types.MaintenanceWindow{Name: "x", Recurrence: "daily", DurationMinutes: 5, AllMonitors: true}, "invalid recurrence"},
+		{"no duration", types.MaintenanceWindow{Name: "x", Recurrence: "@daily", AllMonitors: true}, "duration must be between"},
+		{"too long", types.MaintenanceWindow{Name: "x", Recurrence: "@daily", DurationMinutes: maxMaintenanceMinutes + 1, AllMonitors: true}, "duration must be between"},
+		{"one-off without end", types.MaintenanceWindow{Name: "x", StartsAt: start, AllMonitors: true}, "start and end are required"},
+		{"one-off reversed", types.MaintenanceWindow{Name: "x", StartsAt: start, EndsAt: start.Add(-time.Hour), AllMonitors: true}, "start must be before end"},
+		{"no targets", types.MaintenanceWindow{Name: "x", StartsAt: start, EndsAt: start.Add(time.Hour)}, "select all monitors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaintenanceWindow(&tt.window)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateMaintenanceWindow() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateMaintenanceWindow() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaintenanceIntervals(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("timezone data is not available")
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	interval := func(start, end string) TimeInterval {
+		return TimeInterval{Start: utc(start), End: utc(end)}
+	}
+
+	tests := []struct {
+		name     string
+		window   types.MaintenanceWindow
+		from, to time.Time
+		want     []TimeInterval
+	}{
+		{
+			name:   "disabled",
+			window: types.MaintenanceWindow{Recurrence: "0 3 * * *", DurationMinutes: 60},
+			from:   utc("2024-03-01 00:00"), to: utc("2024-03-03 00:00"),
+		},
+		{
+			name:   "one-off clipped",
+			window: types.MaintenanceWindow{Enabled: true, StartsAt: utc("2024-03-01 22:00"), EndsAt: utc("2024-03-02 02:00")},
+			from:   utc("2024-03-02 00:00"), to: utc("2024-03-03 00:00"),
+			want: []TimeInterval{interval("2024-03-02 00:00", "2024-03-02 02:00")},
+		},
+		{
+			name:   "daily in UTC",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 3 * * *", DurationMinutes: 60},
+			from:   utc("2024-03-01 00:00"), to: utc("2024-03-03 00:00"),
+			want: []TimeInterval{interval("2024-03-01 03:00", "2024-03-01 04:00"), interval("2024-03-02 03:00", "2024-03-02 04:00")},
+		},
+		{
+			name:   "running at the start of the range",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 23 * * *", DurationMinutes: 120},
+			from:   utc("2024-03-02 00:00"), to: utc("2024-03-02 12:00"),
+			want: []TimeInterval{interval("2024-03-02 00:00", "2024-03-02 01:00")},
+		},
+		{
+			name:   "bounded recurrence",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 3 * * *", DurationMinutes: 60, StartsAt: utc("2024-03-02 00:00"), EndsAt: utc("2024-03-03 00:00")},
+			from:   utc("2024-03-01 00:00"), to: utc("2024-03-05 00:00"),
+			want: []TimeInterval{interval("2024-03-02 03:00", "2024-03-02 04:00")},
+		},
+		{
+			name:   "local time across spring forward",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 4 * * *", DurationMinutes: 60, Timezone: "Europe/Berlin"},
+			from:   utc("2024-03-30 00:00"), to: utc("2024-04-01 00:00"),
+			want: []TimeInterval{interval("2024-03-30 03:00", "2024-03-30 04:00"), interval("2024-03-31 02:00", "2024-03-31 03:00")},
+		},
+		{
+			// 02:30 does not exist in Berlin on 2024-03-31, so the window opens at 03:00 CEST
+			name:   "start skipped by spring forward",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "30 2 * * *", DurationMinutes: 60, Timezone: "Europe/Berlin"},
+			from:   utc("2024-03-30 00:00"), to: utc("2024-04-02 00:00"),
+			want: []TimeInterval{
+				interval("2024-03-30 01:30", "2024-03-30 02:30"),
+				interval("2024-03-31 01:00", "2024-03-31 02:00"),
+				interval("2024-04-01 00:30", "2024-04-01 01:30"),
+			},
+		},
+		{
+			// The duration is elapsed time, so a window across fall back ends an hour earlier on the clock
+			name:   "duration across fall back",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 1 * * *", DurationMinutes: 180, Timezone: "Europe/Berlin"},
+			from:   utc("2024-10-26 12:00"), to: utc("2024-10-28 00:00"),
+			want: []TimeInterval{interval("2024-10-26 23:00", "2024-10-27 02:00")},
+		},
+		{
+			// 02:00 happens twice in Berlin on 2024-10-27, a daily window opens at the first
+			name:   "start in the repeated hour",
+			window: types.MaintenanceWindow{Enabled: true, Recurrence: "0 2 * * *", DurationMinutes: 30, Timezone: "Europe/Berlin"},
+			from:   utc("2024-10-26 12:00"), to: utc("2024-10-27 12:00"),
+			want: []TimeInterval{interval("2024-10-27 00:00", "2024-10-27 00:30")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MaintenanceIntervals(&tt.window, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("MaintenanceIntervals() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("MaintenanceIntervals()[%d] = %s - %s, want %s - %s", i, got[i].Start, got[i].End, tt.want[i].Start, tt.want[i].End)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeIntervals(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	interval := func(start, end int) TimeInterval {
+		return TimeInterval{Start: base.Add(time.Duration(start) * time.Hour), End: base.Add(time.Duration(end) * time.Hour)}
+	}
+
+	got := MergeIntervals([]TimeInterval{interval(5, 6), interval(0, 2), interval(1, 3), interval(3, 4), interval(2, 3)})
+	want := []TimeInterval{interval(0, 4), interval(5, 6)}
+	if len(got) != len(want) {
+		t.Fatalf("MergeIntervals() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("MergeIntervals()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
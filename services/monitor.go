@@ -1,39 +1,34 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"time"
 	"uptime-monitor/types"
 )
 
 type MonitorService struct {
-	db          *sql.DB
-	http        *HTTPService
-	curl        *CurlService
+	checkers    *CheckerRegistry
 	credentials *CredentialsService
 	monitorRepo MonitorRepositoryInterface
 }
 
 type MonitorRepositoryInterface interface {
 	CreateMonitor(monitor *types.Monitor) error
-	GetAllMonitors() ([]types.Monitor, error)
-	GetMonitorByID(id string) (*types.Monitor, error)
+	GetAllMonitors(profileID string) ([]types.Monitor, error)
+	GetMonitorByID(profileID, id string) (*types.Monitor, error)
 	UpdateMonitor(monitor *types.Monitor) error
-	DeleteMonitor(id string) error
+	UpdateMonitorStatus(monitor *types.Monitor) error
+	DeleteMonitor(profileID, id string) error
 }
 
 func NewMonitorService(
-	db *sql.DB,
-	http *HTTPService,
-	curl *CurlService,
+	checkers *CheckerRegistry,
 	credentials *CredentialsService,
 	monitorRepo MonitorRepositoryInterface,
 ) *MonitorService {
 	return &MonitorService{
-		db:          db,
-		http:        http,
-		curl:        curl,
+		checkers:    checkers,
 		credentials: credentials,
 		monitorRepo: monitorRepo,
 	}
@@ -63,165 +58,36 @@ func (s *MonitorService) CreateMonitor(monitor *types.Monitor) error {
 	return s.monitorRepo.CreateMonitor(monitor)
 }
 
-func (s *MonitorService) GetMonitor(id string) (*types.Monitor, error) {
-	query := `
-		SELECT id, profile_id, name, type, url, method, request_type,
-			   headers, body, credential_id, check_interval, failure_threshold,
-			   is_active, last_check_at, last_status, created_at, updated_at
-		FROM monitors
-		WHERE id = ?
-	`
-
-	var monitor types.Monitor
-	err := s.db.QueryRow(query, id).Scan(
-		&monitor.ID, &monitor.ProfileID, &monitor.Name, &monitor.Type,
-		&monitor.URL, &monitor.Method, &monitor.RequestType,
-		&monitor.Headers, &monitor.Body, &monitor.CredentialID,
-		&monitor.CheckInterval, &monitor.FailureThreshold,
-		&monitor.IsActive, &monitor.LastChecked, &monitor.Status,
-		&monitor.CreatedAt, &monitor.UpdatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &monitor, nil
+func (s *MonitorService) GetMonitor(profileID, id string) (*types.Monitor, error) {
+	return s.monitorRepo.GetMonitorByID(profileID, id)
 }
 
 func (s *MonitorService) UpdateMonitor(monitor *types.Monitor) error {
 	monitor.UpdatedAt = time.Now()
-
-	query := `
-		UPDATE monitors
-		SET name = ?, type = ?, url = ?, method = ?, request_type = ?,
-			headers = ?, body = ?, credential_id = ?, check_interval = ?,
-			failure_threshold = ?, is_active = ?, updated_at = ?
-		WHERE id = ? AND profile_id = ?
-	`
-
-	result, err := s.db.Exec(query,
-		monitor.Name, monitor.Type, monitor.URL, monitor.Method,
-		monitor.RequestType, monitor.Headers, monitor.Body,
-		monitor.CredentialID, monitor.CheckInterval,
-		monitor.FailureThreshold, monitor.IsActive, monitor.UpdatedAt,
-		monitor.ID, monitor.ProfileID,
-	)
-
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
+	return s.monitorRepo.UpdateMonitor(monitor)
 }
 
 func (s *MonitorService) DeleteMonitor(id, profileID string) error {
-	query := "DELETE FROM monitors WHERE id = ? AND profile_id = ?"
-	result, err := s.db.Exec(query, id, profileID)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
+	return s.monitorRepo.DeleteMonitor(profileID, id)
 }
 
 func (s *MonitorService) GetMonitors(profileID string) ([]types.Monitor, error) {
-	query := `
-		SELECT id, profile_id, name, type, url, method, request_type,
-			   headers, body, credential_id, check_interval, failure_threshold,
-			   is_active, last_check_at, last_status, created_at, updated_at
-		FROM monitors
-		WHERE profile_id = ?
-		ORDER BY created_at DESC
-	`
-
-	rows, err := s.db.Query(query, profileID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var monitors []types.Monitor
-	for rows.Next() {
-		var monitor types.Monitor
-		err := rows.Scan(
-			&monitor.ID, &monitor.ProfileID, &monitor.Name, &monitor.Type,
-			&monitor.URL, &monitor.Method, &monitor.RequestType,
-			&monitor.Headers, &monitor.Body, &monitor.CredentialID,
-			&monitor.CheckInterval, &monitor.FailureThreshold,
-			&monitor.IsActive, &monitor.LastChecked, &monitor.Status,
-			&monitor.CreatedAt, &monitor.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		monitors = append(monitors, monitor)
-	}
-
-	return monitors, nil
+	return s.monitorRepo.GetAllMonitors(profileID)
 }
 
-func (s *MonitorService) CheckMonitor(monitor *types.Monitor) (int, error) {
-	var statusCode int
-	var err error
-
-	switch monitor.RequestType {
-	case "curl":
-		statusCode, message, err := s.curl.ExecuteCurlRequest(monitor)
-		if err != nil {
-			return 0, fmt.Errorf("curl request failed: %s", message)
-		}
-		return statusCode, nil
-	default: // "http"
-		resp, err := s.http.ExecuteRequest(monitor)
-		if err != nil {
-			return 0, fmt.Errorf("http request failed: %w", err)
-		}
-		defer resp.Body.Close()
-		statusCode = resp.StatusCode
-	}
+func (s *MonitorService) CheckMonitor(ctx context.Context, monitor *types.Monitor) (*CheckResult, error) {
+	result := s.checkers.Check(ctx, monitor)
 
 	// Update last check time and status
 	monitor.LastChecked = time.Now()
-	monitor.Status = s.getStatusFromCode(statusCode)
-
-	// Update monitor in database
-	query := `
-		UPDATE monitors
-		SET last_check_at = ?, last_status = ?
-		WHERE id = ?
-	`
-	_, err = s.db.Exec(query, monitor.LastChecked, monitor.Status, monitor.ID)
-	if err != nil {
-		return statusCode, fmt.Errorf("failed to update monitor status: %w", err)
-	}
+	monitor.Status = result.Status
+	monitor.ResponseCode = result.ResponseCode
+	monitor.ResponseTime = result.ResponseTime
 
-	return statusCode, nil
-}
-
-func (s *MonitorService) getStatusFromCode(code int) string {
-	switch {
-	case code >= 200 && code < 300:
-		return "up"
-	case code == 401:
-		return "unauthorized"
-	default:
-		return "down"
+	// Only the check results are written, like the scheduler does
+	if err := s.monitorRepo.UpdateMonitorStatus(monitor); err != nil {
+		return result, fmt.Errorf("failed to update monitor status: %w", err)
 	}
+
+	return result, nil
 }
@@ -2,73 +2,45 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/http"
 	"net/smtp"
-	"strings"
+	"net/textproto"
+	"strconv"
 	"time"
 	"uptime-monitor/types"
 )
 
-// NotificationService handles sending notifications through different channels
+// NotificationService sends notifications through a profile's notification methods
 type NotificationService struct {
-	methods []types.NotificationMethod
+	notifiers *NotifierRegistry
+	methods   []types.NotificationMethod
 }
 
 // NewNotificationService creates a new notification service instance
-func NewNotificationService(methods []types.NotificationMethod) *NotificationService {
-	return &NotificationService{methods: methods}
+func NewNotificationService(notifiers *NotifierRegistry, methods []types.NotificationMethod) *NotificationService {
+	return &NotificationService{notifiers: notifiers, methods: methods}
 }
 
-// getStatusInfo returns consistent status information across all notification types
-func (s *NotificationService) getStatusInfo(status string) (symbol string, color string, displayStatus string) {
-	switch strings.ToLower(status) {
-	case "up":
-		return "✅", "good", "UP"
-	case "down":
-		return "❌", "danger", "DOWN"
-	case "unauthorized", "401":
-		return "⚠️", "warning", "UNAUTHORIZED"
-	default:
-		return "⏳", "default", strings.ToUpper(status)
-	}
-}
-
-// SendNotification sends notifications through all enabled channels
+// SendNotification sends a notification right away through each of the
+// service's methods, e.g. to test them. Monitor notifications are routed with
+// RouteNotification and queued for the dispatcher instead.
 func (s *NotificationService) SendNotification(monitor *types.Monitor, status, message string) error {
 	var errs []error
-	notificationSent := make(map[string]bool)
+	n := &Notification{Monitor: monitor, Status: status, Message: message, Time: time.Now()}
 
 	for _, method := range s.methods {
-		// Skip if this notification type was already sent
-		if notificationSent[method.Type] {
-			continue
-		}
-
-		// Parse config JSON
-		var config map[string]interface{}
-		if err := json.Unmarshal([]byte(method.Config), &config); err != nil {
-			errs = append(errs, fmt.Errorf("config parse error for %s: %v", method.Type, err))
-			continue
-		}
-
-		switch method.Type {
-		case "email":
-			if err := s.SendEmail(monitor, status, message, config); err != nil {
-				errs = append(errs, fmt.Errorf("email error: %v", err))
-			}
-			notificationSent[method.Type] = true
-		case "slack":
-			if err := s.SendSlack(monitor, status, message, config); err != nil {
-				errs = append(errs, fmt.Errorf("slack error: %v", err))
-			}
-			notificationSent[method.Type] = true
-		case "teams":
-			if err := s.SendTeams(monitor, status, message, config); err != nil {
-				errs = append(errs, fmt.Errorf("teams error: %v", err))
-			}
-			notificationSent[method.Type] = true
+		if err := s.notifiers.Send(&method, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s error: %v", method.Type, err))
 		}
 	}
 
@@ -78,94 +50,199 @@ func (s *NotificationService) SendNotification(monitor *types.Monitor, status, m
 	return nil
 }
 
-// SendEmail sends notification via email
-func (s *NotificationService) SendEmail(monitor *types.Monitor, status, message string, config map[string]interface{}) error {
-	symbol, _, displayStatus := s.getStatusInfo(status)
+// EmailNotifier sends notifications by email through the method's SMTP server
+type EmailNotifier struct{}
 
-	subject := fmt.Sprintf("%s Monitor Alert: %s is %s", symbol, monitor.Name, displayStatus)
-	body := fmt.Sprintf(
-		"Monitor Status Notification\n\n"+
-			"%s Status: %s\n"+
-			"Monitor: %s\n"+
-			"URL: %s\n"+
-			"Message: %s\n"+
-			"Response Code: %d\n"+
-			"Response Time: %dms\n"+
-			"Time: %s",
-		symbol,
-		displayStatus,
-		monitor.Name,
-		monitor.URL,
-		message,
-		monitor.ResponseCode,
-		monitor.ResponseTime,
-		time.Now().Format(time.RFC1123),
-	)
+type emailConfig struct {
+	SMTPHost       string `json:"smtp_host"`
+	SMTPPort       int    `json:"smtp_port"`
+	SMTPEmail      string `json:"smtp_email"`
+	SMTPPassword   string `json:"smtp_password"`
+	RecipientEmail string `json:"recipient_email"`
+}
 
-	smtpHost := config["smtp_host"].(string)
-	smtpPort := config["smtp_port"].(float64)
-	smtpEmail := config["smtp_email"].(string)
-	smtpPassword := config["smtp_password"].(string)
-	recipientEmail := config["recipient_email"].(string)
+// Validate implements Notifier
+func (e *EmailNotifier) Validate(config json.RawMessage) error {
+	var cfg emailConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if cfg.SMTPPort <= 0 || cfg.SMTPPort > 65535 {
+		return fmt.Errorf("smtp_port must be between 1 and 65535")
+	}
+	return requireFields("smtp_host", cfg.SMTPHost, "smtp_email", cfg.SMTPEmail,
+		"smtp_password", cfg.SMTPPassword, "recipient_email", cfg.RecipientEmail)
+}
+
+// Send implements Notifier. Notifications with an HTML body are sent as
+// multipart/alternative emails with the plain text body as the fallback.
+func (e *EmailNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg emailConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	msg, err := buildEmail(cfg.SMTPEmail, cfg.RecipientEmail, n.Subject, n.Body, n.HTML)
+	if err != nil {
+		return err
+	}
+
+	auth := smtp.PlainAuth("", cfg.SMTPEmail, cfg.SMTPPassword, cfg.SMTPHost)
+	to := []string{cfg.RecipientEmail}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	return sendMail(ctx, addr, cfg.SMTPHost, auth, cfg.SMTPEmail, to, msg)
+}
+
+// sendMail sends an email like smtp.SendMail, but gives up when ctx is done, so
+// that a stalled SMTP server cannot hold up the delivery past its timeout
+func sendMail(ctx context.Context, addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	// Unblock the SMTP exchange if ctx is cancelled before its deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := c.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildEmail returns an email message with a plain text body, and an HTML
+// alternative if html isn't empty
+func buildEmail(from, to, subject, text, html string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	if html == "" {
+		fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-	auth := smtp.PlainAuth("", smtpEmail, smtpPassword, smtpHost)
-	to := []string{recipientEmail}
+// writeQuotedPrintable writes s to w in the quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
 
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"Content-Type: text/plain; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s\r\n",
-		recipientEmail,
-		smtpEmail,
-		subject,
-		body))
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	client *http.Client
+}
 
-	addr := fmt.Sprintf("%s:%v", smtpHost, int(smtpPort))
-	return smtp.SendMail(addr, auth, smtpEmail, to, msg)
+type slackConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel"`
 }
 
-// SendSlack sends notification to Slack
-func (s *NotificationService) SendSlack(monitor *types.Monitor, status, message string, config map[string]interface{}) error {
-	symbol, _, displayStatus := s.getStatusInfo(status)
-	webhookURL := config["webhook_url"].(string)
-	channel := config["channel"].(string)
+// Validate implements Notifier
+func (sl *SlackNotifier) Validate(config json.RawMessage) error {
+	var cfg slackConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	return requireFields("webhook_url", cfg.WebhookURL)
+}
 
+// Send implements Notifier
+func (sl *SlackNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg slackConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
 	payload := map[string]interface{}{
-		"channel": channel,
+		"channel": cfg.Channel,
+		"text":    n.Subject, // Shown in notifications of clients that can't display blocks
 		"blocks": []map[string]interface{}{
 			{
 				"type": "header",
 				"text": map[string]interface{}{
 					"type":  "plain_text",
-					"text":  fmt.Sprintf("%s Monitor Alert: %s", symbol, monitor.Name),
+					"text":  truncate(n.Subject, 150),
 					"emoji": true,
 				},
 			},
 			{
 				"type": "section",
-				"fields": []map[string]interface{}{
-					{
-						"type": "mrkdwn",
-						"text": fmt.Sprintf("*Status:*\n%s %s", symbol, displayStatus),
-					},
-					{
-						"type": "mrkdwn",
-						"text": fmt.Sprintf("*URL:*\n%s", monitor.URL),
-					},
-					{
-						"type": "mrkdwn",
-						"text": fmt.Sprintf("*Message:*\n%s", message),
-					},
-					{
-						"type": "mrkdwn",
-						"text": fmt.Sprintf("*Response Code:*\n%d", monitor.ResponseCode),
-					},
-					{
-						"type": "mrkdwn",
-						"text": fmt.Sprintf("*Response Time:*\n%dms", monitor.ResponseTime),
-					},
+				"text": map[string]interface{}{
+					"type": "mrkdwn",
+					"text": truncate(n.Body, 3000),
 				},
 			},
 			{
@@ -173,37 +250,44 @@ func (s *NotificationService) SendSlack(monitor *types.Monitor, status, message
 				"elements": []map[string]interface{}{
 					{
 						"type": "mrkdwn",
-						"text": fmt.Sprintf("Time: %s", time.Now().Format(time.RFC1123)),
+						"text": fmt.Sprintf("Time: %s", n.Time.Format(time.RFC1123)),
 					},
 				},
 			},
 		},
 	}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	if err := postJSON(ctx, sl.client, http.MethodPost, cfg.WebhookURL, nil, payload); err != nil {
+		return fmt.Errorf("Slack API %w", err)
 	}
+	return nil
+}
 
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Slack API returned status code: %d", resp.StatusCode)
-	}
+// TeamsNotifier posts notifications to a Microsoft Teams incoming webhook
+type TeamsNotifier struct {
+	client *http.Client
+}
 
-	return nil
+type teamsConfig struct {
+	WebhookURL string `json:"webhook_url"`
 }
 
-// SendTeams sends notification to Microsoft Teams
-func (s *NotificationService) SendTeams(monitor *types.Monitor, status, message string, config map[string]interface{}) error {
-	symbol, cardColor, displayStatus := s.getStatusInfo(status)
-	webhookURL := config["webhook_url"].(string)
+// Validate implements Notifier
+func (t *TeamsNotifier) Validate(config json.RawMessage) error {
+	var cfg teamsConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	return requireFields("webhook_url", cfg.WebhookURL)
+}
 
-	title := fmt.Sprintf("%s Monitor Alert: %s", symbol, monitor.Name)
+// Send implements Notifier
+func (t *TeamsNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg teamsConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	_, cardColor, _ := statusInfo(n.Status)
 
 	payload := map[string]interface{}{
 		"type": "message",
@@ -215,26 +299,15 @@ func (s *NotificationService) SendTeams(monitor *types.Monitor, status, message
 					"body": []map[string]interface{}{
 						{
 							"type":   "TextBlock",
-							"text":   title,
+							"text":   n.Subject,
 							"weight": "bolder",
 							"size":   "large",
-							"wrap":   true,
-						},
-						{
-							"type":   "TextBlock",
-							"text":   fmt.Sprintf("Status: %s %s", symbol, displayStatus),
-							"weight": "bolder",
 							"color":  cardColor,
 							"wrap":   true,
 						},
 						{
 							"type": "TextBlock",
-							"text": fmt.Sprintf("URL: %s\nMessage: %s\nResponse Code: %d\nResponse Time: %dms\nTime: %s",
-								monitor.URL,
-								message,
-								monitor.ResponseCode,
-								monitor.ResponseTime,
-								time.Now().Format(time.RFC1123)),
+							"text": n.Body,
 							"wrap": true,
 						},
 					},
@@ -245,20 +318,8 @@ func (s *NotificationService) SendTeams(monitor *types.Monitor, status, message
 		},
 	}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
+	if err := postJSON(ctx, t.client, http.MethodPost, cfg.WebhookURL, nil, payload); err != nil {
+		return fmt.Errorf("Teams API %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Teams API returned status code: %d", resp.StatusCode)
-	}
-
 	return nil
 }
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"uptime-monitor/types"
+)
+
+// Severities lists the severities a monitor may have
+var Severities = []string{types.SeverityCritical, types.SeverityWarning, types.SeverityInfo}
+
+// ValidateSeverity checks a monitor severity, empty meaning critical
+func ValidateSeverity(severity string) error {
+	if severity != "" && !containsString(Severities, strings.ToLower(severity)) {
+		return fmt.Errorf("severity must be critical, warning or info")
+	}
+	return nil
+}
+
+// ValidateNotificationRule checks that a rule's conditions are well formed.
+// Whether its monitor and methods belong to the profile is checked by the caller.
+func ValidateNotificationRule(rule *types.NotificationRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, severity := range types.SplitList(rule.Severities) {
+		if err := ValidateSeverity(severity); err != nil {
+			return err
+		}
+	}
+	for _, event := range types.SplitList(rule.Events) {
+		if !containsString(types.NotificationEvents, strings.ToLower(event)) {
+			return fmt.Errorf("unknown event %q, expected one of %s", event, strings.Join(types.NotificationEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// Event returns the notification event a notification is routed as
+func (n *Notification) Event() string {
+	switch {
+	case n.IsRecovery():
+		return types.NotificationEventRecovered
+	case n.IsCertificateAlert():
+		return types.NotificationEventCertExpiring
+	case n.Status == "degraded":
+		return types.NotificationEventDegraded
+	default:
+		return types.NotificationEventDown
+	}
+}
+
+// RouteNotification returns the enabled methods a notification is sent through,
+// in the order of methods. The enabled rules of the notification's monitor
+// decide, or the profile's rules if the monitor has none; a method is picked
+// when any matching rule lists it. Without rules every enabled method is picked.
+func RouteNotification(n *Notification, methods []types.NotificationMethod, rules []types.NotificationRule) []types.NotificationMethod {
+	var profileRules, monitorRules []types.NotificationRule
+	for _, rule := range rules {
+		switch {
+		case !rule.Enabled:
+		case rule.MonitorID == n.Monitor.ID:
+			monitorRules = append(monitorRules, rule)
+		case rule.MonitorID == "":
+			profileRules = append(profileRules, rule)
+		}
+	}
+	applicable := profileRules
+	if len(monitorRules) > 0 {
+		applicable = monitorRules
+	}
+
+	everyMethod := len(applicable) == 0
+	picked := make(map[string]bool)
+	event := n.Event()
+	for _, rule := range applicable {
+		if !rule.Matches(n.Monitor, event) {
+			continue
+		}
+		methodIDs := types.SplitList(rule.MethodIDs)
+		if len(methodIDs) == 0 {
+			everyMethod = true
+		}
+		for _, id := range methodIDs {
+			picked[id] = true
+		}
+	}
+
+	var targets []types.NotificationMethod
+	for _, method := range methods {
+		if method.Enabled && (everyMethod || picked[method.ID]) {
+			targets = append(targets, method)
+		}
+	}
+	return targets
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"uptime-monitor/types"
+)
+
+func TestRouteNotification(t *testing.T) {
+	methods := []types.NotificationMethod{
+		{ID: "email", Type: "email", Enabled: true},
+		{ID: "slack", Type: "slack", Enabled: true},
+		{ID: "pager", Type: "pagerduty", Enabled: true},
+		{ID: "off", Type: "webhook", Enabled: false},
+	}
+	api := &types.Monitor{ID: "api", Tags: "production,api"}
+	batch := &types.Monitor{ID: "batch", Tags: "staging", Severity: types.SeverityInfo}
+
+	tests := []struct {
+		name    string
+		monitor *types.Monitor
+		status  string
+		rules   []types.NotificationRule
+		want    string // Comma separated method IDs
+	}{
+		{
+			name:    "no rules",
+			monitor: api, status: "down",
+			want: "email,slack,pager",
+		},
+		{
+			name:    "disabled rules are ignored",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{{Enabled: false, MethodIDs: "pager"}},
+			want:  "email,slack,pager",
+		},
+		{
+			name:    "tag rule",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{
+				{Enabled: true, Tags: "production", MethodIDs: "pager"},
+				{Enabled: true, Tags: "staging", MethodIDs: "email"},
+			},
+			want: "pager",
+		},
+		{
+			name:    "no matching rule",
+			monitor: batch, status: "down",
+			rules: []types.NotificationRule{{Enabled: true, Tags: "production", MethodIDs: "pager"}},
+			want:  "",
+		},
+		{
+			name:    "severity rule",
+			monitor: batch, status: "down",
+			rules: []types.NotificationRule{
+				{Enabled: true, Severities: "critical", MethodIDs: "pager"},
+				{Enabled: true, Severities: "warning,info", MethodIDs: "slack"},
+			},
+			want: "slack",
+		},
+		{
+			name:    "monitor without severity is critical",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{{Enabled: true, Severities: "critical", MethodIDs: "pager"}},
+			want:  "pager",
+		},
+		{
+			name:    "event rule",
+			monitor: api, status: "up",
+			rules: []types.NotificationRule{
+				{Enabled: true, Events: "down", MethodIDs: "pager"},
+				{Enabled: true, Events: "down,recovered", MethodIDs: "slack"},
+			},
+			want: "slack",
+		},
+		{
+			name:    "certificate alerts",
+			monitor: api, status: "cert_warning",
+			rules: []types.NotificationRule{{Enabled: true, Events: "cert_expiring", MethodIDs: "email"}},
+			want:  "email",
+		},
+		{
+			name:    "degraded",
+			monitor: api, status: "degraded",
+			rules: []types.NotificationRule{{Enabled: true, Events: "down", MethodIDs: "pager"}, {Enabled: true, Events: "degraded", MethodIDs: "slack"}},
+			want:  "slack",
+		},
+		{
+			name:    "unauthorized is routed as down",
+			monitor: api, status: "unauthorized",
+			rules: []types.NotificationRule{{Enabled: true, Events: "down", MethodIDs: "pager"}},
+			want:  "pager",
+		},
+		{
+			name:    "rule without methods picks every method",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{{Enabled: true, Tags: "api"}, {Enabled: true, Tags: "api", MethodIDs: "pager"}},
+			want:  "email,slack,pager",
+		},
+		{
+			name:    "methods of matching rules are combined in method order",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{{Enabled: true, Tags: "api", MethodIDs: "pager"}, {Enabled: true, Tags: "production", MethodIDs: "slack,email"}},
+			want:  "email,slack,pager",
+		},
+		{
+			name:    "disabled and unknown methods are skipped",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{{Enabled: true, MethodIDs: "off,deleted,slack"}},
+			want:  "slack",
+		},
+		{
+			name:    "monitor rules override profile rules",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{
+				{Enabled: true, MethodIDs: "email"},
+				{Enabled: true, MonitorID: "api", MethodIDs: "pager"},
+			},
+			want: "pager",
+		},
+		{
+			name:    "monitor rules that do not match silence the monitor",
+			monitor: api, status: "up",
+			rules: []types.NotificationRule{
+				{Enabled: true, MethodIDs: "email"},
+				{Enabled: true, MonitorID: "api", Events: "down", MethodIDs: "pager"},
+			},
+			want: "",
+		},
+		{
+			name:    "other monitors' rules do not apply",
+			monitor: batch, status: "down",
+			rules: []types.NotificationRule{
+				{Enabled: true, MethodIDs: "email"},
+				{Enabled: true, MonitorID: "api", MethodIDs: "pager"},
+			},
+			want: "email",
+		},
+		{
+			name:    "disabled monitor rules fall back to profile rules",
+			monitor: api, status: "down",
+			rules: []types.NotificationRule{
+				{Enabled: true, MethodIDs: "email"},
+				{Enabled: false, MonitorID: "api", MethodIDs: "pager"},
+			},
+			want: "email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Notification{Monitor: tt.monitor, Status: tt.status}
+			var got []string
+			for _, method := range RouteNotification(n, methods, tt.rules) {
+				got = append(got, method.ID)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("RouteNotification() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNotificationRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    types.NotificationRule
+		wantErr string
+	}{
+		{"valid", types.NotificationRule{Name: "on-call", Severities: "Critical, warning", Events: "down,RECOVERED"}, ""},
+		{"no conditions", types.NotificationRule{Name: "all"}, ""},
+		{"no name", types.NotificationRule{}, "name is required"},
+		{"unknown severity", types.NotificationRule{Name: "x", Severities: "critical,urgent"}, "severity must be critical, warning or info"},
+		{"unknown event", types.NotificationRule{Name: "x", Events: "down,flapping"}, `unknown event "flapping"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotificationRule(&tt.rule)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateNotificationRule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateNotificationRule() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
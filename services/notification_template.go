@@ -0,0 +1,322 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"uptime-monitor/types"
+)
+
+// defaultDashboardURL is linked from notifications when HEIMDALL_BASE_URL isn't set
+const defaultDashboardURL = "http://localhost:8080"
+
+// NotificationTemplates are the templates a notification method's text is rendered from
+type NotificationTemplates struct {
+	Subject string `json:"subject_template"`
+	Body    string `json:"body_template"`
+	HTML    string `json:"html_template,omitempty"` // Email only
+}
+
+// TemplateData is what notification templates are rendered with
+type TemplateData struct {
+	Monitor          TemplateMonitor
+	Status           string // up, down, unauthorized, degraded or cert_*
+	DisplayStatus    string // Status as shown to people, e.g. DOWN
+	Symbol           string // Emoji of the status
+	Color            string // Hex color of the status, e.g. #e74c3c
+	PreviousStatus   string // Status before the change, empty for certificate alerts
+	Message          string
+	Time             time.Time
+	IncidentDuration time.Duration // How long the outage has lasted, 0 if there is none
+	ResponseCode     int
+	ResponseTime     int64  // Milliseconds
+	Target           string // URL or host the monitor checks
+	DashboardURL     string // Dashboard page of the monitor
+}
+
+// TemplateMonitor is the monitor a notification is about. It only holds what
+// the outbox keeps of the monitor when the notification is queued (see
+// types.NotificationDelivery), so that a template renders the same when it is
+// previewed and when it is delivered.
+type TemplateMonitor struct {
+	ID       string
+	Name     string
+	Type     string
+	URL      string // Empty for host-based and database monitors
+	Hostname string
+	Tags     string // Comma separated
+	Severity string // critical, warning or info
+}
+
+// notificationTemplateFuncs are available in all notification templates
+var notificationTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"duration": formatDuration,
+}
+
+const (
+	defaultSubjectTemplate = `{{.Symbol}} Monitor Alert: {{.Monitor.Name}} is {{.DisplayStatus}}`
+
+	defaultBodyTemplate = `Status: {{.Symbol}} {{.DisplayStatus}}{{if .PreviousStatus}} (was {{upper .PreviousStatus}}){{end}}
+URL: {{.Target}}
+Message: {{.Message}}
+Response Code: {{.ResponseCode}}
+Response Time: {{.ResponseTime}}ms
+{{- if .IncidentDuration}}
+Incident Duration: {{duration .IncidentDuration}}
+{{- end}}
+Time: {{.Time.Format "Mon, 02 Jan 2006 15:04:05 MST"}}
+Dashboard: {{.DashboardURL}}`
+
+	defaultEmailBodyTemplate = `Monitor Status Notification
+
+{{.Symbol}} Status: {{.DisplayStatus}}{{if .PreviousStatus}} (was {{upper .PreviousStatus}}){{end}}
+Monitor: {{.Monitor.Name}}
+URL: {{.Target}}
+Message: {{.Message}}
+Response Code: {{.ResponseCode}}
+Response Time: {{.ResponseTime}}ms
+{{- if .IncidentDuration}}
+Incident Duration: {{duration .IncidentDuration}}
+{{- end}}
+Time: {{.Time.Format "Mon, 02 Jan 2006 15:04:05 MST"}}
+
+Open the dashboard: {{.DashboardURL}}`
+
+	defaultEmailHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, Helvetica, sans-serif; color: #222;">
+  <h2 style="color: {{.Color}};">{{.Symbol}} {{.Monitor.Name}} is {{.DisplayStatus}}</h2>
+  <table cellpadding="6" style="border-collapse: collapse;">
+    <tr><td><strong>Status</strong></td><td>{{.DisplayStatus}}{{if .PreviousStatus}} (was {{upper .PreviousStatus}}){{end}}</td></tr>
+    <tr><td><strong>URL</strong></td><td>{{.Target}}</td></tr>
+    <tr><td><strong>Message</strong></td><td>{{.Message}}</td></tr>
+    <tr><td><strong>Response Code</strong></td><td>{{.ResponseCode}}</td></tr>
+    <tr><td><strong>Response Time</strong></td><td>{{.ResponseTime}}ms</td></tr>
+    {{- if .IncidentDuration}}
+    <tr><td><strong>Incident Duration</strong></td><td>{{duration .IncidentDuration}}</td></tr>
+    {{- end}}
+    <tr><td><strong>Time</strong></td><td>{{.Time.Format "Mon, 02 Jan 2006 15:04:05 MST"}}</td></tr>
+  </table>
+  <p><a href="{{.DashboardURL}}">Open the dashboard</a></p>
+</body>
+</html>`
+
+	defaultSlackBodyTemplate = `*Status:* {{.Symbol}} {{.DisplayStatus}}{{if .PreviousStatus}} (was {{upper .PreviousStatus}}){{end}}
+*URL:* {{.Target}}
+*Message:* {{.Message}}
+*Response Code:* {{.ResponseCode}}    *Response Time:* {{.ResponseTime}}ms
+{{- if .IncidentDuration}}
+*Incident Duration:* {{duration .IncidentDuration}}
+{{- end}}
+<{{.DashboardURL}}|Open the dashboard>`
+
+	defaultMarkdownBodyTemplate = `**Status:** {{.Symbol}} {{.DisplayStatus}}{{if .PreviousStatus}} (was {{upper .PreviousStatus}}){{end}}
+**URL:** {{.Target}}
+**Message:** {{.Message}}
+**Response Code:** {{.ResponseCode}}
+**Response Time:** {{.ResponseTime}}ms
+{{- if .IncidentDuration}}
+**Incident Duration:** {{duration .IncidentDuration}}
+{{- end}}
+[Open the dashboard]({{.DashboardURL}})`
+)
+
+// DefaultTemplates returns the templates a notification method type renders
+// notifications with when the method doesn't set its own
+func DefaultTemplates(methodType string) NotificationTemplates {
+	templates := NotificationTemplates{Subject: defaultSubjectTemplate, Body: defaultBodyTemplate}
+	switch methodType {
+	case "email":
+		templates.Body = defaultEmailBodyTemplate
+		templates.HTML = defaultEmailHTMLTemplate
+	case "slack":
+		templates.Body = defaultSlackBodyTemplate
+	case "teams", "discord", "mattermost":
+		templates.Body = defaultMarkdownBodyTemplate
+	}
+	return templates
+}
+
+// MethodTemplates returns the templates of a notification method, with the
+// type's defaults for the ones it doesn't set
+func MethodTemplates(method *types.NotificationMethod) NotificationTemplates {
+	templates := DefaultTemplates(method.Type)
+	if method.SubjectTemplate != "" {
+		templates.Subject = method.SubjectTemplate
+	}
+	if method.BodyTemplate != "" {
+		templates.Body = method.BodyTemplate
+	}
+	if method.HTMLTemplate != "" {
+		templates.HTML = method.HTMLTemplate
+	}
+	return templates
+}
+
+// ValidateTemplates checks that a notification method's templates render,
+// using a sample notification
+func ValidateTemplates(method *types.NotificationMethod) error {
+	if method.HTMLTemplate != "" && method.Type != "email" {
+		return fmt.Errorf("html_template is only supported by email methods")
+	}
+	_, err := RenderNotification(method, SampleNotification("down"))
+	return err
+}
+
+// RenderNotification returns a copy of a notification with its subject and
+// body rendered from a notification method's templates
+func RenderNotification(method *types.NotificationMethod, n *Notification) (*Notification, error) {
+	parsed, err := parseTemplates(MethodTemplates(method))
+	if err != nil {
+		return nil, err
+	}
+	data := newTemplateData(n)
+
+	rendered := *n
+	var buf bytes.Buffer
+	if err := parsed.subject.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("%w: subject_template: %v", errInvalidTemplate, err)
+	}
+	// A subject is a single line, whatever the template produced
+	rendered.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := parsed.body.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("%w: body_template: %v", errInvalidTemplate, err)
+	}
+	rendered.Body = strings.TrimSpace(buf.String())
+
+	rendered.HTML = ""
+	if parsed.html != nil {
+		buf.Reset()
+		if err := parsed.html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("%w: html_template: %v", errInvalidTemplate, err)
+		}
+		rendered.HTML = buf.String()
+	}
+	return &rendered, nil
+}
+
+// SampleNotification returns a notification of the given status for a made-up
+// monitor, to preview templates with
+func SampleNotification(status string) *Notification {
+	now := time.Now()
+	monitor := &types.Monitor{
+		ID:           "00000000-0000-0000-0000-000000000000",
+		ProfileID:    "00000000-0000-0000-0000-000000000000",
+		Name:         "Example API",
+		Type:         types.MonitorTypeHTTP,
+		URL:          "https://api.example.com/health",
+		Tags:         "production,api",
+		ResponseCode: 503,
+		ResponseTime: 1245,
+	}
+	n := &Notification{
+		Monitor:           monitor,
+		Status:            status,
+		PreviousStatus:    "up",
+		Message:           "Unexpected status code 503 (expected 200-299)",
+		Time:              now,
+		IncidentStartedAt: now.Add(-12 * time.Minute),
+	}
+
+	switch {
+	case n.IsRecovery():
+		monitor.ResponseCode = 200
+		monitor.ResponseTime = 182
+		n.PreviousStatus = "down"
+		n.Message = "Monitor is up"
+	case n.IsCertificateAlert():
+		monitor.ResponseCode = 200
+		monitor.ResponseTime = 182
+		n.PreviousStatus = ""
+		n.IncidentStartedAt = time.Time{}
+		n.Message = "TLS certificate for Example API expires in 7 days on " + now.AddDate(0, 0, 7).Format(time.RFC1123)
+	}
+	return n
+}
+
+// parsedTemplates are the parsed templates of a notification method
+type parsedTemplates struct {
+	subject *template.Template
+	body    *template.Template
+	html    *htmltemplate.Template // nil if the method sends no HTML
+}
+
+func parseTemplates(templates NotificationTemplates) (*parsedTemplates, error) {
+	var parsed parsedTemplates
+	var err error
+	if parsed.subject, err = template.New("subject").Funcs(notificationTemplateFuncs).Parse(templates.Subject); err != nil {
+		return nil, fmt.Errorf("%w: subject_template: %v", errInvalidTemplate, err)
+	}
+	if parsed.body, err = template.New("body").Funcs(notificationTemplateFuncs).Parse(templates.Body); err != nil {
+		return nil, fmt.Errorf("%w: body_template: %v", errInvalidTemplate, err)
+	}
+	if templates.HTML != "" {
+		funcs := htmltemplate.FuncMap(notificationTemplateFuncs)
+		if parsed.html, err = htmltemplate.New("html").Funcs(funcs).Parse(templates.HTML); err != nil {
+			return nil, fmt.Errorf("%w: html_template: %v", errInvalidTemplate, err)
+		}
+	}
+	return &parsed, nil
+}
+
+func newTemplateData(n *Notification) *TemplateData {
+	return &TemplateData{
+		Monitor: TemplateMonitor{
+			ID:       n.Monitor.ID,
+			Name:     n.Monitor.Name,
+			Type:     n.Monitor.Type,
+			URL:      n.Monitor.URL,
+			Hostname: n.Monitor.Hostname,
+			Tags:     n.Monitor.Tags,
+			Severity: n.Monitor.GetSeverity(),
+		},
+		Status:           n.Status,
+		DisplayStatus:    n.DisplayStatus(),
+		Symbol:           n.Symbol(),
+		Color:            fmt.Sprintf("#%06x", statusColor(n.Status)),
+		PreviousStatus:   n.PreviousStatus,
+		Message:          n.Message,
+		Time:             n.Time,
+		IncidentDuration: n.IncidentDuration(),
+		ResponseCode:     n.Monitor.ResponseCode,
+		ResponseTime:     n.Monitor.ResponseTime,
+		Target:           monitorSource(n.Monitor),
+		DashboardURL:     monitorDashboardURL(n.Monitor),
+	}
+}
+
+// dashboardURL returns the address of the dashboard linked from notifications,
+// set with HEIMDALL_BASE_URL
+func dashboardURL() string {
+	if base := strings.TrimRight(os.Getenv("HEIMDALL_BASE_URL"), "/"); base != "" {
+		return base + "/"
+	}
+	return defaultDashboardURL + "/"
+}
+
+// monitorDashboardURL returns the address of the dashboard showing a monitor
+func monitorDashboardURL(monitor *types.Monitor) string {
+	query := url.Values{}
+	query.Set("profile", monitor.ProfileID)
+	query.Set("monitor", monitor.ID)
+	return dashboardURL() + "?" + query.Encode()
+}
+
+// formatDuration formats a duration to the second, e.g. 1h12m5s
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/types"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  types.NotificationMethod
+		wantErr string
+	}{
+		{"defaults", types.NotificationMethod{Type: "webhook"}, ""},
+		{"monitor fields", types.NotificationMethod{Type: "slack", BodyTemplate: "{{.Monitor.Name}} {{.Monitor.Tags}} {{.Monitor.Severity}} {{.Monitor.Hostname}}"}, ""},
+		{"functions", types.NotificationMethod{Type: "webhook", BodyTemplate: `{{json .Message}} {{upper .Status}} {{duration .IncidentDuration}}`}, ""},
+		{"syntax error", types.NotificationMethod{Type: "webhook", SubjectTemplate: "{{.Status"}, "subject_template"},
+		{"unknown field", types.NotificationMethod{Type: "webhook", BodyTemplate: "{{.Nope}}"}, "body_template"},
+		{"monitor field not kept by the outbox", types.NotificationMethod{Type: "webhook", BodyTemplate: "{{.Monitor.DBPassword}}"}, "body_template"},
+		{"html on a non-email method", types.NotificationMethod{Type: "slack", HTMLTemplate: "<p>{{.Status}}</p>"}, "html_template"},
+		{"html email", types.NotificationMethod{Type: "email", HTMLTemplate: "<p>{{.Monitor.Name}}</p>"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplates(&tt.method)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateTemplates() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateTemplates() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Every monitor field a template can use must survive the outbox, which
+// rebuilds the monitor from its delivery snapshot
+func TestTemplateMonitorIsSnapshotted(t *testing.T) {
+	delivery := &types.NotificationDelivery{
+		ProfileID:   "p1",
+		MonitorID:   "m1",
+		MonitorName: "API",
+		MonitorType: types.MonitorTypeHTTP,
+		URL:         "https://api.example.com/",
+		Hostname:    "api.example.com",
+		DBHost:      "db.example.com",
+		Tags:        "production",
+		Severity:    types.SeverityWarning,
+	}
+	data := newTemplateData(&Notification{Monitor: delivery.Monitor(), Status: "down", Time: time.Now()})
+
+	monitor := reflect.ValueOf(data.Monitor)
+	for i := 0; i < monitor.NumField(); i++ {
+		if monitor.Field(i).IsZero() {
+			t.Errorf("TemplateMonitor.%s is not kept by the delivery snapshot", monitor.Type().Field(i).Name)
+		}
+	}
+}
+
+func TestRenderNotificationLinksToMonitor(t *testing.T) {
+	t.Setenv("HEIMDALL_BASE_URL", "https://heimdall.example.com/")
+	n := SampleNotification("down")
+	rendered, err := RenderNotification(&types.NotificationMethod{Type: "webhook", BodyTemplate: "{{.DashboardURL}}"}, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://heimdall.example.com/?monitor=" + n.Monitor.ID + "&profile=" + n.Monitor.ProfileID
+	if rendered.Body != want {
+		t.Errorf("DashboardURL = %q, want %q", rendered.Body, want)
+	}
+}
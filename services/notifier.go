@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"uptime-monitor/types"
+)
+
+// notifierTimeout bounds the HTTP requests sent by notifiers
+const notifierTimeout = 15 * time.Second
+
+// MaxDeliveryAttempts is the most attempts a notification method may make per notification
+const MaxDeliveryAttempts = 20
+
+// notificationTimeout bounds the delivery through a single notification method
+const notificationTimeout = 30 * time.Second
+
+// Config errors fail the same way on every attempt, so they are never retried
+var (
+	errConfigRequired  = errors.New("config is required")
+	errInvalidConfig   = errors.New("invalid config")
+	errUnsupportedType = errors.New("unsupported notification type")
+	errInvalidTemplate = errors.New("invalid template")
+)
+
+// HTTPStatusError is returned by notifiers when an endpoint answers outside 2xx
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string // Start of the response body
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("returned status code %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("returned status code %d", e.StatusCode)
+}
+
+// IsRetryable reports whether a failed delivery may succeed when it is retried.
+// Requests rejected with a 4xx status are not retried, except for timeouts and
+// rate limiting.
+func IsRetryable(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, errConfigRequired) && !errors.Is(err, errInvalidConfig) && !errors.Is(err, errUnsupportedType) && !errors.Is(err, errInvalidTemplate)
+}
+
+// Notification is a monitor status change, or certificate alert, to deliver
+type Notification struct {
+	Monitor           *types.Monitor
+	Status            string // up, down, unauthorized, degraded, cert_warning, cert_critical or cert_expired
+	PreviousStatus    string // Status before the change, empty for certificate alerts
+	Message           string
+	Time              time.Time
+	IncidentStartedAt time.Time // Start of the monitor's outage, zero if there is none
+
+	// Rendered from the method's templates before the notification is sent
+	Subject string
+	Body    string
+	HTML    string // Email only
+}
+
+// Symbol returns the emoji shown for the notification's status
+func (n *Notification) Symbol() string {
+	symbol, _, _ := statusInfo(n.Status)
+	return symbol
+}
+
+// DisplayStatus returns the status as shown to people
+func (n *Notification) DisplayStatus() string {
+	_, _, display := statusInfo(n.Status)
+	return display
+}
+
+// Title returns a one-line summary of the notification
+func (n *Notification) Title() string {
+	return fmt.Sprintf("%s Monitor Alert: %s is %s", n.Symbol(), n.Monitor.Name, n.DisplayStatus())
+}
+
+// IncidentDuration returns how long the monitor's outage has lasted, or lasted
+// when it recovered
+func (n *Notification) IncidentDuration() time.Duration {
+	if n.IncidentStartedAt.IsZero() {
+		return 0
+	}
+	return n.Time.Sub(n.IncidentStartedAt)
+}
+
+// IsRecovery reports whether the notification tells that a monitor is up again
+func (n *Notification) IsRecovery() bool {
+	return strings.ToLower(n.Status) == "up"
+}
+
+// IsCertificateAlert reports whether the notification is a certificate expiry alert
+func (n *Notification) IsCertificateAlert() bool {
+	return strings.HasPrefix(n.Status, "cert_")
+}
+
+// Notifier delivers notifications through one type of notification method.
+// The config is the method's JSON config, with its secrets decrypted.
+type Notifier interface {
+	// Validate checks a method's config when it is saved. Secret fields may
+	// hold masked values, so they are only checked for presence.
+	Validate(config json.RawMessage) error
+	Send(ctx context.Context, n *Notification, config json.RawMessage) error
+}
+
+// NotifierRegistry maps notification method types to the notifier that handles them
+type NotifierRegistry struct {
+	mu        sync.RWMutex
+	notifiers map[string]Notifier
+}
+
+// NewNotifierRegistry creates an empty registry
+func NewNotifierRegistry() *NotifierRegistry {
+	return &NotifierRegistry{notifiers: make(map[string]Notifier)}
+}
+
+// NewDefaultNotifierRegistry creates a registry with all built-in notification method types registered
+func NewDefaultNotifierRegistry() *NotifierRegistry {
+	client := &http.Client{Timeout: notifierTimeout}
+
+	r := NewNotifierRegistry()
+	r.Register("email", &EmailNotifier{})
+	r.Register("slack", &SlackNotifier{client: client})
+	r.Register("teams", &TeamsNotifier{client: client})
+	r.Register("webhook", &WebhookNotifier{client: client})
+	r.Register("pagerduty", &PagerDutyNotifier{client: client})
+	r.Register("opsgenie", &OpsgenieNotifier{client: client})
+	r.Register("discord", &DiscordNotifier{client: client})
+	r.Register("telegram", &TelegramNotifier{client: client})
+	r.Register("mattermost", &MattermostNotifier{client: client})
+	r.Register("ntfy", &NtfyNotifier{client: client})
+	r.Register("gotify", &GotifyNotifier{client: client})
+	return r
+}
+
+// Register adds or replaces the notifier for a notification method type
+func (r *NotifierRegistry) Register(methodType string, notifier Notifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers[methodType] = notifier
+}
+
+// Get returns the notifier registered for a notification method type
+func (r *NotifierRegistry) Get(methodType string) (Notifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	notifier, ok := r.notifiers[methodType]
+	return notifier, ok
+}
+
+// Types returns the notification method types that have a registered notifier, sorted
+func (r *NotifierRegistry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	methodTypes := make([]string, 0, len(r.notifiers))
+	for t := range r.notifiers {
+		methodTypes = append(methodTypes, t)
+	}
+	sort.Strings(methodTypes)
+	return methodTypes
+}
+
+// Validate checks that a notification method has a registered type and a valid config
+func (r *NotifierRegistry) Validate(method *types.NotificationMethod) error {
+	notifier, ok := r.Get(method.Type)
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnsupportedType, method.Type)
+	}
+	if method.MaxAttempts < 0 || method.MaxAttempts > MaxDeliveryAttempts {
+		return fmt.Errorf("max_attempts must be between 1 and %d", MaxDeliveryAttempts)
+	}
+	if err := ValidateTemplates(method); err != nil {
+		return err
+	}
+	return notifier.Validate(json.RawMessage(method.Config))
+}
+
+// Send renders a notification with a notification method's templates and
+// delivers it through the method
+func (r *NotifierRegistry) Send(method *types.NotificationMethod, n *Notification) error {
+	notifier, ok := r.Get(method.Type)
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnsupportedType, method.Type)
+	}
+	rendered, err := RenderNotification(method, n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	return notifier.Send(ctx, rendered, json.RawMessage(method.Config))
+}
+
+// statusInfo returns consistent status information across all notification types
+func statusInfo(status string) (symbol string, color string, displayStatus string) {
+	switch strings.ToLower(status) {
+	case "up":
+		return "✅", "good", "UP"
+	case "down":
+		return "❌", "danger", "DOWN"
+	case "unauthorized", "401":
+		return "⚠️", "warning", "UNAUTHORIZED"
+	case "degraded":
+		return "🐢", "warning", "DEGRADED"
+	case "cert_warning":
+		return "🔒", "warning", "CERTIFICATE EXPIRING"
+	case "cert_critical":
+		return "🔒", "danger", "CERTIFICATE EXPIRING SOON"
+	case "cert_expired":
+		return "🔓", "danger", "CERTIFICATE EXPIRED"
+	default:
+		return "⏳", "default", strings.ToUpper(status)
+	}
+}
+
+// decodeConfig decodes a notification method's config into v
+func decodeConfig(config json.RawMessage, v interface{}) error {
+	if len(config) == 0 {
+		return errConfigRequired
+	}
+	if err := json.Unmarshal(config, v); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidConfig, err)
+	}
+	return nil
+}
+
+// requireFields returns an error naming the first empty field. fields
+// alternates field names and values.
+func requireFields(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if strings.TrimSpace(fields[i+1]) == "" {
+			return fmt.Errorf("%s is required", fields[i])
+		}
+	}
+	return nil
+}
+
+// validateHTTPURL checks that a config field holds an http or https URL
+func validateHTTPURL(field, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL", field)
+	}
+	return nil
+}
+
+// postJSON sends payload as JSON and checks that the response is successful
+func postJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if _, ok := headers["Content-Type"]; !ok {
+		headers["Content-Type"] = "application/json"
+	}
+	return sendRequest(ctx, client, method, url, headers, body)
+}
+
+// sendRequest sends a notification request. Responses outside 2xx are
+// returned as errors, with the start of the response body.
+func sendRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(detail))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DiscordNotifier posts notifications to a Discord webhook as an embed
+type DiscordNotifier struct {
+	client *http.Client
+}
+
+type discordConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Username   string `json:"username,omitempty"` // Overrides the webhook's name
+}
+
+// Validate implements Notifier
+func (d *DiscordNotifier) Validate(config json.RawMessage) error {
+	var cfg discordConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	return requireFields("webhook_url", cfg.WebhookURL)
+}
+
+// Send implements Notifier
+func (d *DiscordNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg discordConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       truncate(n.Subject, 256),
+				"description": truncate(n.Body, 4096),
+				"color":       statusColor(n.Status),
+				"timestamp":   n.Time.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+	if cfg.Username != "" {
+		payload["username"] = cfg.Username
+	}
+
+	if err := postJSON(ctx, d.client, http.MethodPost, cfg.WebhookURL, nil, payload); err != nil {
+		return fmt.Errorf("Discord API %w", err)
+	}
+	return nil
+}
+
+// TelegramNotifier sends notifications as messages of a Telegram bot
+type TelegramNotifier struct {
+	client *http.Client
+}
+
+// telegramAPIURL is the Bot API endpoint
+const telegramAPIURL = "https://api.telegram.org"
+
+type telegramConfig struct {
+	BotToken string `json:"bot_token"`
+	ChatID   string `json:"chat_id"`           // Chat, group or @channel to post to
+	APIURL   string `json:"api_url,omitempty"` // Bot API endpoint override
+}
+
+// Validate implements Notifier
+func (t *TelegramNotifier) Validate(config json.RawMessage) error {
+	var cfg telegramConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if cfg.APIURL != "" {
+		if err := validateHTTPURL("api_url", cfg.APIURL); err != nil {
+			return err
+		}
+	}
+	return requireFields("bot_token", cfg.BotToken, "chat_id", cfg.ChatID)
+}
+
+// Send implements Notifier
+func (t *TelegramNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg telegramConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	base := cfg.APIURL
+	if base == "" {
+		base = telegramAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(base, "/"), cfg.BotToken)
+
+	payload := map[string]interface{}{
+		"chat_id":                  cfg.ChatID,
+		"text":                     truncate(n.Subject+"\n\n"+n.Body, 4096),
+		"disable_web_page_preview": true,
+	}
+	if err := postJSON(ctx, t.client, http.MethodPost, endpoint, nil, payload); err != nil {
+		// The request URL holds the bot token, so errors never include it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = strings.ReplaceAll(urlErr.URL, cfg.BotToken, "***")
+		}
+		return fmt.Errorf("Telegram API %w", err)
+	}
+	return nil
+}
+
+// MattermostNotifier posts notifications to a Mattermost incoming webhook
+type MattermostNotifier struct {
+	client *http.Client
+}
+
+type mattermostConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel,omitempty"`  // Overrides the webhook's channel
+	Username   string `json:"username,omitempty"` // Overrides the webhook's name
+}
+
+// Validate implements Notifier
+func (m *MattermostNotifier) Validate(config json.RawMessage) error {
+	var cfg mattermostConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	return requireFields("webhook_url", cfg.WebhookURL)
+}
+
+// Send implements Notifier
+func (m *MattermostNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg mattermostConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"attachments": []map[string]interface{}{
+			{
+				"fallback": n.Subject,
+				"color":    fmt.Sprintf("#%06x", statusColor(n.Status)),
+				"title":    n.Subject,
+				"text":     n.Body,
+			},
+		},
+	}
+	if cfg.Channel != "" {
+		payload["channel"] = cfg.Channel
+	}
+	if cfg.Username != "" {
+		payload["username"] = cfg.Username
+	}
+
+	if err := postJSON(ctx, m.client, http.MethodPost, cfg.WebhookURL, nil, payload); err != nil {
+		return fmt.Errorf("Mattermost API %w", err)
+	}
+	return nil
+}
+
+// statusColor returns the RGB color of a status in chat messages
+func statusColor(status string) int {
+	_, color, _ := statusInfo(status)
+	switch color {
+	case "good":
+		return 0x2ecc71
+	case "danger":
+		return 0xe74c3c
+	case "warning":
+		return 0xf1c40f
+	default:
+		return 0x95a5a6
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/types"
+)
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents through the
+// Events API v2. Events are deduplicated per monitor, so a recovery resolves
+// the incident its outage triggered.
+type PagerDutyNotifier struct {
+	client *http.Client
+}
+
+// pagerDutyEventsURL is the Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type pagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"`        // Integration key of the service
+	Severity   string `json:"severity,omitempty"` // critical, error, warning or info, the monitor's severity if empty
+	URL        string `json:"url,omitempty"`      // Events API endpoint override
+}
+
+// Validate implements Notifier
+func (p *PagerDutyNotifier) Validate(config json.RawMessage) error {
+	var cfg pagerDutyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	switch cfg.Severity {
+	case "", "critical", "error", "warning", "info":
+	default:
+		return fmt.Errorf("severity must be critical, error, warning or info")
+	}
+	if cfg.URL != "" {
+		if err := validateHTTPURL("url", cfg.URL); err != nil {
+			return err
+		}
+	}
+	return requireFields("routing_key", cfg.RoutingKey)
+}
+
+// Send implements Notifier
+func (p *PagerDutyNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg pagerDutyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	endpoint := cfg.URL
+	if endpoint == "" {
+		endpoint = pagerDutyEventsURL
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  cfg.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    alertKey(n),
+	}
+	if n.IsRecovery() {
+		event["event_action"] = "resolve"
+	} else {
+		// The method's severity, or else the monitor's, which uses the same levels
+		severity := cfg.Severity
+		if severity == "" {
+			severity = n.Monitor.GetSeverity()
+		}
+		if n.Status == "cert_warning" || n.Status == "degraded" {
+			severity = "warning"
+		}
+		event["payload"] = map[string]interface{}{
+			"summary":        truncate(n.Subject, 1024),
+			"source":         monitorSource(n.Monitor),
+			"severity":       severity,
+			"timestamp":      n.Time.UTC().Format(time.RFC3339),
+			"component":      n.Monitor.Name,
+			"class":          n.Status,
+			"custom_details": alertDetails(n),
+		}
+		event["links"] = []map[string]string{{"href": monitorDashboardURL(n.Monitor), "text": "Heimdall dashboard"}}
+	}
+
+	if err := postJSON(ctx, p.client, http.MethodPost, endpoint, nil, event); err != nil {
+		return fmt.Errorf("PagerDuty API %w", err)
+	}
+	return nil
+}
+
+// OpsgenieNotifier creates Opsgenie alerts, and closes them when the monitor recovers
+type OpsgenieNotifier struct {
+	client *http.Client
+}
+
+// Opsgenie API endpoints per region
+const (
+	opsgenieURL   = "https://api.opsgenie.com"
+	opsgenieEUURL = "https://api.eu.opsgenie.com"
+)
+
+type opsgenieConfig struct {
+	APIKey   string `json:"api_key"`            // API key of an API integration
+	Region   string `json:"region,omitempty"`   // us (default) or eu
+	Priority string `json:"priority,omitempty"` // P1 to P5, Opsgenie's default if empty
+	URL      string `json:"url,omitempty"`      // API endpoint override
+}
+
+// Validate implements Notifier
+func (o *OpsgenieNotifier) Validate(config json.RawMessage) error {
+	var cfg opsgenieConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	switch strings.ToLower(cfg.Region) {
+	case "", "us", "eu":
+	default:
+		return fmt.Errorf("region must be us or eu")
+	}
+	switch cfg.Priority {
+	case "", "P1", "P2", "P3", "P4", "P5":
+	default:
+		return fmt.Errorf("priority must be P1 to P5")
+	}
+	if cfg.URL != "" {
+		if err := validateHTTPURL("url", cfg.URL); err != nil {
+			return err
+		}
+	}
+	return requireFields("api_key", cfg.APIKey)
+}
+
+// Send implements Notifier
+func (o *OpsgenieNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg opsgenieConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	base := cfg.URL
+	switch {
+	case base != "":
+	case strings.EqualFold(cfg.Region, "eu"):
+		base = opsgenieEUURL
+	default:
+		base = opsgenieURL
+	}
+	base = strings.TrimSuffix(base, "/")
+	headers := map[string]string{"Authorization": "GenieKey " + cfg.APIKey}
+	alias := alertKey(n)
+
+	var err error
+	if n.IsRecovery() {
+		endpoint := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", base, url.PathEscape(alias))
+		err = postJSON(ctx, o.client, http.MethodPost, endpoint, headers, map[string]interface{}{
+			"source": "Heimdall",
+			"note":   n.Message,
+		})
+	} else {
+		alert := map[string]interface{}{
+			"message":     truncate(n.Subject, 130),
+			"alias":       alias,
+			"description": truncate(n.Body, 15000),
+			"source":      monitorSource(n.Monitor),
+			"entity":      n.Monitor.Name,
+			"details":     alertDetails(n),
+		}
+		if tags := types.SplitList(n.Monitor.Tags); len(tags) > 0 {
+			alert["tags"] = tags
+		}
+		if cfg.Priority != "" {
+			alert["priority"] = cfg.Priority
+		}
+		err = postJSON(ctx, o.client, http.MethodPost, base+"/v2/alerts", headers, alert)
+	}
+	if err != nil {
+		return fmt.Errorf("Opsgenie API %w", err)
+	}
+	return nil
+}
+
+// alertKey identifies the alert of a monitor in incident management tools, so
+// that repeated notifications update one alert and a recovery closes it.
+// Certificate alerts are kept apart from outages, as a recovery doesn't
+// renew a certificate.
+func alertKey(n *Notification) string {
+	if n.IsCertificateAlert() {
+		return "heimdall-" + n.Monitor.ID + "-certificate"
+	}
+	return "heimdall-" + n.Monitor.ID
+}
+
+// monitorSource returns what a monitor checks, as shown in alerts
+func monitorSource(monitor *types.Monitor) string {
+	switch {
+	case monitor.URL != "":
+		return monitor.URL
+	case monitor.Hostname != "":
+		return monitor.Hostname
+	case monitor.DBHost != "":
+		return monitor.DBHost
+	default:
+		return monitor.Name
+	}
+}
+
+// alertDetails returns the details of a notification attached to alerts, as
+// strings since Opsgenie only accepts string details
+func alertDetails(n *Notification) map[string]string {
+	return map[string]string{
+		"monitor_id":    n.Monitor.ID,
+		"monitor_type":  n.Monitor.Type,
+		"status":        n.Status,
+		"message":       n.Message,
+		"response_code": strconv.Itoa(n.Monitor.ResponseCode),
+		"response_time": fmt.Sprintf("%dms", n.Monitor.ResponseTime),
+	}
+}
+
+// truncate shortens s to at most max runes
+func truncate(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
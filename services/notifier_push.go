@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// NtfyNotifier publishes notifications to an ntfy topic
+type NtfyNotifier struct {
+	client *http.Client
+}
+
+// ntfyServerURL is the public ntfy server
+const ntfyServerURL = "https://ntfy.sh"
+
+type ntfyConfig struct {
+	ServerURL string `json:"server_url,omitempty"` // https://ntfy.sh if empty
+	Topic     string `json:"topic"`
+	Token     string `json:"token,omitempty"`    // Access token for protected topics
+	Priority  int    `json:"priority,omitempty"` // 1 to 5, by status if 0
+}
+
+// Validate implements Notifier
+func (nt *NtfyNotifier) Validate(config json.RawMessage) error {
+	var cfg ntfyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if cfg.ServerURL != "" {
+		if err := validateHTTPURL("server_url", cfg.ServerURL); err != nil {
+			return err
+		}
+	}
+	if cfg.Priority < 0 || cfg.Priority > 5 {
+		return fmt.Errorf("priority must be between 1 and 5")
+	}
+	return requireFields("topic", cfg.Topic)
+}
+
+// Send implements Notifier
+func (nt *NtfyNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg ntfyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	server := cfg.ServerURL
+	if server == "" {
+		server = ntfyServerURL
+	}
+
+	priority, tag := cfg.Priority, "warning"
+	if n.IsRecovery() {
+		tag = "white_check_mark"
+		if priority == 0 {
+			priority = 3
+		}
+	} else if priority == 0 {
+		priority = 5
+		if n.Status == "cert_warning" {
+			priority = 4
+		}
+	}
+
+	// Publishing as JSON to the server root lets the title hold any character
+	payload := map[string]interface{}{
+		"topic":    cfg.Topic,
+		"title":    n.Subject,
+		"message":  n.Body,
+		"priority": priority,
+		"tags":     []string{tag},
+	}
+	var headers map[string]string
+	if cfg.Token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + cfg.Token}
+	}
+
+	if err := postJSON(ctx, nt.client, http.MethodPost, strings.TrimSuffix(server, "/"), headers, payload); err != nil {
+		return fmt.Errorf("ntfy %w", err)
+	}
+	return nil
+}
+
+// GotifyNotifier sends notifications as messages of a Gotify application
+type GotifyNotifier struct {
+	client *http.Client
+}
+
+type gotifyConfig struct {
+	ServerURL string `json:"server_url"`
+	AppToken  string `json:"app_token"`
+	Priority  int    `json:"priority,omitempty"` // 0 to 10, by status if 0
+}
+
+// Validate implements Notifier
+func (g *GotifyNotifier) Validate(config json.RawMessage) error {
+	var cfg gotifyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if err := validateHTTPURL("server_url", cfg.ServerURL); err != nil {
+		return err
+	}
+	if cfg.Priority < 0 || cfg.Priority > 10 {
+		return fmt.Errorf("priority must be between 0 and 10")
+	}
+	return requireFields("app_token", cfg.AppToken)
+}
+
+// Send implements Notifier
+func (g *GotifyNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg gotifyConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	priority := cfg.Priority
+	if priority == 0 {
+		priority = 8
+		if n.IsRecovery() {
+			priority = 4
+		}
+	}
+
+	payload := map[string]interface{}{
+		"title":    n.Subject,
+		"message":  n.Body,
+		"priority": priority,
+	}
+	headers := map[string]string{"X-Gotify-Key": cfg.AppToken}
+	endpoint := strings.TrimSuffix(cfg.ServerURL, "/") + "/message"
+
+	if err := postJSON(ctx, g.client, http.MethodPost, endpoint, headers, payload); err != nil {
+		return fmt.Errorf("Gotify %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/secrets"
+	"uptime-monitor/types"
+)
+
+// recordedRequest is a request received by a notification endpoint
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// startRecorder starts an endpoint that answers with status and records the
+// requests it receives
+func startRecorder(t *testing.T, status int) (*httptest.Server, <-chan recordedRequest) {
+	t.Helper()
+	requests := make(chan recordedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- recordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header, Body: body}
+		w.WriteHeader(status)
+		w.Write([]byte("rejected by test"))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestNotifierPayloads(t *testing.T) {
+	t.Setenv("HEIMDALL_BASE_URL", "https://heimdall.example.com")
+	monitorID := SampleNotification("down").Monitor.ID
+
+	tests := []struct {
+		name       string
+		methodType string
+		config     string // %URL% is replaced by the endpoint's URL
+		status     string
+		wantMethod string
+		wantPath   string
+		wantQuery  string
+		wantHeader map[string]string
+		// JSON paths of the body and a substring of their values
+		wantBody map[string]string
+		// Paths that must not be in the body
+		wantAbsent []string
+	}{
+		{
+			name:       "slack",
+			methodType: "slack",
+			config:     `{"webhook_url":"%URL%/services/T0/B0/x","channel":"#ops"}`,
+			status:     "down",
+			wantPath:   "/services/T0/B0/x",
+			wantBody: map[string]string{
+				"$.channel":                    "#ops",
+				"$.text":                       "Monitor Alert: Example API is DOWN",
+				"$.blocks[0].type":             "header",
+				"$.blocks[0].text.text":        "Example API is DOWN",
+				"$.blocks[1].text.type":        "mrkdwn",
+				"$.blocks[1].text.text":        "*Status:*",
+				"$.blocks[2].type":             "context",
+				"$.blocks[2].elements[0].text": "Time: ",
+			},
+		},
+		{
+			name:       "teams",
+			methodType: "teams",
+			config:     `{"webhook_url":"%URL%/webhook"}`,
+			status:     "down",
+			wantPath:   "/webhook",
+			wantBody: map[string]string{
+				"$.type":                                 "message",
+				"$.attachments[0].contentType":           "application/vnd.microsoft.card.adaptive",
+				"$.attachments[0].content.type":          "AdaptiveCard",
+				"$.attachments[0].content.body[0].text":  "Example API is DOWN",
+				"$.attachments[0].content.body[0].color": "danger",
+				"$.attachments[0].content.body[1].text":  "Unexpected status code 503",
+			},
+		},
+		{
+			name:       "discord",
+			methodType: "discord",
+			config:     `{"webhook_url":"%URL%/api/webhooks/1/x","username":"Heimdall"}`,
+			status:     "down",
+			wantPath:   "/api/webhooks/1/x",
+			wantBody: map[string]string{
+				"$.username":              "Heimdall",
+				"$.embeds[0].title":       "Example API is DOWN",
+				"$.embeds[0].description": "Unexpected status code 503",
+				"$.embeds[0].color":       strconv.Itoa(0xe74c3c),
+				"$.embeds[0].timestamp":   "T",
+			},
+		},
+		{
+			name:       "telegram",
+			methodType: "telegram",
+			config:     `{"api_url":"%URL%/","bot_token":"123:abc","chat_id":"-100042"}`,
+			status:     "down",
+			wantPath:   "/bot123:abc/sendMessage",
+			wantBody: map[string]string{
+				"$.chat_id":                  "-100042",
+				"$.text":                     "Example API is DOWN",
+				"$.disable_web_page_preview": "true",
+			},
+		},
+		{
+			name:       "mattermost",
+			methodType: "mattermost",
+			config:     `{"webhook_url":"%URL%/hooks/x","channel":"town-square","username":"Heimdall"}`,
+			status:     "up",
+			wantPath:   "/hooks/x",
+			wantBody: map[string]string{
+				"$.channel":                 "town-square",
+				"$.username":                "Heimdall",
+				"$.attachments[0].color":    "#2ecc71",
+				"$.attachments[0].title":    "Example API is UP",
+				"$.attachments[0].fallback": "Example API is UP",
+			},
+		},
+		{
+			name:       "ntfy outage",
+			methodType: "ntfy",
+			config:     `{"server_url":"%URL%/","topic":"alerts","token":"tk_1"}`,
+			status:     "down",
+			wantPath:   "/",
+			wantHeader: map[string]string{"Authorization": "Bearer tk_1"},
+			wantBody: map[string]string{
+				"$.topic":    "alerts",
+				"$.title":    "Example API is DOWN",
+				"$.priority": "5",
+				"$.tags[0]":  "warning",
+			},
+		},
+		{
+			name:       "ntfy recovery",
+			methodType: "ntfy",
+			config:     `{"server_url":"%URL%","topic":"alerts"}`,
+			status:     "up",
+			wantPath:   "/",
+			wantBody: map[string]string{
+				"$.priority": "3",
+				"$.tags[0]":  "white_check_mark",
+			},
+		},
+		{
+			name:       "gotify",
+			methodType: "gotify",
+			config:     `{"server_url":"%URL%/","app_token":"A1"}`,
+			status:     "down",
+			wantPath:   "/message",
+			wantHeader: map[string]string{"X-Gotify-Key": "A1"},
+			wantBody: map[string]string{
+				"$.title":    "Example API is DOWN",
+				"$.message":  "Unexpected status code 503",
+				"$.priority": "8",
+			},
+		},
+		{
+			name:       "gotify recovery with priority",
+			methodType: "gotify",
+			config:     `{"server_url":"%URL%","app_token":"A1","priority":6}`,
+			status:     "up",
+			wantPath:   "/message",
+			wantBody:   map[string]string{"$.priority": "6"},
+		},
+		{
+			name:       "pagerduty trigger",
+			methodType: "pagerduty",
+			config:     `{"routing_key":"R0","url":"%URL%/v2/enqueue"}`,
+			status:     "down",
+			wantPath:   "/v2/enqueue",
+			wantBody: map[string]string{
+				"$.routing_key":                          "R0",
+				"$.event_action":                         "trigger",
+				"$.dedup_key":                            "heimdall-" + monitorID,
+				"$.payload.summary":                      "Example API is DOWN",
+				"$.payload.source":                       "https://api.example.com/health",
+				"$.payload.severity":                     types.SeverityCritical,
+				"$.payload.component":                    "Example API",
+				"$.payload.class":                        "down",
+				"$.payload.custom_details.response_code": "503",
+				"$.links[0].href":                        "https://heimdall.example.com/?monitor=" + monitorID,
+			},
+		},
+		{
+			name:       "pagerduty certificate warning",
+			methodType: "pagerduty",
+			config:     `{"routing_key":"R0","severity":"critical","url":"%URL%"}`,
+			status:     "cert_warning",
+			wantPath:   "/",
+			wantBody: map[string]string{
+				"$.dedup_key":        "heimdall-" + monitorID + "-certificate",
+				"$.payload.severity": "warning",
+			},
+		},
+		{
+			name:       "pagerduty resolve",
+			methodType: "pagerduty",
+			config:     `{"routing_key":"R0","url":"%URL%"}`,
+			status:     "up",
+			wantPath:   "/",
+			wantBody: map[string]string{
+				"$.event_action": "resolve",
+				"$.dedup_key":    "heimdall-" + monitorID,
+			},
+			wantAbsent: []string{"$.payload", "$.links"},
+		},
+		{
+			name:       "opsgenie alert",
+			methodType: "opsgenie",
+			config:     `{"api_key":"G1","priority":"P2","url":"%URL%/"}`,
+			status:     "down",
+			wantPath:   "/v2/alerts",
+			wantHeader: map[string]string{"Authorization": "GenieKey G1"},
+			wantBody: map[string]string{
+				"$.message":               "Example API is DOWN",
+				"$.alias":                 "heimdall-" + monitorID,
+				"$.description":           "Unexpected status code 503",
+				"$.entity":                "Example API",
+				"$.priority":              "P2",
+				"$.tags[1]":               "api",
+				"$.details.response_time": "1245ms",
+			},
+		},
+		{
+			name:       "opsgenie close",
+			methodType: "opsgenie",
+			config:     `{"api_key":"G1","url":"%URL%"}`,
+			status:     "up",
+			wantPath:   "/v2/alerts/heimdall-" + monitorID + "/close",
+			wantQuery:  "identifierType=alias",
+			wantHeader: map[string]string{"Authorization": "GenieKey G1"},
+			wantBody: map[string]string{
+				"$.source": "Heimdall",
+				"$.note":   "Monitor is up",
+			},
+		},
+		{
+			name:       "webhook",
+			methodType: "webhook",
+			config:     `{"url":"%URL%/hook","method":"put","headers":{"Authorization":"Bearer w1"}}`,
+			status:     "down",
+			wantMethod: http.MethodPut,
+			wantPath:   "/hook",
+			wantHeader: map[string]string{"Authorization": "Bearer w1", "Content-Type": "application/json"},
+			wantBody: map[string]string{
+				"$.event":           "monitor.status",
+				"$.monitor_id":      monitorID,
+				"$.monitor_name":    "Example API",
+				"$.status":          "down",
+				"$.previous_status": "up",
+				"$.title":           "Example API is DOWN",
+				"$.response_code":   "503",
+			},
+		},
+		{
+			name:       "webhook body template",
+			methodType: "webhook",
+			config:     `{"url":"%URL%","content_type":"application/vnd.custom+json","body":"{\"summary\": {{json .Subject}}, \"status\": {{json .Status}}}"}`,
+			status:     "up",
+			wantPath:   "/",
+			wantHeader: map[string]string{"Content-Type": "application/vnd.custom+json"},
+			wantBody: map[string]string{
+				"$.summary": "Example API is UP",
+				"$.status":  "up",
+			},
+			wantAbsent: []string{"$.event"},
+		},
+	}
+
+	registry := NewDefaultNotifierRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := startRecorder(t, http.StatusOK)
+			method := &types.NotificationMethod{Type: tt.methodType, Config: secrets.JSON(strings.ReplaceAll(tt.config, "%URL%", server.URL))}
+			if err := registry.Validate(method); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if err := registry.Send(method, SampleNotification(tt.status)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			req := <-requests
+
+			wantMethod := tt.wantMethod
+			if wantMethod == "" {
+				wantMethod = http.MethodPost
+			}
+			if req.Method != wantMethod || req.Path != tt.wantPath || req.Query != tt.wantQuery {
+				t.Errorf("request = %s %s?%s, want %s %s?%s", req.Method, req.Path, req.Query, wantMethod, tt.wantPath, tt.wantQuery)
+			}
+			for name, want := range tt.wantHeader {
+				if got := req.Header.Get(name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+
+			var body interface{}
+			if err := json.Unmarshal(req.Body, &body); err != nil {
+				t.Fatalf("body is not JSON: %v\n%s", err, req.Body)
+			}
+			for path, want := range tt.wantBody {
+				value, found, err := lookupJSONPath(body, path)
+				if err != nil || !found {
+					t.Errorf("body has no %s: %s", path, req.Body)
+					continue
+				}
+				if got := jsonValueString(value); !strings.Contains(got, want) {
+					t.Errorf("body %s = %q, want it to contain %q", path, got, want)
+				}
+			}
+			for _, path := range tt.wantAbsent {
+				if _, found, _ := lookupJSONPath(body, path); found {
+					t.Errorf("body has %s: %s", path, req.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server, requests := startRecorder(t, http.StatusNoContent)
+	registry := NewDefaultNotifierRegistry()
+
+	for _, header := range []string{"", "X-Signature"} {
+		config, _ := json.Marshal(map[string]string{"url": server.URL, "secret": "s3cret", "signature_header": header})
+		if err := registry.Send(&types.NotificationMethod{Type: "webhook", Config: config}, SampleNotification("down")); err != nil {
+			t.Fatal(err)
+		}
+		req := <-requests
+		if header == "" {
+			header = DefaultSignatureHeader
+		}
+		if got, want := req.Header.Get(header), SignWebhookBody("s3cret", req.Body); got != want || !strings.HasPrefix(got, "sha256=") {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestNotifierErrors(t *testing.T) {
+	registry := NewDefaultNotifierRegistry()
+
+	tests := []struct {
+		name          string
+		status        int
+		wantRetryable bool
+	}{
+		{"server error", http.StatusBadGateway, true},
+		{"rate limited", http.StatusTooManyRequests, true},
+		{"rejected", http.StatusBadRequest, false},
+		{"unauthorized", http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := startRecorder(t, tt.status)
+			method := &types.NotificationMethod{Type: "slack", Config: secrets.JSON(`{"webhook_url":"` + server.URL + `"}`)}
+			err := registry.Send(method, SampleNotification("down"))
+			<-requests
+
+			var statusErr *HTTPStatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status || statusErr.Body != "rejected by test" {
+				t.Fatalf("Send() error = %v, want status %d with the response body", err, tt.status)
+			}
+			if IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, !tt.wantRetryable, tt.wantRetryable)
+			}
+		})
+	}
+
+	// Config errors fail the same way on every attempt
+	err := registry.Send(&types.NotificationMethod{Type: "slack", Config: secrets.JSON(`{"webhook_url":`)}, SampleNotification("down"))
+	if err == nil || IsRetryable(err) {
+		t.Errorf("Send() with an invalid config error = %v, want a permanent error", err)
+	}
+
+	// The Telegram request URL holds the bot token, which must not end up in logs
+	config := `{"api_url":"http://127.0.0.1:` + strconv.Itoa(closedPort(t)) + `","bot_token":"123:s3cret","chat_id":"1"}`
+	err = registry.Send(&types.NotificationMethod{Type: "telegram", Config: secrets.JSON(config)}, SampleNotification("down"))
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Send() error = %v, want an error without the bot token", err)
+	}
+}
+
+func TestNotifierValidate(t *testing.T) {
+	registry := NewDefaultNotifierRegistry()
+
+	tests := []struct {
+		methodType string
+		config     string
+		wantErr    string
+	}{
+		{"email", `{"smtp_host":"smtp.example.com","smtp_port":587,"smtp_email":"a@example.com","smtp_password":"p","recipient_email":"b@example.com"}`, ""},
+		{"email", `{"smtp_host":"smtp.example.com","smtp_port":0}`, "smtp_port"},
+		{"slack", ``, "config is required"},
+		{"slack", `{}`, "webhook_url is required"},
+		{"telegram", `{"bot_token":"1:a"}`, "chat_id is required"},
+		{"telegram", `{"bot_token":"1:a","chat_id":"1","api_url":"ftp://x"}`, "api_url must be an http or https URL"},
+		{"ntfy", `{"topic":"t","priority":6}`, "priority must be between 1 and 5"},
+		{"gotify", `{"server_url":"gotify.example.com","app_token":"a"}`, "server_url must be an http or https URL"},
+		{"pagerduty", `{"routing_key":"r","severity":"high"}`, "severity must be"},
+		{"opsgenie", `{"api_key":"k","region":"apac"}`, "region must be us or eu"},
+		{"opsgenie", `{"api_key":"k","priority":"P9"}`, "priority must be P1 to P5"},
+		{"webhook", `{"url":"https://example.com","method":"GET"}`, "method must be POST, PUT or PATCH"},
+		{"webhook", `{"url":"https://example.com","body":"{{.Subject"}`, "invalid body template"},
+		{"sms", `{}`, "unsupported notification type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.methodType+" "+tt.wantErr, func(t *testing.T) {
+			err := registry.Validate(&types.NotificationMethod{Type: tt.methodType, Config: secrets.JSON(tt.config)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	plain, err := buildEmail("from@example.com", "to@example.com", "❌ Example API is DOWN", "Status: DOWN", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: to@example.com\r\n", "From: from@example.com\r\n", "Subject: =?utf-8?q?", "Content-Type: text/plain; charset=UTF-8\r\n", "\r\n\r\nStatus: DOWN"} {
+		if !strings.Contains(string(plain), want) {
+			t.Errorf("plain text email does not contain %q:\n%s", want, plain)
+		}
+	}
+
+	html, err := buildEmail("from@example.com", "to@example.com", "Example API is DOWN", "Status: DOWN", "<p>Status: DOWN</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Content-Type: multipart/alternative; boundary=", "Content-Type: text/plain; charset=UTF-8", "Content-Type: text/html; charset=UTF-8", "<p>Status: DOWN</p>"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML email does not contain %q:\n%s", want, html)
+		}
+	}
+}
+
+// startSMTPServer starts an SMTP server on a local port that sends the
+// message it receives on the returned channel. A stalled server accepts
+// connections but never answers.
+func startSMTPServer(t *testing.T, stalled bool) (int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if stalled {
+				t.Cleanup(func() { conn.Close() })
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				text := textproto.NewConn(conn)
+				text.PrintfLine("220 localhost ESMTP")
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+					case "EHLO":
+						text.PrintfLine("250-localhost")
+						text.PrintfLine("250 AUTH PLAIN")
+					case "AUTH":
+						text.PrintfLine("235 Authenticated")
+					case "DATA":
+						text.PrintfLine("354 Go ahead")
+						message, _ := text.ReadDotBytes()
+						messages <- string(message)
+						text.PrintfLine("250 Queued")
+					case "QUIT":
+						text.PrintfLine("221 Bye")
+						return
+					default:
+						text.PrintfLine("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, messages
+}
+
+func TestEmailNotifierSend(t *testing.T) {
+	n := &Notification{Subject: "Example API is DOWN", Body: "Status: DOWN"}
+	config := func(port int) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"smtp_host":"127.0.0.1","smtp_port":%d,"smtp_email":"from@example.com","smtp_password":"hunter2","recipient_email":"to@example.com"}`, port))
+	}
+
+	port, messages := startSMTPServer(t, false)
+	if err := (&EmailNotifier{}).Send(context.Background(), n, config(port)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if message := <-messages; !strings.Contains(message, "Subject: Example API is DOWN") || !strings.Contains(message, "Status: DOWN") {
+		t.Errorf("server received %q", message)
+	}
+
+	// A server that never answers fails the delivery when its context ends
+	port, _ = startSMTPServer(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := (&EmailNotifier{}).Send(ctx, n, config(port)); err == nil {
+		t.Fatal("Send() to a stalled server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send() to a stalled server returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultSignatureHeader carries the HMAC-SHA256 signature of webhook bodies
+const DefaultSignatureHeader = "X-Heimdall-Signature-256"
+
+// WebhookNotifier sends notifications to any HTTP endpoint. The body is a JSON
+// document describing the notification, or rendered from a text/template.
+// With a secret, the body is signed like GitHub webhooks: the signature header
+// holds "sha256=" and the hex HMAC-SHA256 of the body.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+type webhookConfig struct {
+	URL             string            `json:"url"`
+	Method          string            `json:"method,omitempty"`       // POST if empty
+	Headers         map[string]string `json:"headers,omitempty"`      // Authorization is encrypted like other secrets
+	ContentType     string            `json:"content_type,omitempty"` // application/json if empty
+	Body            string            `json:"body,omitempty"`         // text/template, e.g. {"text": {{json .Subject}}}
+	Secret          string            `json:"secret,omitempty"`       // HMAC-SHA256 signing key
+	SignatureHeader string            `json:"signature_header,omitempty"`
+}
+
+// Validate implements Notifier
+func (w *WebhookNotifier) Validate(config json.RawMessage) error {
+	var cfg webhookConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if err := validateHTTPURL("url", cfg.URL); err != nil {
+		return err
+	}
+	switch strings.ToUpper(cfg.Method) {
+	case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("method must be POST, PUT or PATCH")
+	}
+	if cfg.Body != "" {
+		if _, err := template.New("body").Funcs(notificationTemplateFuncs).Parse(cfg.Body); err != nil {
+			return fmt.Errorf("invalid body template: %v", err)
+		}
+	}
+	return nil
+}
+
+// Send implements Notifier
+func (w *WebhookNotifier) Send(ctx context.Context, n *Notification, config json.RawMessage) error {
+	var cfg webhookConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	body, err := webhookBody(cfg.Body, n)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if cfg.ContentType != "" {
+		headers["Content-Type"] = cfg.ContentType
+	}
+	for name, value := range cfg.Headers {
+		headers[name] = value
+	}
+	if cfg.Secret != "" {
+		signatureHeader := cfg.SignatureHeader
+		if signatureHeader == "" {
+			signatureHeader = DefaultSignatureHeader
+		}
+		headers[signatureHeader] = SignWebhookBody(cfg.Secret, body)
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	if err := sendRequest(ctx, w.client, method, cfg.URL, headers, body); err != nil {
+		return fmt.Errorf("webhook %w", err)
+	}
+	return nil
+}
+
+// SignWebhookBody returns the signature header value of a webhook body
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBody renders the body template, or the default JSON document when
+// there is none
+func webhookBody(bodyTemplate string, n *Notification) ([]byte, error) {
+	if bodyTemplate == "" {
+		monitor := n.Monitor
+		return json.Marshal(map[string]interface{}{
+			"event":           "monitor.status",
+			"monitor_id":      monitor.ID,
+			"monitor_name":    monitor.Name,
+			"monitor_type":    monitor.Type,
+			"url":             monitor.URL,
+			"status":          n.Status,
+			"previous_status": n.PreviousStatus,
+			"message":         n.Message,
+			"title":           n.Subject,
+			"text":            n.Body,
+			"response_code":   monitor.ResponseCode,
+			"response_time":   monitor.ResponseTime,
+			"timestamp":       n.Time.UTC().Format(time.RFC3339),
+		})
+	}
+
+	tmpl, err := template.New("body").Funcs(notificationTemplateFuncs).Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("failed to render body template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
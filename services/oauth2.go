@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"uptime-monitor/secrets"
+)
+
+// OAuth2 grant types supported to obtain access tokens
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// tokenRefreshMargin is how long before its expiry an access token is refreshed
+const tokenRefreshMargin = time.Minute
+
+// ValidateOAuth2Credential checks the settings of an oauth2 credential. When
+// creating, the token URL, client ID and the secrets of the grant are required,
+// and defaults are filled in: the refresh_token grant when a refresh token is
+// given, client_credentials otherwise, and the Authorization header. Updates
+// only check the settings they change.
+func ValidateOAuth2Credential(cred *Credential, creating bool) error {
+	if creating {
+		if cred.GrantType == "" {
+			cred.GrantType = GrantClientCredentials
+			if cred.RefreshToken != "" {
+				cred.GrantType = GrantRefreshToken
+			}
+		}
+		if cred.HeaderName == "" {
+			cred.HeaderName = "Authorization"
+		}
+		if cred.ClientID == "" {
+			return errors.New("client_id is required")
+		}
+	}
+
+	if creating || cred.TokenURL != "" {
+		tokenURL, err := url.Parse(cred.TokenURL)
+		if err != nil || (tokenURL.Scheme != "http" && tokenURL.Scheme != "https") || tokenURL.Host == "" {
+			return errors.New("token_url must be an http or https URL")
+		}
+	}
+	switch cred.GrantType {
+	case "":
+	case GrantClientCredentials:
+		if creating && cred.ClientSecret == "" {
+			return errors.New("client_secret is required for the client_credentials grant")
+		}
+	case GrantRefreshToken:
+		if creating && cred.RefreshToken == "" {
+			return errors.New("refresh_token is required for the refresh_token grant")
+		}
+	default:
+		return fmt.Errorf("unsupported grant_type %q", cred.GrantType)
+	}
+	return nil
+}
+
+// ResolveCredential retrieves a credential to apply to a check. The access
+// token of an oauth2 credential is refreshed first when it is missing or about
+// to expire, within the check's ctx.
+func (s *CredentialsService) ResolveCredential(ctx context.Context, credentialID string) (*Credential, error) {
+	cred, err := s.GetCredential(credentialID)
+	if err != nil || cred.Type != "oauth2" || !needsRefresh(cred) {
+		return cred, err
+	}
+
+	lock, _ := s.refreshLocks.LoadOrStore(credentialID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Another check may have refreshed the token while we waited for the lock
+	if cred, err = s.GetCredential(credentialID); err != nil || !needsRefresh(cred) {
+		return cred, err
+	}
+
+	if err := s.RefreshOAuth2Token(ctx, cred); err != nil {
+		// The current token can still be used until it actually expires
+		if cred.Token != "" && time.Now().Before(cred.TokenExpiry) {
+			log.Printf("⚠️ Failed to refresh OAuth2 token of credential %s, using the current one: %v", cred.ID, err)
+			return cred, nil
+		}
+		return nil, err
+	}
+	return cred, nil
+}
+
+// needsRefresh reports whether an oauth2 credential has no access token, or one
+// that expires within tokenRefreshMargin. Tokens without an expiry are kept.
+func needsRefresh(cred *Credential) bool {
+	if cred.Token == "" {
+		return true
+	}
+	return !cred.TokenExpiry.IsZero() && time.Now().Add(tokenRefreshMargin).After(cred.TokenExpiry)
+}
+
+// RefreshOAuth2Token requests a new access token from the credential's token
+// endpoint and stores it. Failures are stored in the credential's
+// RefreshError, and cleared by the next successful refresh.
+func (s *CredentialsService) RefreshOAuth2Token(ctx context.Context, cred *Credential) error {
+	token, err := s.requestToken(ctx, cred)
+	if err != nil {
+		err = fmt.Errorf("failed to refresh OAuth2 token: %w", err)
+		if dbErr := s.db.Model(&Credential{}).Where("id = ?", cred.ID).Update("refresh_error", err.Error()).Error; dbErr != nil {
+			log.Printf("❌ Failed to store the refresh error of credential %s: %v", cred.ID, dbErr)
+		}
+		cred.RefreshError = err.Error()
+		return err
+	}
+
+	cred.Token = secrets.String(token.AccessToken)
+	cred.TokenExpiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		cred.TokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" {
+		cred.RefreshToken = secrets.String(token.RefreshToken)
+	}
+	cred.RefreshError = ""
+	cred.UpdatedAt = time.Now()
+
+	updates := map[string]interface{}{
+		"token":         cred.Token,
+		"token_expiry":  cred.TokenExpiry,
+		"refresh_token": cred.RefreshToken,
+		"refresh_error": "",
+		"updated_at":    cred.UpdatedAt,
+	}
+	if err := s.db.Model(&Credential{}).Where("id = ?", cred.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to store OAuth2 token: %w", err)
+	}
+	log.Printf("🔑 Refreshed OAuth2 token of credential %s (%s)", cred.ID, cred.Name)
+	return nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts the credential's grant to its token endpoint. The client
+// credentials are sent in the form body, which more providers accept than
+// HTTP basic authentication.
+func (s *CredentialsService) requestToken(ctx context.Context, cred *Credential) (*tokenResponse, error) {
+	if cred.TokenURL == "" {
+		return nil, errors.New("no token URL configured")
+	}
+
+	form := url.Values{
+		"grant_type": {cred.GrantType},
+		"client_id":  {cred.ClientID},
+	}
+	if cred.ClientSecret != "" {
+		form.Set("client_secret", string(cred.ClientSecret))
+	}
+	switch cred.GrantType {
+	case GrantClientCredentials:
+	case GrantRefreshToken:
+		if cred.RefreshToken == "" {
+			return nil, errors.New("no refresh token stored")
+		}
+		form.Set("refresh_token", string(cred.RefreshToken))
+	default:
+		return nil, fmt.Errorf("unsupported grant type %q", cred.GrantType)
+	}
+	if cred.Scopes != "" {
+		form.Set("scope", cred.Scopes)
+	}
+	if cred.Audience != "" {
+		form.Set("audience", cred.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cred.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var token tokenResponse
+	jsonErr := json.Unmarshal(body, &token)
+	switch {
+	case token.Error != "" && token.ErrorDescription != "":
+		return nil, fmt.Errorf("token endpoint returned %s: %s", token.Error, token.ErrorDescription)
+	case token.Error != "":
+		return nil, fmt.Errorf("token endpoint returned %s", token.Error)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("token endpoint returned status code %d", resp.StatusCode)
+	case jsonErr != nil:
+		return nil, fmt.Errorf("failed to parse token response: %w", jsonErr)
+	case token.AccessToken == "":
+		return nil, errors.New("token response has no access_token")
+	}
+	return &token, nil
+}
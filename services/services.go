@@ -4,12 +4,17 @@ import "gorm.io/gorm"
 
 type Services struct {
 	Credentials *CredentialsService
+	Checkers    *CheckerRegistry
+	Notifiers   *NotifierRegistry
 	// Add other services here as needed
 }
 
 func NewServices(db *gorm.DB) *Services {
+	credentials := NewCredentialsService(db)
 	return &Services{
-		Credentials: NewCredentialsService(db),
+		Credentials: credentials,
+		Checkers:    NewDefaultCheckerRegistry(credentials),
+		Notifiers:   NewDefaultNotifierRegistry(),
 		// Initialize other services here
 	}
 }
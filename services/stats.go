@@ -0,0 +1,281 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"uptime-monitor/types"
+)
+
+// statsGapIntervals is how many check intervals a status lasts without a
+// newer log before the time counts as missing data
+const statsGapIntervals = 3
+
+// TimeInterval is a half-open period [Start, End)
+type TimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// UptimeStats summarises a monitor's check history over a time window. Durations
+// are in seconds and latencies in milliseconds.
+type UptimeStats struct {
+	MonitorID   string    `json:"monitor_id"`
+	MonitorName string    `json:"monitor_name"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+
+	TotalChecks int `json:"total_checks"`
+	UpChecks    int `json:"up_checks"`
+	DownChecks  int `json:"down_checks"`
+
+	// UptimePercent is the share of monitored time the monitor was up. Time with
+	// no check data, pending checks and maintenance are not counted.
+	UptimePercent    float64 `json:"uptime_percent"`
+	MonitoredSeconds float64 `json:"monitored_seconds"`
+	ExcludedSeconds  float64 `json:"excluded_seconds"`
+
+	Incidents        int     `json:"incidents"`
+	IncidentDuration float64 `json:"incident_duration_seconds"`
+	OngoingIncident  bool    `json:"ongoing_incident"` // The window ends during an incident
+	MTTRSeconds      float64 `json:"mttr_seconds"`
+	MTBFSeconds      float64 `json:"mtbf_seconds"`
+
+	// Latency of successful checks
+	LatencyAverage float64 `json:"latency_avg_ms"`
+	LatencyP50     int64   `json:"latency_p50_ms"`
+	LatencyP95     int64   `json:"latency_p95_ms"`
+	LatencyP99     int64   `json:"latency_p99_ms"`
+}
+
+// StatsSummary aggregates the stats of every monitor in a profile
+type StatsSummary struct {
+	From          time.Time     `json:"from"`
+	To            time.Time     `json:"to"`
+	Monitors      []UptimeStats `json:"monitors"`
+	UptimePercent float64       `json:"uptime_percent"` // Mean uptime of monitors with data
+	Incidents     int           `json:"incidents"`
+	DownSeconds   float64       `json:"incident_duration_seconds"`
+}
+
+// ParseStatsWindow resolves a window name (24h, 7d, 30d, month or custom) into a
+// time range ending now. month takes a YYYY-MM value and custom takes RFC 3339
+// from and to values.
+func ParseStatsWindow(window, month, from, to string, now time.Time) (time.Time, time.Time, error) {
+	switch window {
+	case "", "24h":
+		return now.Add(-24 * time.Hour), now, nil
+	case "7d":
+		return now.AddDate(0, 0, -7), now, nil
+	case "30d":
+		return now.AddDate(0, 0, -30), now, nil
+	case "month":
+		start, err := time.ParseInLocation("2006-01", month, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	case "custom":
+		start, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from time %q", from)
+		}
+		end := now
+		if to != "" {
+			if end, err = time.Parse(time.RFC3339, to); err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid to time %q", to)
+			}
+		}
+		if !start.Before(end) {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+		}
+		return start, end, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid window %q, expected 24h, 7d, 30d, month or custom", window)
+	}
+}
+
+// ComputeUptimeStats computes uptime statistics for a monitor from its logs in
+// [from, to). logs must be sorted oldest first and may start with the last log
+// before from, which gives the status at the start of the window. Each status
+// lasts until the next log, up to a few check intervals. Time inside the
+// excluded intervals, and logs with the maintenance status, are left out.
+func ComputeUptimeStats(monitor *types.Monitor, logs []types.Log, from, to time.Time, excluded []TimeInterval) UptimeStats {
+	stats := UptimeStats{
+		MonitorID:   monitor.ID,
+		MonitorName: monitor.Name,
+		From:        from,
+		To:          to,
+	}
+
+	var upSeconds float64
+	var latencies []int64
+	var latencySum int64
+	inIncident := false
+
+	interval := monitor.CheckInterval
+	if interval < 60 {
+		interval = 60
+	}
+	maxSpan := time.Duration(interval*statsGapIntervals) * time.Second
+
+	for i, entry := range logs {
+		start := entry.CreatedAt
+		if start.Before(from) {
+			start = from
+		}
+		end := to
+		if i+1 < len(logs) && logs[i+1].CreatedAt.Before(to) {
+			end = logs[i+1].CreatedAt
+		}
+		// A gap in the history (scheduler down, monitor paused) is not monitored time
+		if limit := entry.CreatedAt.Add(maxSpan); end.After(limit) {
+			end = limit
+		}
+		// Checks inside the window are counted; an earlier log only sets the initial status
+		counted := !entry.CreatedAt.Before(from) && entry.CreatedAt.Before(to)
+		if !counted && !start.Before(end) {
+			continue
+		}
+
+		excludedSeconds := overlapSeconds(start, end, excluded)
+		seconds := end.Sub(start).Seconds() - excludedSeconds
+		if seconds < 0 {
+			seconds = 0
+		}
+
+		switch entry.Status {
+		case "up":
+			if counted {
+				stats.TotalChecks++
+				stats.UpChecks++
+				latencies = append(latencies, entry.ResponseTime)
+				latencySum += entry.ResponseTime
+			}
+			upSeconds += seconds
+			stats.MonitoredSeconds += seconds
+			stats.ExcludedSeconds += excludedSeconds
+			inIncident = false
+		case "down", "unauthorized":
+			if counted {
+				stats.TotalChecks++
+				stats.DownChecks++
+			}
+			if !inIncident && seconds > 0 {
+				stats.Incidents++
+				inIncident = true
+			}
+			stats.IncidentDuration += seconds
+			stats.MonitoredSeconds += seconds
+			stats.ExcludedSeconds += excludedSeconds
+		case "maintenance":
+			stats.ExcludedSeconds += end.Sub(start).Seconds()
+		default:
+			// pending and unknown statuses are not monitored time
+			if counted {
+				stats.TotalChecks++
+			}
+		}
+	}
+	stats.OngoingIncident = inIncident
+
+	if stats.MonitoredSeconds > 0 {
+		stats.UptimePercent = roundTo(upSeconds/stats.MonitoredSeconds*100, 4)
+	}
+	if stats.Incidents > 0 {
+		stats.MTTRSeconds = roundTo(stats.IncidentDuration/float64(stats.Incidents), 1)
+		stats.MTBFSeconds = roundTo(upSeconds/float64(stats.Incidents), 1)
+	}
+	stats.IncidentDuration = roundTo(stats.IncidentDuration, 1)
+	stats.MonitoredSeconds = roundTo(stats.MonitoredSeconds, 1)
+	stats.ExcludedSeconds = roundTo(stats.ExcludedSeconds, 1)
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.LatencyAverage = roundTo(float64(latencySum)/float64(len(latencies)), 1)
+		stats.LatencyP50 = percentile(latencies, 50)
+		stats.LatencyP95 = percentile(latencies, 95)
+		stats.LatencyP99 = percentile(latencies, 99)
+	}
+	return stats
+}
+
+// SummarizeStats aggregates per-monitor stats into a profile summary
+func SummarizeStats(from, to time.Time, monitors []UptimeStats) StatsSummary {
+	summary := StatsSummary{From: from, To: to, Monitors: monitors}
+	var uptimeSum float64
+	withData := 0
+	for _, m := range monitors {
+		summary.Incidents += m.Incidents
+		summary.DownSeconds += m.IncidentDuration
+		if m.MonitoredSeconds > 0 {
+			uptimeSum += m.UptimePercent
+			withData++
+		}
+	}
+	if withData > 0 {
+		summary.UptimePercent = roundTo(uptimeSum/float64(withData), 4)
+	}
+	return summary
+}
+
+// StatsCSVHeader is the header row of a stats CSV export
+var StatsCSVHeader = []string{
+	"monitor_id", "monitor_name", "from", "to", "uptime_percent", "total_checks",
+	"incidents", "incident_duration_seconds", "mttr_seconds", "mtbf_seconds",
+	"latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "excluded_seconds",
+}
+
+// CSVRecord renders the stats as a row matching StatsCSVHeader
+func (s UptimeStats) CSVRecord() []string {
+	return []string{
+		s.MonitorID,
+		s.MonitorName,
+		s.From.Format(time.RFC3339),
+		s.To.Format(time.RFC3339),
+		fmt.Sprintf("%.4f", s.UptimePercent),
+		fmt.Sprint(s.TotalChecks),
+		fmt.Sprint(s.Incidents),
+		fmt.Sprintf("%.0f", s.IncidentDuration),
+		fmt.Sprintf("%.0f", s.MTTRSeconds),
+		fmt.Sprintf("%.0f", s.MTBFSeconds),
+		fmt.Sprint(s.LatencyP50),
+		fmt.Sprint(s.LatencyP95),
+		fmt.Sprint(s.LatencyP99),
+		fmt.Sprintf("%.0f", s.ExcludedSeconds),
+	}
+}
+
+// overlapSeconds returns how much of [start, end) falls inside the intervals.
+// The intervals must not overlap each other.
+func overlapSeconds(start, end time.Time, intervals []TimeInterval) float64 {
+	var total float64
+	for _, in := range intervals {
+		s, e := in.Start, in.End
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if s.Before(e) {
+			total += e.Sub(s).Seconds()
+		}
+	}
+	return total
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func roundTo(v float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(v*factor) / factor
+}
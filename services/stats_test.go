@@ -0,0 +1,152 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"uptime-monitor/types"
+)
+
+func TestComputeUptimeStats(t *testing.T) {
+	from := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Minute)
+	at := func(seconds int) time.Time { return from.Add(time.Duration(seconds) * time.Second) }
+
+	// checks builds one log per minute from the start of the window with the given statuses
+	checks := func(statuses ...string) []types.Log {
+		logs := make([]types.Log, len(statuses))
+		for i, status := range statuses {
+			logs[i] = types.Log{Status: status, ResponseTime: int64(i+1) * 100, CreatedAt: at(i * 60)}
+		}
+		return logs
+	}
+	up := func(n int) []string {
+		statuses := make([]string, n)
+		for i := range statuses {
+			statuses[i] = "up"
+		}
+		return statuses
+	}
+
+	tests := []struct {
+		name     string
+		logs     []types.Log
+		excluded []TimeInterval
+		want     UptimeStats
+	}{
+		{
+			name: "no logs",
+			want: UptimeStats{},
+		},
+		{
+			name: "always up",
+			logs: checks(up(10)...),
+			want: UptimeStats{
+				TotalChecks: 10, UpChecks: 10, UptimePercent: 100, MonitoredSeconds: 600,
+				LatencyAverage: 550, LatencyP50: 500, LatencyP95: 1000, LatencyP99: 1000,
+			},
+		},
+		{
+			name: "one incident",
+			logs: checks("up", "down", "unauthorized", "up", "up", "up", "up", "up", "up", "up"),
+			want: UptimeStats{
+				TotalChecks: 10, UpChecks: 8, DownChecks: 2, UptimePercent: 80, MonitoredSeconds: 600,
+				Incidents: 1, IncidentDuration: 120, MTTRSeconds: 120, MTBFSeconds: 480,
+				LatencyAverage: 625, LatencyP50: 600, LatencyP95: 1000, LatencyP99: 1000,
+			},
+		},
+		{
+			name: "status before the window",
+			logs: []types.Log{
+				{Status: "down", CreatedAt: at(-30)},
+				{Status: "up", ResponseTime: 50, CreatedAt: at(60)},
+			},
+			// Down until the first check, then up for three intervals
+			want: UptimeStats{
+				TotalChecks: 1, UpChecks: 1, UptimePercent: 75, MonitoredSeconds: 240,
+				Incidents: 1, IncidentDuration: 60, MTTRSeconds: 60, MTBFSeconds: 180,
+				LatencyAverage: 50, LatencyP50: 50, LatencyP95: 50, LatencyP99: 50,
+			},
+		},
+		{
+			name: "gap in the history",
+			logs: []types.Log{{Status: "up", ResponseTime: 10, CreatedAt: at(0)}},
+			want: UptimeStats{
+				TotalChecks: 1, UpChecks: 1, UptimePercent: 100, MonitoredSeconds: 180,
+				LatencyAverage: 10, LatencyP50: 10, LatencyP95: 10, LatencyP99: 10,
+			},
+		},
+		{
+			name: "ongoing incident",
+			logs: []types.Log{
+				{Status: "up", ResponseTime: 10, CreatedAt: at(0)},
+				{Status: "down", CreatedAt: at(300)},
+			},
+			want: UptimeStats{
+				TotalChecks: 2, UpChecks: 1, DownChecks: 1, UptimePercent: 50, MonitoredSeconds: 360,
+				Incidents: 1, IncidentDuration: 180, OngoingIncident: true, MTTRSeconds: 180, MTBFSeconds: 180,
+				LatencyAverage: 10, LatencyP50: 10, LatencyP95: 10, LatencyP99: 10,
+			},
+		},
+		{
+			name:     "downtime inside a maintenance window",
+			logs:     checks("up", "up", "down", "up", "up", "up", "up", "up", "up", "up"),
+			excluded: []TimeInterval{{Start: at(120), End: at(180)}},
+			want: UptimeStats{
+				TotalChecks: 10, UpChecks: 9, DownChecks: 1, UptimePercent: 100, MonitoredSeconds: 540, ExcludedSeconds: 60,
+				LatencyAverage: 577.8, LatencyP50: 600, LatencyP95: 1000, LatencyP99: 1000,
+			},
+		},
+		{
+			name: "maintenance and pending statuses",
+			logs: checks("up", "maintenance", "pending", "up", "up", "up", "up", "up", "up", "up"),
+			want: UptimeStats{
+				TotalChecks: 9, UpChecks: 8, UptimePercent: 100, MonitoredSeconds: 480, ExcludedSeconds: 60,
+				LatencyAverage: 625, LatencyP50: 600, LatencyP95: 1000, LatencyP99: 1000,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &types.Monitor{ID: "m1", Name: "API", CheckInterval: 60}
+			got := ComputeUptimeStats(monitor, tt.logs, from, to, tt.excluded)
+
+			want := tt.want
+			want.MonitorID, want.MonitorName, want.From, want.To = "m1", "API", from, to
+			if got != want {
+				t.Errorf("ComputeUptimeStats() =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseStatsWindow(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		window, month    string
+		from, to         string
+		wantFrom, wantTo time.Time
+		wantErr          bool
+	}{
+		{name: "default", wantFrom: now.Add(-24 * time.Hour), wantTo: now},
+		{name: "7d", window: "7d", wantFrom: now.AddDate(0, 0, -7), wantTo: now},
+		{name: "month", window: "month", month: "2024-02", wantFrom: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "custom", window: "custom", from: "2024-03-01T00:00:00Z", to: "2024-03-02T00:00:00Z", wantFrom: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "custom until now", window: "custom", from: "2024-03-01T00:00:00Z", wantFrom: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), wantTo: now},
+		{name: "bad month", window: "month", month: "March", wantErr: true},
+		{name: "custom reversed", window: "custom", from: "2024-03-02T00:00:00Z", to: "2024-03-01T00:00:00Z", wantErr: true},
+		{name: "unknown window", window: "1y", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseStatsWindow(tt.window, tt.month, tt.from, tt.to, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatsWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (!from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo)) {
+				t.Errorf("ParseStatsWindow() = %s, %s, want %s, %s", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"uptime-monitor/types"
+)
+
+// defaultAcceptedStatusCodes is used when a monitor accepts no explicit codes
+const defaultAcceptedStatusCodes = "200-299"
+
+// statusCodeRange is an inclusive range of HTTP status codes
+type statusCodeRange struct {
+	min, max int
+}
+
+// ValidateStatusCodes checks a list of accepted status codes and ranges
+func ValidateStatusCodes(spec string) error {
+	_, err := parseStatusCodeRanges(spec)
+	return err
+}
+
+// parseStatusCodeRanges parses a list of codes and ranges such as "200-299,301,418"
+func parseStatusCodeRanges(spec string) ([]statusCodeRange, error) {
+	var ranges []statusCodeRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		low, high, isRange := strings.Cut(part, "-")
+		min, err := parseStatusCode(low)
+		if err != nil {
+			return nil, err
+		}
+		max := min
+		if isRange {
+			if max, err = parseStatusCode(high); err != nil {
+				return nil, err
+			}
+			if max < min {
+				return nil, fmt.Errorf("invalid status code range %q", part)
+			}
+		}
+		ranges = append(ranges, statusCodeRange{min: min, max: max})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no status codes given")
+	}
+	return ranges, nil
+}
+
+// parseStatusCode parses a single HTTP status code
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code %q", s)
+	}
+	return code, nil
+}
+
+// acceptedStatusCodes returns the status code ranges a monitor treats as up
+func acceptedStatusCodes(monitor *types.Monitor) []statusCodeRange {
+	spec := monitor.AcceptedStatusCodes
+	if spec == "" && monitor.ExpectedStatus != 0 {
+		spec = strconv.Itoa(monitor.ExpectedStatus)
+	}
+	if spec == "" {
+		spec = defaultAcceptedStatusCodes
+	}
+
+	ranges, err := parseStatusCodeRanges(spec)
+	if err != nil {
+		// Invalid settings are rejected by the controller; fall back to 2xx for older rows
+		ranges, _ = parseStatusCodeRanges(defaultAcceptedStatusCodes)
+	}
+	return ranges
+}
+
+// statusFromCode maps an HTTP response code to a monitor status. Accepted codes
+// are up, 401 and 403 are unauthorized and everything else is down.
+func statusFromCode(monitor *types.Monitor, code int) string {
+	for _, r := range acceptedStatusCodes(monitor) {
+		if code >= r.min && code <= r.max {
+			return "up"
+		}
+	}
+
+	switch code {
+	case 401, 403:
+		return "unauthorized"
+	default:
+		return "down"
+	}
+}
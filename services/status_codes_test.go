@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"uptime-monitor/types"
+)
+
+func TestValidateStatusCodes(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "200"},
+		{spec: "200-299"},
+		{spec: "200-299, 301,418"},
+		{spec: "100-599"},
+		{spec: "204,"},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "99", wantErr: true},
+		{spec: "600", wantErr: true},
+		{spec: "2xx", wantErr: true},
+		{spec: "299-200", wantErr: true},
+		{spec: "200-", wantErr: true},
+		{spec: "200-299-301", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if err := ValidateStatusCodes(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStatusCodes(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusFromCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		monitor types.Monitor
+		code    int
+		want    string
+	}{
+		{"default accepts 2xx", types.Monitor{}, 204, "up"},
+		{"default rejects redirects", types.Monitor{}, 301, "down"},
+		{"unauthorized", types.Monitor{}, 401, "unauthorized"},
+		{"forbidden", types.Monitor{}, 403, "unauthorized"},
+		{"server error", types.Monitor{}, 503, "down"},
+		{"accepted range", types.Monitor{AcceptedStatusCodes: "200-299,301-302"}, 302, "up"},
+		{"outside accepted codes", types.Monitor{AcceptedStatusCodes: "200"}, 201, "down"},
+		{"accepted 401", types.Monitor{AcceptedStatusCodes: "200,401"}, 401, "up"},
+		{"expected status", types.Monitor{ExpectedStatus: 418}, 418, "up"},
+		{"expected status only", types.Monitor{ExpectedStatus: 418}, 200, "down"},
+		{"accepted codes win over expected status", types.Monitor{AcceptedStatusCodes: "200", ExpectedStatus: 418}, 418, "down"},
+		{"invalid codes fall back to 2xx", types.Monitor{AcceptedStatusCodes: "abc"}, 200, "up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusFromCode(&tt.monitor, tt.code); got != tt.want {
+				t.Errorf("statusFromCode(%d) = %s, want %s", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+	"uptime-monitor/types"
+)
+
+// StatusPageDays is the number of daily uptime bars shown per component
+const StatusPageDays = 90
+
+// Component and page states shown on a status page
+const (
+	StatusOperational   = "operational"
+	StatusDegraded      = "degraded"
+	StatusPartialOutage = "partial_outage"
+	StatusMajorOutage   = "major_outage"
+	StatusMaintenance   = "maintenance"
+	StatusUnknown       = "unknown"
+)
+
+var statusPageSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// The public view of a status page. These types deliberately hold only names,
+// states and times: monitor URLs, hosts, check messages and credentials never
+// leave the server through a status page.
+type (
+	PublicStatusPage struct {
+		Title       string              `json:"title"`
+		Description string              `json:"description"`
+		LogoURL     string              `json:"logo_url,omitempty"`
+		Status      string              `json:"status"`
+		Components  []PublicComponent   `json:"components"`
+		Incidents   []PublicIncident    `json:"incidents"`
+		Maintenance []PublicMaintenance `json:"maintenance"`
+		GeneratedAt time.Time           `json:"generated_at"`
+	}
+
+	PublicComponent struct {
+		Name          string      `json:"name"`
+		Description   string      `json:"description,omitempty"`
+		Status        string      `json:"status"`
+		UptimePercent *float64    `json:"uptime_percent"` // Over all days shown, nil without data
+		Days          []PublicDay `json:"days"`
+	}
+
+	// PublicDay is one uptime bar; UptimePercent is nil when there is no data for the day
+	PublicDay struct {
+		Date          string   `json:"date"`
+		UptimePercent *float64 `json:"uptime_percent"`
+		Incidents     int      `json:"incidents"`
+	}
+
+	PublicIncident struct {
+		Component      string     `json:"component"`
+		Status         string     `json:"status"`
+		StartedAt      time.Time  `json:"started_at"`
+		AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	}
+
+	PublicMaintenance struct {
+		Name        string    `json:"name"`
+		Description string    `json:"description,omitempty"`
+		Components  []string  `json:"components"`
+		StartsAt    time.Time `json:"starts_at"`
+		EndsAt      time.Time `json:"ends_at"`
+		Active      bool      `json:"active"`
+	}
+)
+
+// ValidateStatusPage checks a status page's slug, logo and components
+func ValidateStatusPage(page *types.StatusPage) error {
+	if !statusPageSlugPattern.MatchString(page.Slug) {
+		return fmt.Errorf("slug must be lowercase letters, digits and dashes")
+	}
+	if page.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if page.LogoURL != "" {
+		u, err := url.Parse(page.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("logo URL must be an http or https URL")
+		}
+	}
+	for _, c := range page.Components {
+		if c.Name == "" {
+			return fmt.Errorf("component name is required")
+		}
+		if len(types.SplitList(c.MonitorIDs)) == 0 {
+			return fmt.Errorf("component %q has no monitors", c.Name)
+		}
+	}
+	return nil
+}
+
+// StatusPageData is the state a status page is rendered from. Logs holds each
+// monitor's history for the shown days, oldest first, optionally starting with
+// the last earlier log.
+type StatusPageData struct {
+	Page      *types.StatusPage
+	Monitors  map[string]*types.Monitor
+	Logs      map[string][]types.Log
+	Incidents []types.Incident // Unresolved incidents of the page's monitors
+	Windows   []types.MaintenanceWindow
+}
+
+// BuildPublicStatusPage computes the public view of a status page at now
+func BuildPublicStatusPage(data StatusPageData, now time.Time) *PublicStatusPage {
+	page := &PublicStatusPage{
+		Title:       data.Page.Title,
+		Description: data.Page.Description,
+		LogoURL:     data.Page.LogoURL,
+		Components:  []PublicComponent{},
+		Incidents:   []PublicIncident{},
+		Maintenance: []PublicMaintenance{},
+		GeneratedAt: now,
+	}
+
+	// Days are UTC calendar days
+	utc := now.UTC()
+	today := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(StatusPageDays - 1))
+	componentOf := make(map[string]string)
+
+	for _, c := range data.Page.Components {
+		var monitors []*types.Monitor
+		for _, id := range types.SplitList(c.MonitorIDs) {
+			if m, ok := data.Monitors[id]; ok {
+				monitors = append(monitors, m)
+				componentOf[id] = c.Name
+			}
+		}
+
+		component := PublicComponent{
+			Name:        c.Name,
+			Description: c.Description,
+			Status:      componentStatus(monitors),
+		}
+		component.Days, component.UptimePercent = componentDays(monitors, data, from, now)
+		page.Components = append(page.Components, component)
+	}
+	page.Status = overallStatus(page.Components)
+
+	for _, incident := range data.Incidents {
+		name, ok := componentOf[incident.MonitorID]
+		if !ok {
+			continue
+		}
+		page.Incidents = append(page.Incidents, PublicIncident{
+			Component:      name,
+			Status:         incident.Status,
+			StartedAt:      incident.StartedAt,
+			AcknowledgedAt: incident.AcknowledgedAt,
+		})
+	}
+
+	page.Maintenance = publicMaintenance(data, componentOf, now)
+	return page
+}
+
+// componentDays computes a component's daily uptime bars and its overall uptime,
+// weighting each monitor by its monitored time
+func componentDays(monitors []*types.Monitor, data StatusPageData, from, now time.Time) ([]PublicDay, *float64) {
+	days := make([]PublicDay, 0, StatusPageDays)
+	var upSum, monitoredSum float64
+
+	for day := from; day.Before(now); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		if end.After(now) {
+			end = now
+		}
+
+		var dayUp, dayMonitored float64
+		incidents := 0
+		for _, m := range monitors {
+			logs := logsForRange(data.Logs[m.ID], day, end)
+			excluded := MaintenanceExclusions(data.Windows, m, day, end)
+			stats := ComputeUptimeStats(m, logs, day, end, excluded)
+			dayMonitored += stats.MonitoredSeconds
+			dayUp += stats.MonitoredSeconds * stats.UptimePercent / 100
+			incidents += stats.Incidents
+		}
+		upSum += dayUp
+		monitoredSum += dayMonitored
+
+		publicDay := PublicDay{Date: day.Format("2006-01-02"), Incidents: incidents}
+		if dayMonitored > 0 {
+			uptime := roundTo(dayUp/dayMonitored*100, 2)
+			publicDay.UptimePercent = &uptime
+		}
+		days = append(days, publicDay)
+	}
+
+	if monitoredSum == 0 {
+		return days, nil
+	}
+	uptime := roundTo(upSum/monitoredSum*100, 2)
+	return days, &uptime
+}
+
+// logsForRange returns the logs in [from, to) preceded by the last earlier log
+func logsForRange(logs []types.Log, from, to time.Time) []types.Log {
+	start := sort.Search(len(logs), func(i int) bool { return !logs[i].CreatedAt.Before(from) })
+	end := sort.Search(len(logs), func(i int) bool { return !logs[i].CreatedAt.Before(to) })
+	if start > 0 {
+		start--
+	}
+	return logs[start:end]
+}
+
+// componentStatus derives a component's state from its monitors' current status
+func componentStatus(monitors []*types.Monitor) string {
+	if len(monitors) == 0 {
+		return StatusUnknown
+	}
+
+	down, maintenance, up := 0, 0, 0
+	for _, m := range monitors {
+		switch m.Status {
+		case "down", "unauthorized":
+			down++
+		case "maintenance":
+			maintenance++
+		case "up":
+			up++
+		}
+	}
+
+	switch {
+	case down == len(monitors):
+		return StatusMajorOutage
+	case down > 0:
+		return StatusPartialOutage
+	case maintenance > 0:
+		return StatusMaintenance
+	case up == len(monitors):
+		return StatusOperational
+	case up > 0:
+		return StatusDegraded
+	default:
+		return StatusUnknown
+	}
+}
+
+// overallStatus is the worst state among a page's components
+func overallStatus(components []PublicComponent) string {
+	severity := map[string]int{
+		StatusOperational:   0,
+		StatusUnknown:       1,
+		StatusMaintenance:   2,
+		StatusDegraded:      3,
+		StatusPartialOutage: 4,
+		StatusMajorOutage:   5,
+	}
+
+	status := StatusOperational
+	for _, c := range components {
+		if severity[c.Status] > severity[status] {
+			status = c.Status
+		}
+	}
+	return status
+}
+
+// publicMaintenance lists the running and upcoming (next 7 days) maintenance
+// that covers the page's monitors
+func publicMaintenance(data StatusPageData, componentOf map[string]string, now time.Time) []PublicMaintenance {
+	maintenance := []PublicMaintenance{}
+	horizon := now.AddDate(0, 0, 7)
+
+	for i := range data.Windows {
+		w := &data.Windows[i]
+
+		seen := make(map[string]bool)
+		var components []string
+		for id, name := range componentOf {
+			if !seen[name] && w.AppliesTo(data.Monitors[id]) {
+				seen[name] = true
+				components = append(components, name)
+			}
+		}
+		if len(components) == 0 {
+			continue
+		}
+		sort.Strings(components)
+
+		var intervals []TimeInterval
+		if w.IsRecurring() {
+			intervals = MaintenanceIntervals(w, now.Add(-time.Duration(w.DurationMinutes)*time.Minute), horizon)
+		} else if w.Enabled && w.StartsAt.Before(horizon) {
+			// Show one-off windows with their full period
+			intervals = []TimeInterval{{Start: w.StartsAt, End: w.EndsAt}}
+		}
+		for _, in := range intervals {
+			if !in.End.After(now) {
+				continue
+			}
+			maintenance = append(maintenance, PublicMaintenance{
+				Name:        w.Name,
+				Description: w.Description,
+				Components:  components,
+				StartsAt:    in.Start,
+				EndsAt:      in.End,
+				Active:      !in.Start.After(now),
+			})
+		}
+	}
+
+	sort.Slice(maintenance, func(i, j int) bool { return maintenance[i].StartsAt.Before(maintenance[j].StartsAt) })
+	return maintenance
+}
+
+var statusLabels = map[string]string{
+	StatusOperational:   "All systems operational",
+	StatusDegraded:      "Degraded performance",
+	StatusPartialOutage: "Partial outage",
+	StatusMajorOutage:   "Major outage",
+	StatusMaintenance:   "Under maintenance",
+	StatusUnknown:       "No data",
+}
+
+// StatusLabel returns a human readable description of the page's overall state
+func (p *PublicStatusPage) StatusLabel() string {
+	return statusLabels[p.Status]
+}
+
+// StatusLabel returns a human readable description of the component's state
+func (c PublicComponent) StatusLabel() string {
+	if c.Status == StatusOperational {
+		return "Operational"
+	}
+	return statusLabels[c.Status]
+}
+
+// Level buckets the day's uptime for colouring its bar: none, good, warn or bad
+func (d PublicDay) Level() string {
+	switch {
+	case d.UptimePercent == nil:
+		return "none"
+	case *d.UptimePercent >= 99.9:
+		return "good"
+	case *d.UptimePercent >= 95:
+		return "warn"
+	default:
+		return "bad"
+	}
+}
+
+// UptimeLabel formats the component's uptime over all days shown
+func (c PublicComponent) UptimeLabel() string {
+	return uptimeLabel(c.UptimePercent)
+}
+
+// UptimeLabel formats the day's uptime
+func (d PublicDay) UptimeLabel() string {
+	return uptimeLabel(d.UptimePercent)
+}
+
+func uptimeLabel(percent *float64) string {
+	if percent == nil {
+		return "no data"
+	}
+	return fmt.Sprintf("%.2f%% uptime", *percent)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+	"uptime-monitor/types"
+)
+
+// defaultTCPTimeout bounds a TCP connect when the monitor has no timeout set
+const defaultTCPTimeout = 10 * time.Second
+
+// TCPChecker implements Checker for the tcp monitor type. A check succeeds when
+// a TCP connection to Hostname:Port can be opened, which also serves as a ping
+// for hosts that block ICMP.
+type TCPChecker struct{}
+
+// Check opens and immediately closes a TCP connection to the monitor's address
+func (c *TCPChecker) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	timeout := defaultTCPTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(monitor.Hostname, strconv.Itoa(monitor.Port))
+	metadata := map[string]interface{}{"address": addr}
+
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return &CheckResult{
+			Status:        "down",
+			ResponseTime:  responseTime,
+			Message:       fmt.Sprintf("TCP connection to %s failed: %v", addr, err),
+			Metadata:      metadata,
+			ErrorCategory: classifyError(err),
+		}
+	}
+	metadata["remote_addr"] = conn.RemoteAddr().String()
+	conn.Close()
+
+	return &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Message:      fmt.Sprintf("TCP connection to %s succeeded", addr),
+		Metadata:     metadata,
+		Timings:      &CheckTimings{ConnectMs: responseTime},
+	}
+}
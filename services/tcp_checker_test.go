@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"uptime-monitor/types"
+)
+
+func TestTCPCheckerCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name         string
+		port         int
+		wantStatus   string
+		wantCategory string
+	}{
+		{"listening", listener.Addr().(*net.TCPAddr).Port, "up", ""},
+		{"nothing listening", closedPort(t), "down", ErrorCategoryConnect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &types.Monitor{Type: types.MonitorTypeTCP, Hostname: "127.0.0.1", Port: tt.port, Timeout: 5}
+			result := (&TCPChecker{}).Check(context.Background(), monitor)
+			if result.Status != tt.wantStatus || result.ErrorCategory != tt.wantCategory {
+				t.Fatalf("Check() = %s %s (%s), want %s %s", result.Status, result.ErrorCategory, result.Message, tt.wantStatus, tt.wantCategory)
+			}
+			if !strings.Contains(result.Message, "127.0.0.1") {
+				t.Errorf("Check() message %q does not name the address", result.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/types"
+)
+
+const (
+	// defaultTLSTimeout bounds a TLS handshake when the monitor has no timeout set
+	defaultTLSTimeout = 10 * time.Second
+	// defaultTLSPort is used by tls monitors without a port
+	defaultTLSPort = 443
+
+	// Default certificate expiry thresholds in days
+	DefaultTLSWarningDays  = 30
+	DefaultTLSCriticalDays = 7
+)
+
+// TLSCertificateInfo describes the leaf certificate presented by a server
+type TLSCertificateInfo struct {
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	SANs          []string  `json:"sans"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+	ChainValid    bool      `json:"chain_valid"`
+	ChainError    string    `json:"chain_error,omitempty"`
+}
+
+// TLSChecker implements Checker for the tls monitor type. It performs a TLS
+// handshake with Hostname:Port without sending an HTTP request.
+type TLSChecker struct{}
+
+// Check performs the handshake and reports the certificate state
+func (c *TLSChecker) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	timeout := defaultTLSTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	port := monitor.Port
+	if port == 0 {
+		port = defaultTLSPort
+	}
+	addr := net.JoinHostPort(monitor.Hostname, strconv.Itoa(port))
+	metadata := map[string]interface{}{"address": addr}
+
+	start := time.Now()
+	info, err := InspectTLS(ctx, addr, monitor.Hostname)
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return &CheckResult{
+			Status:        "down",
+			ResponseTime:  responseTime,
+			Message:       fmt.Sprintf("TLS handshake with %s failed: %v", addr, err),
+			Metadata:      metadata,
+			ErrorCategory: classifyError(err),
+		}
+	}
+
+	result := &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Message:      fmt.Sprintf("%s presented a valid certificate expiring in %d days", addr, info.DaysRemaining),
+		Metadata:     metadata,
+		TLS:          info,
+	}
+	switch {
+	case !info.ChainValid:
+		result.Status = "down"
+		result.Message = fmt.Sprintf("%s presented an invalid certificate chain: %s", addr, info.ChainError)
+		result.ErrorCategory = ErrorCategoryTLS
+	case info.DaysRemaining < 0:
+		result.Status = "down"
+		result.Message = fmt.Sprintf("%s presented a certificate that expired on %s", addr, info.NotAfter.Format(time.RFC1123))
+		result.ErrorCategory = ErrorCategoryTLS
+	}
+	return result
+}
+
+// InspectTLS performs a TLS handshake with addr and returns the leaf certificate
+// details. The chain is verified separately so that details are still reported
+// for invalid or expired certificates.
+func InspectTLS(ctx context.Context, addr, serverName string) (*TLSCertificateInfo, error) {
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true, // verified below against the system roots
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("server presented no certificate")
+	}
+
+	info := certificateInfo(state.PeerCertificates[0])
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, verifyErr := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	info.ChainValid = verifyErr == nil
+	if verifyErr != nil {
+		info.ChainError = verifyErr.Error()
+	}
+	return info, nil
+}
+
+// tlsInfoFromState builds certificate details from an established HTTPS connection,
+// whose chain was already verified by the HTTP client
+func tlsInfoFromState(state *tls.ConnectionState) *TLSCertificateInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	info := certificateInfo(state.PeerCertificates[0])
+	info.ChainValid = len(state.VerifiedChains) > 0
+	return info
+}
+
+// certificateInfo extracts the reported fields from a leaf certificate
+func certificateInfo(cert *x509.Certificate) *TLSCertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return &TLSCertificateInfo{
+		Subject:       cert.Subject.String(),
+		Issuer:        cert.Issuer.String(),
+		SANs:          sans,
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		DaysRemaining: int(time.Until(cert.NotAfter).Hours() / 24),
+	}
+}
+
+// isTLSError reports whether a request failed because of the server certificate
+// or the TLS handshake
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &invalidCert) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &alertErr) || errors.As(err, &recordErr)
+}
+
+// httpsAddress returns the host:port and server name of an HTTPS URL
+func httpsAddress(rawURL string) (addr, serverName string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "https") {
+		return "", "", false
+	}
+	port := u.Port()
+	if port == "" {
+		port = strconv.Itoa(defaultTLSPort)
+	}
+	return net.JoinHostPort(u.Hostname(), port), u.Hostname(), true
+}
+
+// TLSAlertLevel classifies a certificate against the monitor's expiry thresholds.
+// It returns "" when no alert is needed, otherwise warning, critical or expired.
+func TLSAlertLevel(monitor *types.Monitor, info *TLSCertificateInfo) string {
+	warningDays := monitor.TLSWarningDays
+	if warningDays <= 0 {
+		warningDays = DefaultTLSWarningDays
+	}
+	criticalDays := monitor.TLSCriticalDays
+	if criticalDays <= 0 {
+		criticalDays = DefaultTLSCriticalDays
+	}
+
+	switch {
+	case info.NotAfter.Before(time.Now()):
+		return "expired"
+	case info.DaysRemaining <= criticalDays:
+		return "critical"
+	case info.DaysRemaining <= warningDays:
+		return "warning"
+	default:
+		return ""
+	}
+}
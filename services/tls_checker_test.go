@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/types"
+)
+
+// startTLSListener serves a self-signed certificate for localhost valid until
+// notAfter, and returns the port it listens on
+func startTLSListener(t *testing.T, notAfter time.Time) int {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+func TestInspectTLS(t *testing.T) {
+	notAfter := time.Now().Add(45*24*time.Hour + time.Hour).Truncate(time.Second)
+	port := startTLSListener(t, notAfter)
+
+	info, err := InspectTLS(context.Background(), net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "CN=localhost" || strings.Join(info.SANs, ",") != "localhost,127.0.0.1" {
+		t.Errorf("InspectTLS() subject = %q, SANs = %v", info.Subject, info.SANs)
+	}
+	if !info.NotAfter.Equal(notAfter) || info.DaysRemaining != 45 {
+		t.Errorf("InspectTLS() not after = %s, days remaining = %d, want %s and 45", info.NotAfter, info.DaysRemaining, notAfter)
+	}
+	// A self-signed certificate is reported, but not trusted
+	if info.ChainValid || info.ChainError == "" {
+		t.Errorf("InspectTLS() chain valid = %v (%q), want an invalid chain", info.ChainValid, info.ChainError)
+	}
+}
+
+func TestTLSCheckerCheck(t *testing.T) {
+	validPort := startTLSListener(t, time.Now().Add(45*24*time.Hour))
+	expiredPort := startTLSListener(t, time.Now().Add(-24*time.Hour))
+
+	tests := []struct {
+		name         string
+		port         int
+		wantCategory string
+		wantMessage  string
+	}{
+		{"untrusted chain", validPort, ErrorCategoryTLS, "invalid certificate chain"},
+		{"expired and untrusted", expiredPort, ErrorCategoryTLS, "invalid certificate chain"},
+		{"nothing listening", closedPort(t), ErrorCategoryConnect, "TLS handshake with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &types.Monitor{Type: types.MonitorTypeTLS, Hostname: "localhost", Port: tt.port, Timeout: 5}
+			result := (&TLSChecker{}).Check(context.Background(), monitor)
+			if result.Status != "down" || result.ErrorCategory != tt.wantCategory || !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Check() = %s %s %q, want down %s %q", result.Status, result.ErrorCategory, result.Message, tt.wantCategory, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestTLSAlertLevel(t *testing.T) {
+	certificate := func(days int) *TLSCertificateInfo {
+		return &TLSCertificateInfo{NotAfter: time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour), DaysRemaining: days}
+	}
+
+	tests := []struct {
+		name    string
+		monitor types.Monitor
+		info    *TLSCertificateInfo
+		want    string
+	}{
+		{"far from expiry", types.Monitor{}, certificate(90), ""},
+		{"default warning", types.Monitor{}, certificate(DefaultTLSWarningDays), "warning"},
+		{"default critical", types.Monitor{}, certificate(DefaultTLSCriticalDays), "critical"},
+		{"expired", types.Monitor{}, &TLSCertificateInfo{NotAfter: time.Now().Add(-time.Hour)}, "expired"},
+		{"custom warning", types.Monitor{TLSWarningDays: 60}, certificate(45), "warning"},
+		{"custom critical", types.Monitor{TLSCriticalDays: 14}, certificate(10), "critical"},
+		{"below custom warning", types.Monitor{TLSWarningDays: 10}, certificate(20), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TLSAlertLevel(&tt.monitor, tt.info); got != tt.want {
+				t.Errorf("TLSAlertLevel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"uptime-monitor/secrets"
+	"uptime-monitor/types"
+)
+
+// maxTransactionSteps caps the number of steps of a transaction monitor
+const maxTransactionSteps = 20
+
+var (
+	// templateVariable matches a {{name}} placeholder in a step template
+	templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	variableName     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	transactionMethods = map[string]bool{
+		"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true,
+	}
+)
+
+// StepResult is the outcome of one step of a transaction check
+type StepResult struct {
+	Name          string        `json:"name"`
+	Status        string        `json:"status"`
+	ResponseCode  int           `json:"response_code"`
+	DurationMs    int64         `json:"duration_ms"`
+	Message       string        `json:"message,omitempty"`
+	ErrorCategory string        `json:"error_category,omitempty"`
+	Timings       *CheckTimings `json:"timings,omitempty"`
+}
+
+// TransactionChecker runs the steps of a transaction monitor in order. Each
+// step is sent like an HTTP monitor's request, and the check stops at the
+// first step that fails.
+type TransactionChecker struct {
+	http *HTTPService
+}
+
+func NewTransactionChecker(httpService *HTTPService) *TransactionChecker {
+	return &TransactionChecker{http: httpService}
+}
+
+// Check implements Checker for transaction monitors
+func (c *TransactionChecker) Check(ctx context.Context, monitor *types.Monitor) *CheckResult {
+	steps, err := monitor.GetTransactionSteps()
+	if err != nil || len(steps) == 0 {
+		return &CheckResult{
+			Status:  "down",
+			Message: "Transaction monitor has no valid steps",
+		}
+	}
+
+	result := &CheckResult{
+		Status:   "up",
+		Message:  fmt.Sprintf("All %d steps passed", len(steps)),
+		Metadata: map[string]interface{}{"steps": len(steps)},
+	}
+	variables := make(map[string]string)
+	start := time.Now()
+	for _, step := range steps {
+		stepResult := c.runStep(ctx, monitor, step, variables)
+		result.Steps = append(result.Steps, *stepResult)
+		result.ResponseCode = stepResult.ResponseCode
+		if stepResult.Status != "up" {
+			result.Status = stepResult.Status
+			result.Message = fmt.Sprintf("Step %q failed: %s", step.Name, stepResult.Message)
+			result.ErrorCategory = stepResult.ErrorCategory
+			result.FailedStep = step.Name
+			result.Metadata["failed_step"] = step.Name
+			break
+		}
+	}
+	result.ResponseTime = time.Since(start).Milliseconds()
+	return result
+}
+
+// runStep sends the request of a step, checks its assertions and adds the
+// variables it extracts to variables
+func (c *TransactionChecker) runStep(ctx context.Context, monitor *types.Monitor, step types.TransactionStep, variables map[string]string) *StepResult {
+	result := &StepResult{Name: step.Name, Status: "down"}
+
+	request, err := stepMonitor(monitor, step, variables)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	// A URL built from extracted variables is only known now
+	if err := validateStepURL(request.URL); err != nil {
+		result.Message = fmt.Sprintf("%v, got %q", err, redactURL(request.URL))
+		return result
+	}
+
+	timeout := defaultHTTPTimeout
+	if monitor.Timeout > 0 {
+		timeout = time.Duration(monitor.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	trace, timings := newTimingTrace()
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
+	start := time.Now()
+	resp, err := c.http.ExecuteRequest(ctx, request)
+	defer func() { result.DurationMs = time.Since(start).Milliseconds() }()
+	result.Timings = timings()
+	if err != nil {
+		var credErr *credentialError
+		switch {
+		case errors.As(err, &credErr):
+			result.Message = credErr.Error()
+			result.ErrorCategory = ErrorCategoryCredential
+		case isTLSError(err):
+			result.Message = fmt.Sprintf("TLS error: %s", redactRequestError(err))
+			result.ErrorCategory = ErrorCategoryTLS
+		default:
+			result.Message = fmt.Sprintf("Connection error: %s", redactRequestError(err))
+			result.ErrorCategory = classifyError(err)
+		}
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.ResponseCode = resp.StatusCode
+	if result.Status = statusFromCode(request, resp.StatusCode); result.Status != "up" {
+		result.Message = describeStatusCode(redactURL(request.URL), resp.StatusCode)
+		return result
+	}
+
+	var body []byte
+	if request.HasBodyAssertions() || extractsFromBody(step) {
+		if body, err = readBody(request, resp.Body); err != nil {
+			result.Status = "down"
+			result.Message = fmt.Sprintf("Failed to read response body: %v", err)
+			result.ErrorCategory = classifyError(err)
+			return result
+		}
+		result.Timings = timings()
+	}
+	if failure := evaluateBodyAssertions(request, body); failure != "" {
+		result.Status = "down"
+		result.Message = fmt.Sprintf("Assertion failed: %s", failure)
+		result.ErrorCategory = ErrorCategoryAssertion
+		return result
+	}
+	if err := extractVariables(step, resp.Header, body, variables); err != nil {
+		result.Status = "down"
+		result.Message = err.Error()
+		result.ErrorCategory = ErrorCategoryAssertion
+	}
+	return result
+}
+
+// stepMonitor renders the templates of a step into an HTTP monitor, so that the
+// request is built and checked like those of HTTP monitors. Values substituted
+// into the body are JSON escaped.
+func stepMonitor(monitor *types.Monitor, step types.TransactionStep, variables map[string]string) (*types.Monitor, error) {
+	noEscape := func(s string) string { return s }
+
+	method, err := renderTemplate(step.Method, variables, noEscape)
+	if err != nil {
+		return nil, err
+	}
+	if method = strings.ToUpper(method); method == "" {
+		method = "GET"
+	}
+	rawURL, err := renderTemplate(step.URL, variables, noEscape)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &types.Monitor{
+		ID:                  monitor.ID,
+		ProfileID:           monitor.ProfileID,
+		Name:                monitor.Name + " / " + step.Name,
+		Type:                types.MonitorTypeHTTP,
+		URL:                 rawURL,
+		Method:              method,
+		CredentialID:        step.CredentialID,
+		Timeout:             monitor.Timeout,
+		FollowRedirects:     monitor.FollowRedirects,
+		MaxBodyBytes:        monitor.MaxBodyBytes,
+		AcceptedStatusCodes: step.AcceptedStatusCodes,
+		BodyContains:        step.BodyContains,
+		BodyNotContains:     step.BodyNotContains,
+		BodyRegex:           step.BodyRegex,
+	}
+
+	if len(step.Headers) > 0 {
+		headers := make(map[string]string, len(step.Headers))
+		for name, value := range step.Headers {
+			if headers[name], err = renderTemplate(value, variables, noEscape); err != nil {
+				return nil, err
+			}
+		}
+		encoded, err := json.Marshal(headers)
+		if err != nil {
+			return nil, err
+		}
+		request.Headers = secrets.JSONText(encoded)
+	}
+
+	if step.Body != "" {
+		if request.Body, err = renderTemplate(step.Body, variables, jsonEscape); err != nil {
+			return nil, err
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(request.Body), &object); err != nil {
+			return nil, fmt.Errorf("body is not a JSON object: %v", err)
+		}
+	}
+
+	if len(step.JSONPathAssertions) > 0 {
+		encoded, err := json.Marshal(step.JSONPathAssertions)
+		if err != nil {
+			return nil, err
+		}
+		request.JSONPathAssertions = string(encoded)
+	}
+	return request, nil
+}
+
+// renderTemplate replaces the {{name}} placeholders of a template by the
+// escaped values of the variables
+func renderTemplate(template string, variables map[string]string, escape func(string) string) (string, error) {
+	var missing string
+	rendered := templateVariable.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templateVariable.FindStringSubmatch(placeholder)[1]
+		value, ok := variables[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return placeholder
+		}
+		return escape(value)
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable %q is not defined", missing)
+	}
+	return rendered, nil
+}
+
+// jsonEscape escapes a value for use inside a JSON string
+func jsonEscape(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded[1 : len(encoded)-1])
+}
+
+// extractsFromBody reports whether a step extracts variables from its response body
+func extractsFromBody(step types.TransactionStep) bool {
+	for _, v := range step.Extract {
+		if v.Source != "header" {
+			return true
+		}
+	}
+	return false
+}
+
+// extractVariables sets the variables a step extracts from its response
+func extractVariables(step types.TransactionStep, header http.Header, body []byte, variables map[string]string) error {
+	var doc interface{}
+	decoded := false
+	for _, v := range step.Extract {
+		if v.Source == "header" {
+			value := header.Get(v.Path)
+			if value == "" {
+				return fmt.Errorf("cannot extract %s: header %s is missing", v.Name, v.Path)
+			}
+			variables[v.Name] = value
+			continue
+		}
+
+		if !decoded {
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return fmt.Errorf("cannot extract %s: body is not valid JSON: %v", v.Name, err)
+			}
+			decoded = true
+		}
+		value, found, err := lookupJSONPath(doc, v.Path)
+		if err != nil {
+			return fmt.Errorf("cannot extract %s: JSONPath %s: %v", v.Name, v.Path, err)
+		}
+		if !found {
+			return fmt.Errorf("cannot extract %s: JSONPath %s does not exist", v.Name, v.Path)
+		}
+		variables[v.Name] = jsonValueString(value)
+	}
+	return nil
+}
+
+// ValidateTransactionSteps checks that a transaction monitor's steps are well
+// formed and only use variables extracted by earlier steps
+func ValidateTransactionSteps(monitor *types.Monitor) error {
+	steps, err := monitor.GetTransactionSteps()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if len(steps) == 0 {
+		return errors.New("at least one step is required")
+	}
+	if len(steps) > maxTransactionSteps {
+		return fmt.Errorf("at most %d steps are allowed", maxTransactionSteps)
+	}
+
+	// Validate against placeholder values, as the real ones are only known
+	// while the check runs
+	defined := make(map[string]string)
+	names := make(map[string]bool)
+	for i, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("step %d has no name", i+1)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("duplicate step name %q", step.Name)
+		}
+		names[step.Name] = true
+
+		request, err := stepMonitor(monitor, step, defined)
+		if err != nil {
+			return fmt.Errorf("step %q: %v", step.Name, err)
+		}
+		if !templateVariable.MatchString(step.Method) && !transactionMethods[request.Method] {
+			return fmt.Errorf("step %q: invalid HTTP method %s", step.Name, request.Method)
+		}
+		// A URL with variables is checked once they are known, when the step runs
+		if !templateVariable.MatchString(step.URL) {
+			if err := validateStepURL(request.URL); err != nil {
+				return fmt.Errorf("step %q: %v", step.Name, err)
+			}
+		}
+		if step.AcceptedStatusCodes != "" {
+			if err := ValidateStatusCodes(step.AcceptedStatusCodes); err != nil {
+				return fmt.Errorf("step %q: invalid accepted status codes: %v", step.Name, err)
+			}
+		}
+		if err := ValidateBodyAssertions(request); err != nil {
+			return fmt.Errorf("step %q: %v", step.Name, err)
+		}
+
+		for _, v := range step.Extract {
+			if !variableName.MatchString(v.Name) {
+				return fmt.Errorf("step %q: invalid variable name %q", step.Name, v.Name)
+			}
+			if v.Path == "" {
+				return fmt.Errorf("step %q: variable %s has no path", step.Name, v.Name)
+			}
+			switch v.Source {
+			case "header":
+			case "", "json":
+				if _, err := parseJSONPath(v.Path); err != nil {
+					return fmt.Errorf("step %q: invalid JSONPath %q: %v", step.Name, v.Path, err)
+				}
+			default:
+				return fmt.Errorf("step %q: invalid variable source %q", step.Name, v.Source)
+			}
+			defined[v.Name] = "0"
+		}
+	}
+	return nil
+}
+
+// validateStepURL checks that a step requests an http or https URL
+func validateStepURL(rawURL string) error {
+	stepURL, err := url.Parse(rawURL)
+	if err != nil || (stepURL.Scheme != "http" && stepURL.Scheme != "https") || stepURL.Host == "" {
+		return errors.New("URL must be an http or https URL")
+	}
+	return nil
+}
+
+// redactURL drops the user info, query string and fragment of a step URL, which
+// may carry extracted tokens, so that it can be shown in check messages
+func redactURL(rawURL string) string {
+	stepURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	stepURL.User = nil
+	stepURL.RawQuery = ""
+	stepURL.ForceQuery = false
+	stepURL.Fragment = ""
+	stepURL.RawFragment = ""
+	return stepURL.String()
+}
+
+// redactRequestError describes the error of a step's request with its URL
+// redacted
+func redactRequestError(err error) string {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err.Error()
+	}
+	redacted := *urlErr
+	redacted.URL = redactURL(urlErr.URL)
+	return redacted.Error()
+}
@@ -245,7 +245,7 @@ async function loadMonitors() {
                 
                 // Create a gradient card-like style for each monitor row
                 return `
-                    <tr style="transition: all 0.3s ease; border-bottom: 1px solid var(--border-color);">
+                    <tr id="monitor-${monitor.id}" style="transition: all 0.3s ease; border-bottom: 1px solid var(--border-color);">
                         <td style="padding: 1.25rem 1rem; background: linear-gradient(135deg, rgba(42, 42, 42, 0.5) 0%, rgba(42, 42, 42, 0.8) 100%);">
                             <div style="display: flex; align-items: center; margin-bottom: 0.5rem;">
                                 <strong style="font-size: 1.2rem; color: var(--text-secondary); background: var(--metallic-gold); -webkit-background-clip: text; -webkit-text-fill-color: transparent; font-weight: 700;">${monitor.name}</strong>
@@ -307,6 +307,8 @@ async function loadMonitors() {
             }).join('');
         }
 
+        focusLinkedMonitor();
+
         // Update monitor stats with enhanced styling
         const totalMonitors = document.getElementById('totalMonitors');
         const upMonitors = document.getElementById('upMonitors');
@@ -354,6 +356,19 @@ function hexToRgb(hex) {
     return `${r}, ${g}, ${b}`;
 }
 
+// Scroll to and highlight the monitor a notification linked to with ?monitor=,
+// once, as the monitor list is reloaded periodically
+let linkedMonitorFocused = false;
+function focusLinkedMonitor() {
+    const monitorId = new URLSearchParams(window.location.search).get('monitor');
+    const row = !linkedMonitorFocused && monitorId && document.getElementById(`monitor-${monitorId}`);
+    if (row) {
+        linkedMonitorFocused = true;
+        row.scrollIntoView({ behavior: 'smooth', block: 'center' });
+        row.style.outline = '2px solid var(--accent-color)';
+    }
+}
+
 async function loadProfiles() {
     try {
         const response = await fetch('/api/profiles');
@@ -372,7 +387,9 @@ async function loadProfiles() {
             `).join('')}
         `;
 
-        const activeProfile = profiles.find(p => p.is_active);
+        // A notification links to its monitor's profile with ?profile=
+        const linkedProfileId = new URLSearchParams(window.location.search).get('profile');
+        const activeProfile = profiles.find(p => p.id === linkedProfileId) || profiles.find(p => p.is_active);
         if (activeProfile) {
             currentProfileId = activeProfile.id;
             localStorage.setItem('profile_id', currentProfileId);
@@ -0,0 +1,33 @@
+// Session handling shared by all pages: send the user to the login page when
+// the session has expired, and sign out on request. Requests to this server
+// carry the selected profile in the X-Profile-ID header unless they set it.
+(function () {
+    const originalFetch = window.fetch;
+    window.fetch = async function (input, init) {
+        const profileId = localStorage.getItem('profile_id');
+        const url = new URL(input instanceof Request ? input.url : input, window.location.href);
+        if (profileId && url.origin === window.location.origin) {
+            const headers = new Headers((init && init.headers) || (input instanceof Request ? input.headers : undefined));
+            if (!headers.has('X-Profile-ID')) {
+                headers.set('X-Profile-ID', profileId);
+                init = Object.assign({}, init, { headers });
+            }
+        }
+
+        const response = await originalFetch.call(this, input, init);
+        if (response.status === 401 && window.location.pathname !== '/login') {
+            const next = encodeURIComponent(window.location.pathname);
+            window.location.href = '/login?next=' + next;
+        }
+        return response;
+    };
+})();
+
+async function logout() {
+    try {
+        await fetch('/api/auth/logout', { method: 'POST' });
+    } finally {
+        localStorage.removeItem('profile_id');
+        window.location.href = '/login';
+    }
+}
@@ -0,0 +1,293 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"gorm.io/gorm"
+)
+
+// escalationPollInterval is how often escalations are checked for due levels
+const escalationPollInterval = 15 * time.Second
+
+// EscalationWorker notifies the levels of escalation policies while outages
+// go unacknowledged. Escalations are stored, so after a restart the worker
+// carries on where it left off, notifying the levels that came due meanwhile.
+type EscalationWorker struct {
+	escalations *repository.EscalationRepository
+	incidents   *repository.IncidentRepository
+	monitors    *repository.MonitorRepository
+	profiles    *repository.ProfileRepository
+	dispatcher  *NotificationDispatcher
+	wake        chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewEscalationWorker(escalations *repository.EscalationRepository, incidents *repository.IncidentRepository, monitors *repository.MonitorRepository, profiles *repository.ProfileRepository, dispatcher *NotificationDispatcher) *EscalationWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EscalationWorker{
+		escalations: escalations,
+		incidents:   incidents,
+		monitors:    monitors,
+		profiles:    profiles,
+		dispatcher:  dispatcher,
+		wake:        make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start launches the loop that notifies due escalation levels
+func (w *EscalationWorker) Start() {
+	log.Println("⏫ ESCALATION: Starting escalation worker")
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+}
+
+// Stop stops the escalation loop
+func (w *EscalationWorker) Stop() {
+	log.Println("ESCALATION: Stopping escalation worker")
+	w.cancel()
+	w.wg.Wait()
+}
+
+// Escalate starts the escalation policy of a monitor for its incident, unless
+// the incident already has an escalation. It returns whether the escalation is
+// active: if the monitor has no policy, or its escalation completed or was
+// stopped, the outage is notified through the routing rules, so an outage that
+// outlasts every level keeps being repeated with the usual backoff.
+func (w *EscalationWorker) Escalate(monitor *types.Monitor, incident *types.Incident) bool {
+	policy, err := w.escalations.GetMonitorPolicy(monitor)
+	if err != nil {
+		log.Printf("❌ ESCALATION: Error loading escalation policy of %s: %v", monitor.Name, err)
+		return false
+	}
+	if policy == nil || len(policy.Levels) == 0 {
+		return false
+	}
+
+	escalation, started, err := w.escalations.StartEscalation(&types.Escalation{
+		ProfileID:   monitor.ProfileID,
+		PolicyID:    policy.ID,
+		IncidentID:  incident.ID,
+		MonitorID:   monitor.ID,
+		NextLevel:   0,
+		NextLevelAt: incident.StartedAt.Add(time.Duration(policy.Levels[0].DelayMinutes) * time.Minute),
+	})
+	if err != nil {
+		// Notify through the routing rules rather than not at all
+		log.Printf("❌ ESCALATION: Error starting escalation of incident %s: %v", incident.ID, err)
+		return false
+	}
+	if started {
+		log.Printf("⏫ ESCALATION: Escalating incident %s of %s with policy %q (%d levels)",
+			incident.ID, monitor.Name, policy.Name, len(policy.Levels))
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	} else {
+		log.Printf("  Incident %s is handled by escalation %s (%s)", incident.ID, escalation.ID, escalation.Status)
+	}
+	return escalation.Status == types.EscalationStatusActive
+}
+
+// Resolve stops the escalation of a recovered incident and returns the methods
+// its levels notified, which are told about the recovery as well
+func (w *EscalationWorker) Resolve(incident *types.Incident) []string {
+	escalation, err := w.escalations.GetIncidentEscalation(incident.ID)
+	if err != nil {
+		log.Printf("❌ ESCALATION: Error loading escalation of incident %s: %v", incident.ID, err)
+		return nil
+	}
+	if escalation == nil {
+		return nil
+	}
+	if escalation.Status == types.EscalationStatusActive {
+		w.stop(escalation, "resolved")
+	}
+	return types.SplitList(escalation.NotifiedMethodIDs)
+}
+
+// run advances the active escalations until the worker is stopped
+func (w *EscalationWorker) run() {
+	ticker := time.NewTicker(escalationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		escalations, err := w.escalations.GetActiveEscalations()
+		if err != nil {
+			log.Printf("❌ ESCALATION: Error loading active escalations: %v", err)
+		}
+		for i := range escalations {
+			w.advance(&escalations[i])
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// advance stops an escalation whose incident was acknowledged or resolved, and
+// otherwise notifies its next level once it is due
+func (w *EscalationWorker) advance(escalation *types.Escalation) {
+	incident, err := w.incidents.GetIncidentByID(escalation.ProfileID, escalation.IncidentID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		w.stop(escalation, "incident deleted")
+		return
+	case err != nil:
+		log.Printf("❌ ESCALATION: Error loading incident %s: %v", escalation.IncidentID, err)
+		return
+	case incident.Status == types.IncidentStatusResolved:
+		w.stop(escalation, "resolved")
+		return
+	case incident.IsAcknowledged():
+		w.stop(escalation, "acknowledged by "+incident.AcknowledgedBy)
+		return
+	}
+	if escalation.NextLevelAt.After(time.Now()) {
+		return
+	}
+
+	policy, err := w.escalations.GetPolicyByID(escalation.ProfileID, escalation.PolicyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.stop(escalation, "policy deleted")
+		return
+	}
+	if err != nil {
+		log.Printf("❌ ESCALATION: Error loading escalation policy %s: %v", escalation.PolicyID, err)
+		return
+	}
+	if escalation.NextLevel >= len(policy.Levels) {
+		// The policy lost levels since the last one was notified
+		w.complete(escalation)
+		return
+	}
+
+	monitor, err := w.monitors.GetMonitorByID(escalation.ProfileID, escalation.MonitorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.stop(escalation, "monitor deleted")
+		return
+	}
+	if err != nil {
+		log.Printf("❌ ESCALATION: Error loading monitor %s: %v", escalation.MonitorID, err)
+		return
+	}
+	if monitor.Status == "maintenance" {
+		// Maintenance holds back notifications; the level is notified once it ends
+		return
+	}
+
+	level := policy.Levels[escalation.NextLevel]
+	if err := w.notifyLevel(escalation, policy, level, monitor, incident); err != nil {
+		log.Printf("❌ ESCALATION: Error notifying level %d of incident %s, retrying: %v",
+			level.Position+1, incident.ID, err)
+		return
+	}
+
+	escalation.NextLevel++
+	if escalation.NextLevel >= len(policy.Levels) {
+		w.complete(escalation)
+		return
+	}
+	next := policy.Levels[escalation.NextLevel]
+	escalation.NextLevelAt = incident.StartedAt.Add(time.Duration(next.DelayMinutes) * time.Minute)
+	if err := w.escalations.SaveEscalation(escalation); err != nil {
+		log.Printf("❌ ESCALATION: Error saving escalation %s: %v", escalation.ID, err)
+	}
+}
+
+// notifyLevel queues the outage notification for the enabled methods of a level
+// and records it in the incident's timeline
+func (w *EscalationWorker) notifyLevel(escalation *types.Escalation, policy *types.EscalationPolicy, level types.EscalationLevel, monitor *types.Monitor, incident *types.Incident) error {
+	methods, err := w.profiles.GetNotificationMethods(escalation.ProfileID)
+	if err != nil {
+		return err
+	}
+	levelMethods := types.SplitList(level.MethodIDs)
+	var targets []types.NotificationMethod
+	var names []string
+	for _, method := range methods {
+		if method.Enabled && containsID(levelMethods, method.ID) {
+			targets = append(targets, method)
+			names = append(names, method.Type)
+		}
+	}
+
+	now := time.Now()
+	status := monitor.Status
+	if status != "down" && status != "unauthorized" {
+		status = "down"
+	}
+	n := &services.Notification{
+		Monitor: monitor,
+		Status:  status,
+		Message: fmt.Sprintf("Unacknowledged for %s, escalated to level %d of %q. %s",
+			now.Sub(incident.StartedAt).Round(time.Minute), level.Position+1, policy.Name, incident.Cause),
+		Time:              now,
+		IncidentStartedAt: incident.StartedAt,
+	}
+	if err := w.dispatcher.Enqueue(n, targets); err != nil {
+		return err
+	}
+
+	notified := types.SplitList(escalation.NotifiedMethodIDs)
+	for _, method := range targets {
+		if !containsID(notified, method.ID) {
+			notified = append(notified, method.ID)
+		}
+	}
+	escalation.NotifiedMethodIDs = strings.Join(notified, ",")
+
+	message := fmt.Sprintf("Escalated to level %d of %q: %s", level.Position+1, policy.Name, strings.Join(names, ", "))
+	if len(targets) == 0 {
+		message = fmt.Sprintf("Escalated to level %d of %q, which has no enabled notification methods", level.Position+1, policy.Name)
+	}
+	log.Printf("⏫ ESCALATION: %s for %s", message, monitor.Name)
+	if err := w.incidents.RecordEscalation(incident.ID, message); err != nil {
+		log.Printf("❌ ESCALATION: Error recording escalation of incident %s: %v", incident.ID, err)
+	}
+	return nil
+}
+
+func (w *EscalationWorker) stop(escalation *types.Escalation, reason string) {
+	log.Printf("⏹️ ESCALATION: Stopping escalation of incident %s: %s", escalation.IncidentID, reason)
+	if err := w.escalations.StopEscalation(escalation, reason); err != nil {
+		log.Printf("❌ ESCALATION: Error stopping escalation %s: %v", escalation.ID, err)
+	}
+}
+
+func (w *EscalationWorker) complete(escalation *types.Escalation) {
+	log.Printf("✅ ESCALATION: Every level of the escalation of incident %s was notified", escalation.IncidentID)
+	escalation.Status = types.EscalationStatusCompleted
+	if err := w.escalations.SaveEscalation(escalation); err != nil {
+		log.Printf("❌ ESCALATION: Error saving escalation %s: %v", escalation.ID, err)
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package tasks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/types"
+)
+
+func TestEscalationWorker(t *testing.T) {
+	db := newTestDB(t)
+	profiles := repository.NewProfileRepository(db)
+	incidents := repository.NewIncidentRepository(db)
+	monitors := repository.NewMonitorRepository(db)
+	escalations := repository.NewEscalationRepository(db)
+	w := NewEscalationWorker(escalations, incidents, monitors, profiles, newTestDispatcher(db, &stubNotifier{}))
+
+	for _, id := range []string{"on-call", "lead", "off"} {
+		method := types.NotificationMethod{ID: id, ProfileID: "p1", Type: "webhook", Enabled: id != "off"}
+		if err := profiles.CreateNotificationMethod(&method); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := &types.EscalationPolicy{Name: "outages", Levels: []types.EscalationLevel{
+		{DelayMinutes: 0, MethodIDs: "on-call"},
+		{DelayMinutes: 30, MethodIDs: "lead,off"},
+	}}
+	if err := escalations.CreatePolicy("p1", policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// newOutage creates a down monitor with an incident that started ten minutes ago
+	newOutage := func(id, policyID string) (*types.Monitor, *types.Incident) {
+		t.Helper()
+		monitor := &types.Monitor{ID: id, ProfileID: "p1", Name: id, Status: "down", EscalationPolicyID: policyID}
+		if err := monitors.CreateMonitor(monitor); err != nil {
+			t.Fatal(err)
+		}
+		incident, err := incidents.OpenIncident(monitor, "timeout", time.Now().Add(-10*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return monitor, incident
+	}
+	// advance runs the worker over the stored escalation of an incident
+	advance := func(incident *types.Incident) *types.Escalation {
+		t.Helper()
+		escalation, err := escalations.GetIncidentEscalation(incident.ID)
+		if err != nil || escalation == nil {
+			t.Fatalf("GetIncidentEscalation() = %v, %v", escalation, err)
+		}
+		w.advance(escalation)
+		escalation, _ = escalations.GetIncidentEscalation(incident.ID)
+		return escalation
+	}
+	// notified returns the methods queued a notification about a monitor, in order
+	notified := func(monitorID string) string {
+		var deliveries []types.NotificationDelivery
+		db.Where("monitor_id = ?", monitorID).Order("created_at, method_id").Find(&deliveries)
+		var methods []string
+		for _, delivery := range deliveries {
+			methods = append(methods, delivery.MethodID)
+		}
+		return strings.Join(methods, ",")
+	}
+
+	t.Run("levels are notified when due", func(t *testing.T) {
+		monitor, incident := newOutage("api", policy.ID)
+		if !w.Escalate(monitor, incident) {
+			t.Fatal("Escalate() = false for a monitor with a policy")
+		}
+		if !w.Escalate(monitor, incident) {
+			t.Fatal("Escalate() = false for an incident that is escalating")
+		}
+
+		escalation := advance(incident)
+		if got := notified("api"); got != "on-call" {
+			t.Fatalf("first level notified %q, want on-call", got)
+		}
+		if escalation.NextLevel != 1 || !escalation.NextLevelAt.Equal(incident.StartedAt.Add(30*time.Minute)) {
+			t.Errorf("next level %d at %s, want 1 at %s", escalation.NextLevel, escalation.NextLevelAt, incident.StartedAt.Add(30*time.Minute))
+		}
+
+		// The second level is only due half an hour into the outage
+		advance(incident)
+		if got := notified("api"); got != "on-call" {
+			t.Fatalf("second level notified early: %q", got)
+		}
+
+		escalation.NextLevelAt = time.Now().Add(-time.Second)
+		if err := escalations.SaveEscalation(escalation); err != nil {
+			t.Fatal(err)
+		}
+		escalation = advance(incident)
+		if got := notified("api"); got != "on-call,lead" {
+			t.Fatalf("notified %q, want on-call then lead", got)
+		}
+		if escalation.Status != types.EscalationStatusCompleted || escalation.NotifiedMethodIDs != "on-call,lead" {
+			t.Errorf("escalation %s notified %q, want completed after on-call and lead", escalation.Status, escalation.NotifiedMethodIDs)
+		}
+
+		// Once every level was notified the outage is repeated through the routing rules
+		if w.Escalate(monitor, incident) {
+			t.Error("Escalate() = true for a completed escalation")
+		}
+		if got := w.Resolve(incident); !reflect.DeepEqual(got, []string{"on-call", "lead"}) {
+			t.Errorf("Resolve() = %v, want the methods every level notified", got)
+		}
+
+		var events int64
+		db.Model(&types.IncidentEvent{}).Where("incident_id = ? AND type = ?", incident.ID, types.IncidentEventEscalated).Count(&events)
+		if events != 2 {
+			t.Errorf("%d escalations in the incident timeline, want 2", events)
+		}
+	})
+
+	t.Run("acknowledged incidents stop escalating", func(t *testing.T) {
+		monitor, incident := newOutage("db", policy.ID)
+		if !w.Escalate(monitor, incident) {
+			t.Fatal("Escalate() = false for a monitor with a policy")
+		}
+		if err := incidents.Acknowledge(incident, "alice", "looking"); err != nil {
+			t.Fatal(err)
+		}
+		escalation := advance(incident)
+		if escalation.Status != types.EscalationStatusStopped || escalation.StopReason != "acknowledged by alice" {
+			t.Errorf("escalation %s: %s, want stopped when acknowledged", escalation.Status, escalation.StopReason)
+		}
+		if got := notified("db"); got != "" {
+			t.Errorf("notified %q after the incident was acknowledged", got)
+		}
+	})
+
+	t.Run("resolved incidents stop escalating", func(t *testing.T) {
+		monitor, incident := newOutage("web", policy.ID)
+		if !w.Escalate(monitor, incident) {
+			t.Fatal("Escalate() = false for a monitor with a policy")
+		}
+		advance(incident)
+		if got := w.Resolve(incident); !reflect.DeepEqual(got, []string{"on-call"}) {
+			t.Errorf("Resolve() = %v, want the first level's methods", got)
+		}
+		escalation, _ := escalations.GetIncidentEscalation(incident.ID)
+		if escalation.Status != types.EscalationStatusStopped || escalation.StopReason != "resolved" {
+			t.Errorf("escalation %s: %s, want stopped when resolved", escalation.Status, escalation.StopReason)
+		}
+	})
+
+	t.Run("monitors without a policy are not escalated", func(t *testing.T) {
+		monitor, incident := newOutage("batch", "")
+		if w.Escalate(monitor, incident) {
+			t.Error("Escalate() = true without a policy")
+		}
+		if escalation, _ := escalations.GetIncidentEscalation(incident.ID); escalation != nil {
+			t.Errorf("Escalate() stored %v without a policy", escalation)
+		}
+	})
+}
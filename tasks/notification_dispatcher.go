@@ -0,0 +1,259 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultDeliveryAttempts is how many times a notification is tried when its
+	// method doesn't set max_attempts
+	DefaultDeliveryAttempts = 6
+
+	dispatchWorkers      = 4                // Notifications sent at the same time
+	dispatchPollInterval = 5 * time.Second  // How often the outbox is checked for due notifications
+	retryBaseDelay       = 30 * time.Second // Delay before the first retry, doubled on every retry
+	retryMaxDelay        = time.Hour        // Longest delay between two attempts
+	staleClaimTimeout    = 5 * time.Minute  // Claimed notifications not recorded by then are requeued
+)
+
+// NotificationDispatcher delivers the notifications queued in the outbox with
+// a pool of workers. Failed attempts are retried with exponential backoff
+// until the method's attempts run out, after which the notification is dead
+// and only sent again when someone resends it.
+type NotificationDispatcher struct {
+	notifiers  *services.NotifierRegistry
+	deliveries *repository.DeliveryRepository
+	profiles   *repository.ProfileRepository
+	wake       chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func NewNotificationDispatcher(notifiers *services.NotifierRegistry, deliveries *repository.DeliveryRepository, profiles *repository.ProfileRepository) *NotificationDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NotificationDispatcher{
+		notifiers:  notifiers,
+		deliveries: deliveries,
+		profiles:   profiles,
+		wake:       make(chan struct{}, 1),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start launches the workers and the loop that hands them due notifications
+func (d *NotificationDispatcher) Start() {
+	log.Printf("📬 DISPATCHER: Starting notification delivery with %d workers", dispatchWorkers)
+
+	jobs := make(chan types.NotificationDelivery)
+	for i := 0; i < dispatchWorkers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for delivery := range jobs {
+				d.deliver(&delivery)
+			}
+		}()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer close(jobs)
+		d.run(jobs)
+	}()
+}
+
+// Stop stops claiming notifications and waits for the ones being sent
+func (d *NotificationDispatcher) Stop() {
+	log.Println("DISPATCHER: Stopping notification delivery")
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Enqueue queues a notification for each of the given methods, as picked by
+// services.RouteNotification
+func (d *NotificationDispatcher) Enqueue(n *services.Notification, methods []types.NotificationMethod) error {
+	monitor := n.Monitor
+	var incidentStartedAt *time.Time
+	if !n.IncidentStartedAt.IsZero() {
+		incidentStartedAt = &n.IncidentStartedAt
+	}
+
+	deliveries := make([]types.NotificationDelivery, 0, len(methods))
+	for _, method := range methods {
+		maxAttempts := method.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = DefaultDeliveryAttempts
+		}
+		deliveries = append(deliveries, types.NotificationDelivery{
+			ProfileID:         monitor.ProfileID,
+			MethodID:          method.ID,
+			MethodType:        method.Type,
+			MonitorID:         monitor.ID,
+			MonitorName:       monitor.Name,
+			MonitorType:       monitor.Type,
+			URL:               monitor.URL,
+			Hostname:          monitor.Hostname,
+			DBHost:            monitor.DBHost,
+			Tags:              monitor.Tags,
+			Severity:          monitor.Severity,
+			ResponseCode:      monitor.ResponseCode,
+			ResponseTime:      monitor.ResponseTime,
+			Event:             n.Status,
+			PreviousStatus:    n.PreviousStatus,
+			Message:           n.Message,
+			EventTime:         n.Time,
+			IncidentStartedAt: incidentStartedAt,
+			MaxAttempts:       maxAttempts,
+		})
+	}
+	if err := d.deliveries.Enqueue(deliveries); err != nil {
+		return err
+	}
+
+	// Wake the dispatch loop instead of waiting for its next poll
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run claims due notifications and hands them to the workers until the
+// dispatcher is stopped
+func (d *NotificationDispatcher) run(jobs chan<- types.NotificationDelivery) {
+	ticker := time.NewTicker(dispatchPollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		if released, err := d.deliveries.ReleaseStale(time.Now().Add(-staleClaimTimeout)); err != nil {
+			log.Printf("❌ DISPATCHER: Error requeueing stale notifications: %v", err)
+		} else if released > 0 {
+			log.Printf("⚠️ DISPATCHER: Requeued %d notifications whose delivery was interrupted", released)
+		}
+		if time.Since(lastPrune) > time.Hour {
+			if err := d.deliveries.Prune(); err != nil {
+				log.Printf("❌ DISPATCHER: Error pruning the delivery log: %v", err)
+			}
+			lastPrune = time.Now()
+		}
+
+		// Claim a batch at a time, and come back for more while the outbox has due notifications
+		for {
+			due, err := d.deliveries.ClaimDue(dispatchWorkers)
+			if err != nil {
+				log.Printf("❌ DISPATCHER: Error claiming notifications: %v", err)
+			}
+			for _, delivery := range due {
+				select {
+				case jobs <- delivery:
+				case <-d.ctx.Done():
+					// Left claimed, so it is requeued once the claim is stale
+					return
+				}
+			}
+			if len(due) < dispatchWorkers {
+				break
+			}
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliver makes one attempt to send a notification and records its outcome
+func (d *NotificationDispatcher) deliver(delivery *types.NotificationDelivery) {
+	started := time.Now()
+	err := d.send(delivery)
+	retryable := !errors.Is(err, errMethodDeleted) && services.IsRetryable(err)
+	attempt := &types.NotificationAttempt{
+		Attempt:  delivery.Attempts + 1,
+		Success:  err == nil,
+		Duration: time.Since(started).Milliseconds(),
+	}
+
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = types.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		log.Printf("📨 DISPATCHER: Delivered %s notification for %s through %s (attempt %d)",
+			delivery.Event, delivery.MonitorName, delivery.MethodType, delivery.Attempts)
+	case retryable && delivery.Attempts < delivery.MaxAttempts:
+		attempt.Error = err.Error()
+		delivery.Status = types.DeliveryStatusPending
+		delivery.LastError = attempt.Error
+		delivery.NextAttemptAt = time.Now().Add(retryDelay(delivery.Attempts))
+		log.Printf("⚠️ DISPATCHER: Attempt %d/%d of %s notification for %s through %s failed, retrying at %s: %v",
+			delivery.Attempts, delivery.MaxAttempts, delivery.Event, delivery.MonitorName, delivery.MethodType,
+			delivery.NextAttemptAt.Format(time.RFC3339), err)
+	default:
+		attempt.Error = err.Error()
+		delivery.Status = types.DeliveryStatusDead
+		delivery.LastError = attempt.Error
+		log.Printf("❌ DISPATCHER: Giving up on %s notification for %s through %s after %d attempts: %v",
+			delivery.Event, delivery.MonitorName, delivery.MethodType, delivery.Attempts, err)
+	}
+
+	if err := d.deliveries.RecordAttempt(delivery, attempt); err != nil {
+		log.Printf("❌ DISPATCHER: Error recording delivery attempt for %s: %v", delivery.ID, err)
+	}
+}
+
+// errMethodDeleted fails the notifications queued for a deleted method
+var errMethodDeleted = errors.New("notification method was deleted")
+
+// send delivers a notification through its method as currently configured
+func (d *NotificationDispatcher) send(delivery *types.NotificationDelivery) error {
+	method, err := d.profiles.GetNotificationMethod(delivery.ProfileID, delivery.MethodID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errMethodDeleted
+	}
+	if err != nil {
+		return err
+	}
+
+	n := &services.Notification{
+		Monitor:        delivery.Monitor(),
+		Status:         delivery.Event,
+		PreviousStatus: delivery.PreviousStatus,
+		Message:        delivery.Message,
+		Time:           delivery.EventTime,
+	}
+	if delivery.IncidentStartedAt != nil {
+		n.IncidentStartedAt = *delivery.IncidentStartedAt
+	}
+	return d.notifiers.Send(method, n)
+}
+
+// retryDelay returns the backoff before the attempt after the given number of
+// failed attempts
+func retryDelay(failed int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < failed && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,198 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"uptime-monitor/models"
+	"uptime-monitor/repository"
+	"uptime-monitor/secrets"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens a fresh database with the schema config.InitConfig migrates
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	keyring, err := secrets.NewKeyring(bytes.Repeat([]byte{1}, secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := secrets.Default()
+	secrets.SetDefault(keyring)
+	t.Cleanup(func() { secrets.SetDefault(previous) })
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&types.Profile{}, &types.ProfileMembership{}, &types.Monitor{}, &types.Log{},
+		&types.SMTPSettings{}, &types.NotificationSettings{}, &types.NotificationMethod{},
+		&types.NotificationDelivery{}, &types.NotificationAttempt{}, &types.NotificationRule{},
+		&types.EscalationPolicy{}, &types.EscalationLevel{}, &types.Escalation{},
+		&types.Incident{}, &types.IncidentEvent{}, &types.MaintenanceWindow{},
+		&types.StatusPage{}, &types.StatusPageComponent{},
+		&models.User{}, &models.Session{}, &models.APIToken{}, &models.APITokenUsage{},
+		&services.Credential{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// stubNotifier records the notifications it is asked to send and fails them
+// with the queued errors, one per call
+type stubNotifier struct {
+	mu   sync.Mutex
+	errs []error
+	sent []*services.Notification
+}
+
+func (s *stubNotifier) Validate(json.RawMessage) error { return nil }
+
+func (s *stubNotifier) Send(_ context.Context, n *services.Notification, _ json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, n)
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+// newTestDispatcher creates a dispatcher on db that sends webhook
+// notifications through notifier, without starting it
+func newTestDispatcher(db *gorm.DB, notifier services.Notifier) *NotificationDispatcher {
+	notifiers := services.NewNotifierRegistry()
+	notifiers.Register("webhook", notifier)
+	return NewNotificationDispatcher(notifiers, repository.NewDeliveryRepository(db), repository.NewProfileRepository(db))
+}
+
+func TestNotificationDispatcherDeliver(t *testing.T) {
+	unavailable := &services.HTTPStatusError{StatusCode: 503}
+	rejected := &services.HTTPStatusError{StatusCode: 400}
+	pending := types.DeliveryStatusPending
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		errs         []error
+		deleted      bool
+		wantStatus   []string // Status after each attempt
+		wantAttempts int
+	}{
+		{"delivered", 3, nil, false, []string{types.DeliveryStatusDelivered}, 1},
+		{"delivered on retry", 3, []error{unavailable}, false, []string{pending, types.DeliveryStatusDelivered}, 2},
+		{"dead after the last attempt", 3, []error{unavailable, errors.New("connection refused"), unavailable},
+			false, []string{pending, pending, types.DeliveryStatusDead}, 3},
+		{"permanent error is not retried", 3, []error{rejected}, false, []string{types.DeliveryStatusDead}, 1},
+		{"deleted method is not retried", 3, nil, true, []string{types.DeliveryStatusDead}, 1},
+		{"default attempts", 0, []error{unavailable, unavailable, unavailable, unavailable, unavailable, unavailable},
+			false, []string{pending, pending, pending, pending, pending, types.DeliveryStatusDead}, DefaultDeliveryAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			notifier := &stubNotifier{errs: tt.errs}
+			d := newTestDispatcher(db, notifier)
+			method := types.NotificationMethod{ID: "hook", ProfileID: "p1", Type: "webhook", Enabled: true, MaxAttempts: tt.maxAttempts}
+			if err := d.profiles.CreateNotificationMethod(&method); err != nil {
+				t.Fatal(err)
+			}
+
+			monitor := &types.Monitor{ID: "api", ProfileID: "p1", Name: "api"}
+			n := &services.Notification{Monitor: monitor, Status: "down", PreviousStatus: "up", Message: "timeout", Time: time.Now()}
+			if err := d.Enqueue(n, []types.NotificationMethod{method}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.deleted {
+				if err := d.profiles.DeleteNotificationMethod("p1", "hook"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i, wantStatus := range tt.wantStatus {
+				due, err := d.deliveries.ClaimDue(dispatchWorkers)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(due) != 1 {
+					t.Fatalf("attempt %d: ClaimDue() = %d notifications, want 1", i+1, len(due))
+				}
+				before := time.Now()
+				d.deliver(&due[0])
+
+				stored, err := d.deliveries.GetDeliveryByID("p1", due[0].ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stored.Status != wantStatus || stored.Attempts != i+1 {
+					t.Fatalf("attempt %d: status = %s after %d attempts, want %s", i+1, stored.Status, stored.Attempts, wantStatus)
+				}
+				if wantStatus != types.DeliveryStatusPending {
+					break
+				}
+
+				// A retry waits for its backoff
+				if delay := stored.NextAttemptAt.Sub(before); delay < retryDelay(i+1) || delay > retryDelay(i+1)+time.Minute {
+					t.Errorf("attempt %d: retried after %v, want %v", i+1, delay, retryDelay(i+1))
+				}
+				if stored.LastError == "" {
+					t.Errorf("attempt %d: failure was not recorded", i+1)
+				}
+				if due, _ := d.deliveries.ClaimDue(dispatchWorkers); len(due) != 0 {
+					t.Fatalf("attempt %d: ClaimDue() returned a notification before its retry was due", i+1)
+				}
+				if err := db.Model(&types.NotificationDelivery{}).Where("id = ?", stored.ID).
+					Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var attempts int64
+			db.Model(&types.NotificationAttempt{}).Count(&attempts)
+			if attempts != int64(tt.wantAttempts) {
+				t.Errorf("%d attempts recorded, want %d", attempts, tt.wantAttempts)
+			}
+			if due, _ := d.deliveries.ClaimDue(dispatchWorkers); len(due) != 0 {
+				t.Errorf("ClaimDue() = %v after the last attempt", due)
+			}
+			for _, sent := range notifier.sent {
+				if sent.Status != "down" || sent.Monitor.Name != "api" || sent.Message != "timeout" {
+					t.Errorf("sent %s notification for %s: %s, want the queued one", sent.Status, sent.Monitor.Name, sent.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.failed); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.failed, got, tt.want)
+		}
+	}
+}
@@ -1,59 +1,114 @@
 package tasks
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
-	"net/smtp"
+	"os"
+	"strings"
 	"sync"
 	"time"
-	"uptime-monitor/config"
 	"uptime-monitor/repository"
 	"uptime-monitor/services"
 	"uptime-monitor/types"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 var (
-	monitorStatusMap = make(map[string]string)    // Track previous status by monitor ID
-	lastNotifiedMap  = make(map[string]time.Time) // Track last notification time for each monitor
-	statusMutex      = sync.RWMutex{}
-	notifyMutex      = sync.RWMutex{}
+	lastNotifiedMap = make(map[string]time.Time) // Track last notification time for each monitor
+	notifyMutex     = sync.RWMutex{}
 )
 
 type Scheduler struct {
-	monitors    []*types.Monitor
-	mu          sync.RWMutex
-	credentials *services.CredentialsService
+	workers     map[string]*monitorWorker
+	mu          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	checkers    *services.CheckerRegistry
+	dispatcher  *NotificationDispatcher
+	escalations *EscalationWorker
 	monitorRepo *repository.MonitorRepository
 	logRepo     *repository.LogRepository
+	incidents   *repository.IncidentRepository
+	maintenance *repository.MaintenanceRepository
+	profiles    *repository.ProfileRepository
+	rules       *repository.NotificationRuleRepository
+	location    string // Check location recorded on each log, from HEIMDALL_LOCATION
+	worker      string // Host name of this scheduler instance
 }
 
-func NewScheduler(credentials *services.CredentialsService, monitorRepo *repository.MonitorRepository, logRepo *repository.LogRepository) *Scheduler {
+// monitorWorker is a running check loop for a single monitor. The worker is
+// cancelled and restarted whenever the stored monitor configuration changes.
+type monitorWorker struct {
+	monitor    *types.Monitor
+	configHash string
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func NewScheduler(checkers *services.CheckerRegistry, dispatcher *NotificationDispatcher, escalations *EscalationWorker, monitorRepo *repository.MonitorRepository, logRepo *repository.LogRepository, incidents *repository.IncidentRepository, maintenance *repository.MaintenanceRepository, profiles *repository.ProfileRepository, rules *repository.NotificationRuleRepository) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
-		monitors:    make([]*types.Monitor, 0),
-		credentials: credentials,
+		workers:     make(map[string]*monitorWorker),
+		ctx:         ctx,
+		cancel:      cancel,
+		checkers:    checkers,
+		dispatcher:  dispatcher,
+		escalations: escalations,
 		monitorRepo: monitorRepo,
 		logRepo:     logRepo,
+		incidents:   incidents,
+		maintenance: maintenance,
+		profiles:    profiles,
+		rules:       rules,
+		location:    checkLocation(),
+		worker:      workerName(),
+	}
+}
+
+// checkLocation returns the location this instance checks from
+func checkLocation() string {
+	if location := os.Getenv("HEIMDALL_LOCATION"); location != "" {
+		return location
 	}
+	return "default"
 }
 
+// workerName identifies this scheduler instance in the check history
+func workerName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// AddMonitor starts checking a monitor, restarting its worker if one is already running
 func (s *Scheduler) AddMonitor(monitor *types.Monitor) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.monitors = append(s.monitors, monitor)
+	previous := s.workers[monitor.ID]
+	if previous != nil {
+		s.stopWorker(previous)
+	}
+	s.startWorker(monitor, previous)
 }
 
+// RemoveMonitor stops checking a monitor and waits for its worker to exit
 func (s *Scheduler) RemoveMonitor(monitorID string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	for i, m := range s.monitors {
-		if m.ID == monitorID {
-			s.monitors = append(s.monitors[:i], s.monitors[i+1:]...)
-			break
-		}
+	w, exists := s.workers[monitorID]
+	if exists {
+		s.removeWorker(w)
+	}
+	s.mu.Unlock()
+
+	if exists {
+		<-w.done
 	}
 }
 
@@ -70,14 +125,34 @@ func (s *Scheduler) Start() {
 	log.Println("✅ SCHEDULER: Monitor Checking System Initialized Successfully")
 }
 
+// Stop cancels every monitor worker and waits for them to exit
+func (s *Scheduler) Stop() {
+	log.Println("SCHEDULER: Stopping all monitor workers")
+	s.cancel()
+
+	s.mu.Lock()
+	stopped := make([]*monitorWorker, 0, len(s.workers))
+	for _, w := range s.workers {
+		s.stopWorker(w)
+		stopped = append(stopped, w)
+	}
+	s.mu.Unlock()
+
+	for _, w := range stopped {
+		<-w.done
+	}
+}
+
 // periodicMonitorReload reloads monitors from the database periodically
 func (s *Scheduler) periodicMonitorReload() {
-	// Reload monitors every minute to ensure deleted monitors are removed quickly
+	// Reload monitors every minute so edits, pauses and deletions are picked up quickly
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-s.ctx.Done():
+			return
 		case <-ticker.C:
 			log.Println("SCHEDULER: Performing periodic reload of monitors from database")
 			s.reloadMonitors()
@@ -85,11 +160,13 @@ func (s *Scheduler) periodicMonitorReload() {
 	}
 }
 
-// reloadMonitors loads monitors from the repository and updates the scheduler's monitor list
+// reloadMonitors reconciles the running workers with the monitors stored in the
+// repository: new monitors are started, deleted or paused monitors are stopped and
+// monitors whose configuration changed are restarted with the new configuration.
 func (s *Scheduler) reloadMonitors() {
-	// Load monitors from repository
+	// Load monitors of every profile; profiles only scope API requests
 	log.Println("SCHEDULER: Loading monitors from repository...")
-	monitors, err := s.monitorRepo.GetAllMonitors()
+	monitors, err := s.monitorRepo.GetAllMonitorsAcrossProfiles()
 	if err != nil {
 		log.Printf("❌ CRITICAL: Error loading monitors: %v", err)
 		return
@@ -97,158 +174,160 @@ func (s *Scheduler) reloadMonitors() {
 
 	// Log total number of monitors
 	log.Printf("📊 SCHEDULER: Total Monitors Loaded: %d", len(monitors))
-
-	// Create a map of database monitors by ID for quick lookup
-	dbMonitors := make(map[string]bool)
-	for _, m := range monitors {
-		dbMonitors[m.ID] = true
-	}
-
-	// Check if no monitors are found
 	if len(monitors) == 0 {
 		log.Println("⚠️ WARNING: No monitors found. Please create monitors.")
-
-		// Clear existing monitors in the scheduler
-		s.mu.Lock()
-		oldCount := len(s.monitors)
-		s.monitors = make([]*types.Monitor, 0)
-		s.mu.Unlock()
-
-		log.Printf("SCHEDULER: Cleared all %d monitors from scheduler", oldCount)
-		return
-	}
-
-	// Create a map of existing monitors for tracking
-	s.mu.RLock()
-	existingMonitors := make(map[string]*types.Monitor)
-	for _, m := range s.monitors {
-		existingMonitors[m.ID] = m
 	}
-	s.mu.RUnlock()
 
-	// Create a map of new monitors from the database
-	newMonitors := make(map[string]*types.Monitor)
+	// Only active monitors should have a running worker
+	desired := make(map[string]*types.Monitor)
 	for i := range monitors {
 		monitor := &monitors[i]
-		newMonitors[monitor.ID] = monitor
-	}
-
-	// Identify monitors to add, update, and remove
-	var monitorsToAdd []*types.Monitor
-	var monitorsToRemove []string
-
-	// Find monitors to add (in new but not in existing)
-	for id, monitor := range newMonitors {
-		if _, exists := existingMonitors[id]; !exists {
-			// Ensure monitor has a reasonable check interval
-			if monitor.CheckInterval < 10 {
-				log.Printf("⏱️ WARN: Monitor %s has very low check interval. Setting to 60 seconds.", monitor.Name)
-				monitor.CheckInterval = 60
-			}
-
-			// Ensure monitor has an initial status
-			if monitor.Status == "" {
-				log.Printf("SCHEDULER: Monitor %s has no status, setting to pending", monitor.Name)
-				monitor.Status = "pending"
-			}
+		if !monitor.IsActive {
+			continue
+		}
 
-			if monitor.IsActive {
-				log.Printf("🔍 SCHEDULER: Adding new monitor: %s (ID: %s, URL: %s)",
-					monitor.Name, monitor.ID, monitor.URL)
-				monitorsToAdd = append(monitorsToAdd, monitor)
-			}
+		// Ensure monitor has a reasonable check interval
+		if monitor.CheckInterval < 10 {
+			log.Printf("⏱️ WARN: Monitor %s has very low check interval. Setting to 60 seconds.", monitor.Name)
+			monitor.CheckInterval = 60
 		}
-	}
 
-	// Find monitors to remove (in existing but not in new)
-	for id, monitor := range existingMonitors {
-		if !dbMonitors[id] {
-			log.Printf("🗑️ SCHEDULER: Removing deleted monitor: %s (ID: %s)",
-				monitor.Name, monitor.ID)
-			monitorsToRemove = append(monitorsToRemove, id)
-
-			// Double-check that the monitor is actually gone from the database
-			// If it's not, force delete it
-			var count int64
-			if db, ok := s.monitorRepo.GetDB().(*gorm.DB); ok {
-				db.Model(&types.Monitor{}).Where("id = ?", id).Count(&count)
-				if count > 0 {
-					log.Printf("WARNING: Monitor %s still exists in database after deletion! Forcing delete", id)
-					db.Exec("DELETE FROM monitors WHERE id = ?", id)
-				}
-			}
+		// Ensure monitor has an initial status
+		if monitor.Status == "" {
+			log.Printf("SCHEDULER: Monitor %s has no status, setting to pending", monitor.Name)
+			monitor.Status = "pending"
 		}
+
+		desired[monitor.ID] = monitor
 	}
 
-	// Update the scheduler's monitor list
 	s.mu.Lock()
+	var added, restarted, removed int
+	var stopped []*monitorWorker
+
+	// Stop workers for monitors that were deleted or paused, and restart those
+	// whose configuration changed since the worker was started
+	for id, w := range s.workers {
+		monitor, exists := desired[id]
+		if !exists {
+			log.Printf("🗑️ SCHEDULER: Stopping monitor %s (ID: %s) - deleted or paused", w.monitor.Name, id)
+			s.removeWorker(w)
+			stopped = append(stopped, w)
+			removed++
+			continue
+		}
 
-	// Remove deleted monitors
-	for _, id := range monitorsToRemove {
-		for i := 0; i < len(s.monitors); i++ {
-			if s.monitors[i].ID == id {
-				log.Printf("Removing monitor at index %d: %s (ID: %s)",
-					i, s.monitors[i].Name, s.monitors[i].ID)
-				// Remove the monitor from the slice
-				s.monitors = append(s.monitors[:i], s.monitors[i+1:]...)
-				// Decrement i to account for the removed element
-				i--
-			}
+		if monitorConfigHash(monitor) != w.configHash {
+			log.Printf("🔄 SCHEDULER: Configuration changed for monitor %s (ID: %s), restarting worker", monitor.Name, id)
+			s.stopWorker(w)
+			s.startWorker(monitor, w)
+			restarted++
 		}
 	}
 
-	// Add new monitors
-	for _, monitor := range monitorsToAdd {
-		// Double-check that this monitor ID isn't already in our list
-		isDuplicate := false
-		for _, m := range s.monitors {
-			if m.ID == monitor.ID {
-				log.Printf("WARNING: Not adding duplicate monitor %s (ID: %s)", monitor.Name, monitor.ID)
-				isDuplicate = true
-				break
-			}
+	// Start workers for monitors that are not running yet
+	for id, monitor := range desired {
+		if _, running := s.workers[id]; running {
+			continue
 		}
+		log.Printf("🔍 SCHEDULER: Adding new monitor: %s (ID: %s, URL: %s)",
+			monitor.Name, monitor.ID, monitor.URL)
+		s.startWorker(monitor, nil)
+		added++
+	}
+	active := len(s.workers)
+	s.mu.Unlock()
 
-		if !isDuplicate {
-			s.monitors = append(s.monitors, monitor)
-
-			// Start monitoring for new monitors
-			go func(m *types.Monitor) {
-				log.Printf("🚦 SCHEDULER: Starting Monitoring for new monitor %s", m.Name)
-				s.runMonitor(m)
-			}(monitor)
-		}
+	for _, w := range stopped {
+		<-w.done
 	}
+	log.Printf("SCHEDULER: Monitor list updated - Added: %d, Restarted: %d, Removed: %d, Total Active: %d",
+		added, restarted, removed, active)
+}
 
-	// Log the current monitors in the scheduler for debugging
-	log.Printf("Current monitors in scheduler after update:")
-	for i, m := range s.monitors {
-		log.Printf("  %d: %s (ID: %s)", i, m.Name, m.ID)
+// startWorker launches the check loop for a monitor. A worker replacing a
+// stopped one waits for it to exit before checking, so a monitor is never
+// checked twice at once. Callers must hold s.mu.
+func (s *Scheduler) startWorker(monitor *types.Monitor, previous *monitorWorker) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	w := &monitorWorker{
+		monitor:    monitor,
+		configHash: monitorConfigHash(monitor),
+		cancel:     cancel,
+		done:       make(chan struct{}),
 	}
+	s.workers[monitor.ID] = w
 
-	s.mu.Unlock()
+	go func() {
+		defer close(w.done)
+		if previous != nil {
+			<-previous.done
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("🚦 SCHEDULER: Starting Monitoring for monitor %s", monitor.Name)
+		s.runMonitor(ctx, monitor)
+	}()
+}
 
-	log.Printf("SCHEDULER: Monitor list updated - Added: %d, Removed: %d, Total Active: %d",
-		len(monitorsToAdd), len(monitorsToRemove), len(s.monitors))
+// stopWorker cancels a worker and forgets it. Callers must hold s.mu, and wait
+// on w.done only once they released it, as a running check may call back into
+// the scheduler.
+func (s *Scheduler) stopWorker(w *monitorWorker) {
+	w.cancel()
+	delete(s.workers, w.monitor.ID)
 }
 
-func (s *Scheduler) runMonitor(monitor *types.Monitor) {
+// removeWorker stops the worker of a monitor that is no longer checked and
+// forgets when it was last notified. A worker restarted for a configuration
+// change keeps that, so editing a failing monitor doesn't reset the
+// notification backoff. Callers must hold s.mu.
+func (s *Scheduler) removeWorker(w *monitorWorker) {
+	s.stopWorker(w)
+
+	notifyMutex.Lock()
+	delete(lastNotifiedMap, w.monitor.ID)
+	notifyMutex.Unlock()
+}
+
+// monitorConfigHash fingerprints the user-editable configuration of a monitor,
+// ignoring the fields that every check writes back
+func monitorConfigHash(monitor *types.Monitor) string {
+	cfg := *monitor
+	cfg.Status = ""
+	cfg.FailureCount = 0
+	cfg.ResponseCode = 0
+	cfg.ResponseTime = 0
+	cfg.LastChecked = time.Time{}
+	cfg.TLSCertExpiry = time.Time{}
+	cfg.TLSCertIssuer = ""
+	cfg.TLSCertSANs = ""
+	cfg.TLSChainValid = false
+	cfg.TLSChainError = ""
+	cfg.TLSAlertLevel = ""
+	cfg.Degraded = false
+	cfg.CreatedAt = time.Time{}
+	cfg.UpdatedAt = time.Time{}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	// Secrets are masked in JSON, so they are hashed as they are
+	data = append(data, cfg.DBPassword...)
+	data = append(data, cfg.Headers...)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Scheduler) runMonitor(ctx context.Context, monitor *types.Monitor) {
 	log.Printf("MONITOR: Starting continuous monitoring for %s with interval %d seconds",
 		monitor.Name, monitor.CheckInterval)
 
 	// Perform initial check immediately
 	log.Printf("MONITOR: Performing initial check for %s", monitor.Name)
-
-	// First verify the monitor still exists in the database
-	if !s.verifyMonitorExists(monitor.ID) {
-		log.Printf("MONITOR %s (ID: %s) no longer exists in database, stopping monitoring",
-			monitor.Name, monitor.ID)
-		// Remove from scheduler's list
-		s.RemoveMonitor(monitor.ID)
-		return
-	}
-
-	if err := s.checkMonitor(monitor); err != nil {
+	if err := s.checkMonitor(ctx, monitor); err != nil {
 		log.Printf("INITIAL CHECK ERROR for %s: %v", monitor.Name, err)
 	} else {
 		log.Printf("INITIAL CHECK COMPLETED for %s", monitor.Name)
@@ -261,19 +340,20 @@ func (s *Scheduler) runMonitor(monitor *types.Monitor) {
 	// Continuous monitoring loop
 	for {
 		select {
+		case <-ctx.Done():
+			log.Printf("MONITOR: Stopped monitoring %s (ID: %s)", monitor.Name, monitor.ID)
+			return
 		case <-ticker.C:
 			log.Printf("TICKER: Time to check monitor %s", monitor.Name)
 
 			// Verify the monitor still exists in the database before each check
 			if !s.verifyMonitorExists(monitor.ID) {
-				log.Printf("MONITOR %s (ID: %s) no longer exists in database, stopping monitoring",
+				log.Printf("MONITOR %s (ID: %s) no longer exists in database, skipping check until next reload",
 					monitor.Name, monitor.ID)
-				// Remove from scheduler's list
-				s.RemoveMonitor(monitor.ID)
-				return
+				continue
 			}
 
-			if err := s.checkMonitor(monitor); err != nil {
+			if err := s.checkMonitor(ctx, monitor); err != nil {
 				log.Printf("PERIODIC CHECK ERROR for %s: %v", monitor.Name, err)
 			} else {
 				log.Printf("PERIODIC CHECK COMPLETED for %s", monitor.Name)
@@ -284,7 +364,7 @@ func (s *Scheduler) runMonitor(monitor *types.Monitor) {
 
 // verifyMonitorExists checks if a monitor still exists in the database
 func (s *Scheduler) verifyMonitorExists(monitorID string) bool {
-	_, err := s.monitorRepo.GetMonitorByID(monitorID)
+	_, err := s.monitorRepo.GetMonitorByIDAcrossProfiles(monitorID)
 	if err != nil {
 		log.Printf("Monitor %s not found in database: %v", monitorID, err)
 		return false
@@ -292,7 +372,7 @@ func (s *Scheduler) verifyMonitorExists(monitorID string) bool {
 	return true
 }
 
-func (s *Scheduler) checkMonitor(monitor *types.Monitor) error {
+func (s *Scheduler) checkMonitor(ctx context.Context, monitor *types.Monitor) error {
 	// Add more detailed logging at the start of the method
 	log.Printf("========== CHECKING MONITOR: %s ==========", monitor.Name)
 	log.Printf("  URL: %s", monitor.URL)
@@ -311,331 +391,418 @@ func (s *Scheduler) checkMonitor(monitor *types.Monitor) error {
 
 	// More robust status determination
 	previousStatus := monitor.Status
-	var status string
-	var message string
-	var responseTime int64
-	var credentialError bool = false
-	var credential *services.Credential = nil
-
-	// Check if credential exists if specified
-	if monitor.CredentialID != "" {
-		log.Printf("  Monitor %s uses credential ID: %s", monitor.Name, monitor.CredentialID)
-		var err error
-		credential, err = s.credentials.GetCredential(monitor.CredentialID)
-		if err != nil {
-			log.Printf("  ERROR: Failed to retrieve credential for monitor %s: %v", monitor.Name, err)
-			status = "down"
-			message = fmt.Sprintf("Credential error: %v", err)
-			monitor.ResponseCode = 0
-			monitor.FailureCount++
-			credentialError = true
-			log.Printf("  Credential error - Failure count increased to %d/%d",
-				monitor.FailureCount, monitor.FailureThreshold)
-
-			// Force status to down if credential error occurs and threshold is reached
-			if monitor.FailureCount >= monitor.FailureThreshold {
-				log.Printf("  Failure threshold reached - Setting status to DOWN")
-			}
-		} else {
-			log.Printf("  Successfully retrieved credential: %s (%s)", credential.Name, credential.Type)
-		}
+
+	// Run the checker registered for the monitor's type
+	log.Printf("  Running %s check", monitor.Type)
+	result := s.checkers.Check(ctx, monitor)
+	status := result.Status
+	message := result.Message
+	responseTime := result.ResponseTime
+	monitor.ResponseCode = result.ResponseCode
+	log.Printf("  Check result: status=%s code=%d time=%dms category=%s message=%s",
+		result.Status, result.ResponseCode, result.ResponseTime, result.ErrorCategory, result.Message)
+
+	// Drop the result if the worker was cancelled mid-check; the monitor is
+	// being reconfigured, paused or deleted and a new worker owns it now
+	if ctx.Err() != nil {
+		log.Printf("  Check for %s cancelled, discarding result", monitor.Name)
+		return ctx.Err()
 	}
 
-	// Perform monitor check only if no credential error occurred
-	if !credentialError {
-		// Perform monitor check
-		if monitor.RequestType == "curl" {
-			log.Printf("  Using CURL for request")
-			curlService := services.NewCurlService(s.credentials)
-			code, msg, err := curlService.ExecuteCurlRequest(monitor)
-			if err != nil {
-				log.Printf("  CURL ERROR: %v", err)
-				status = "down"
-				message = fmt.Sprintf("CURL check failed: %v", err)
-				monitor.ResponseCode = 0
-			} else {
-				log.Printf("  CURL SUCCESS: Status code %d", code)
-				monitor.ResponseCode = code
-				status = getStatusFromCode(code)
-				message = msg
-
-				// Ensure we explicitly set status to "up" for successful curl requests
-				if code >= 200 && code < 300 {
-					log.Printf("  Setting status to UP for successful curl request")
-					status = "up"
-				}
-			}
-		} else {
-			// HTTP Client check
-			log.Printf("  Using HTTP client for request")
-			client := &http.Client{
-				Timeout: time.Duration(monitor.Timeout) * time.Second,
-			}
+	// Checks during maintenance are recorded but don't count as failures
+	if window := s.activeMaintenance(monitor); window != nil {
+		s.recordMaintenanceCheck(monitor, window, result)
+		return nil
+	}
 
-			startTime := time.Now()
-			log.Printf("  Creating request: %s %s", monitor.Method, monitor.URL)
-			req, err := http.NewRequest(monitor.Method, monitor.URL, nil)
-			if err != nil {
-				log.Printf("  REQUEST CREATION ERROR for %s: %v", monitor.Name, err)
-				status = "down"
-				message = fmt.Sprintf("Request creation failed: %v", err)
-				monitor.ResponseCode = 0
-			} else {
-				// Add headers if specified
-				if headers := monitor.GetHeadersMap(); headers != nil {
-					log.Printf("  Adding %d headers to request", len(headers))
-					for key, value := range headers {
-						req.Header.Add(key, value)
-						log.Printf("    Added Header: %s: %s", key, value)
-					}
-				} else {
-					log.Printf("  No headers to add")
-				}
-
-				// Add credential headers if specified
-				if monitor.CredentialID != "" && credential != nil {
-					log.Printf("  Adding credential headers")
-					headerValue := s.credentials.GetHeaderValue(credential)
-					req.Header.Add(credential.HeaderName, headerValue)
-					log.Printf("    Added Credential Header: %s: [REDACTED]", credential.HeaderName)
-				} else if monitor.CredentialID != "" && credential == nil {
-					// This should not happen since we already checked for credential errors
-					log.Printf("  ERROR: Credential is nil but CredentialID is set")
-					status = "down"
-					message = "Internal error: credential is nil"
-					monitor.ResponseCode = 0
-				}
-
-				// Only proceed with request if we don't have a status yet (no credential error)
-				if status == "" {
-					log.Printf("  Executing HTTP request...")
-					resp, err := client.Do(req)
-					responseTime = time.Since(startTime).Milliseconds()
-					log.Printf("  Request completed in %d ms", responseTime)
-
-					if err != nil {
-						log.Printf("  CONNECTION ERROR for %s: %v", monitor.Name, err)
-						status = "down"
-						message = fmt.Sprintf("Connection error: %v", err)
-						monitor.ResponseCode = 0
-					} else {
-						defer resp.Body.Close()
-						monitor.ResponseCode = resp.StatusCode
-						status = getStatusFromCode(resp.StatusCode)
-						message = resp.Status
-						log.Printf("  Response received: %s", resp.Status)
-
-						log.Printf("  RESPONSE DETAILS for %s:", monitor.Name)
-						log.Printf("    Status Code: %d", resp.StatusCode)
-						log.Printf("    Status: %s", status)
-						log.Printf("    Response Time: %d ms", responseTime)
-					}
-				}
-			}
-		}
+	// A monitor leaving maintenance starts over like a new monitor
+	leavingMaintenance := previousStatus == "maintenance"
+	if leavingMaintenance {
+		previousStatus = "pending"
+	}
 
-		// Enhanced status change logic
-		log.Printf("  Processing status change: %s -> %s", previousStatus, status)
-		if status == "down" || status == "unauthorized" {
-			monitor.FailureCount++
-			log.Printf("  Failure count for %s increased to %d/%d",
-				monitor.Name, monitor.FailureCount, monitor.FailureThreshold)
-
-			// Only change to down if failure threshold met
-			if monitor.FailureCount >= monitor.FailureThreshold {
-				log.Printf("  Failure threshold reached - Setting status to DOWN")
-				status = "down"
-			} else if previousStatus == "pending" {
-				// Keep as pending during initial failures
-				log.Printf("  Keeping status as PENDING during initial failures")
-				status = "pending"
-			} else {
-				// Keep previous status during failure count accumulation
-				log.Printf("  Keeping previous status %s during failure count accumulation", previousStatus)
-				status = previousStatus
-			}
-		} else if status == "up" {
-			// Reset failure count on successful check
-			log.Printf("  Check successful - Resetting failure count")
-			monitor.FailureCount = 0
-
-			// Explicitly change from pending to up
-			if previousStatus == "pending" {
-				log.Printf("  Changing status from PENDING to UP")
-				status = "up"
-			}
-		} else if status == "" && !credentialError {
-			// Handle case where status wasn't set (could happen with curl)
-			log.Printf("  WARNING: Status was not set, defaulting to previous status: %s", previousStatus)
+	// Enhanced status change logic
+	log.Printf("  Processing status change: %s -> %s", previousStatus, status)
+	if status == "down" || status == "unauthorized" {
+		monitor.FailureCount++
+		log.Printf("  Failure count for %s increased to %d/%d",
+			monitor.Name, monitor.FailureCount, monitor.FailureThreshold)
+
+		// Only change to down (or unauthorized) if failure threshold met
+		if monitor.FailureCount >= monitor.FailureThreshold {
+			log.Printf("  Failure threshold reached - Setting status to %s", strings.ToUpper(status))
+		} else if previousStatus == "pending" {
+			// Keep as pending during initial failures
+			log.Printf("  Keeping status as PENDING during initial failures")
+			status = "pending"
+		} else {
+			// Keep previous status during failure count accumulation
+			log.Printf("  Keeping previous status %s during failure count accumulation", previousStatus)
 			status = previousStatus
 		}
-
-		// Force transition from pending to down if we've been pending for too long
-		// This ensures monitors don't get stuck in pending state
-		if previousStatus == "pending" && monitor.LastChecked.Add(time.Duration(monitor.CheckInterval*3)*time.Second).Before(time.Now()) {
-			log.Printf("  Monitor %s has been pending for too long, forcing to DOWN state", monitor.Name)
-			status = "down"
+	} else if status == "up" {
+		// Reset failure count on successful check
+		log.Printf("  Check successful - Resetting failure count")
+		monitor.FailureCount = 0
+
+		// Explicitly change from pending to up
+		if previousStatus == "pending" {
+			log.Printf("  Changing status from PENDING to UP")
+			status = "up"
 		}
+	} else if status == "" {
+		// Handle case where status wasn't set (could happen with curl)
+		log.Printf("  WARNING: Status was not set, defaulting to previous status: %s", previousStatus)
+		status = previousStatus
+	}
 
-		// Update monitor status
-		monitor.Status = status
-		monitor.ResponseTime = responseTime
-		monitor.LastChecked = time.Now()
-
-		// Log status changes
-		log.Printf("  Monitor %s status: %s -> %s (Failures: %d/%d)",
-			monitor.Name,
-			previousStatus,
-			status,
-			monitor.FailureCount,
-			monitor.FailureThreshold,
-		)
-
-		// Add more detailed logging
-		log.Printf("  Monitor Check Summary: %s", monitor.Name)
-		log.Printf("    URL: %s", monitor.URL)
-		log.Printf("    Response Code: %d", monitor.ResponseCode)
-		log.Printf("    Response Time: %d ms", responseTime)
-		log.Printf("    Status: %s", status)
-		log.Printf("    Message: %s", message)
-		log.Printf("    Last Checked: %v", monitor.LastChecked)
-
-		// Create notification service
-		log.Printf("  Checking for notification requirements")
-		profileRepo := repository.NewProfileRepository(config.DB)
-		methods, err := profileRepo.GetNotificationMethods(monitor.ProfileID)
-		if err != nil {
-			log.Printf("  Error getting notification methods: %v", err)
-			return err
-		}
-		log.Printf("  Found %d notification methods", len(methods))
-
-		// Convert methods to types.NotificationMethod
-		var typedMethods []types.NotificationMethod
-		for _, m := range methods {
-			typedMethods = append(typedMethods, types.NotificationMethod{
-				ID:        m.ID,
-				ProfileID: m.ProfileID,
-				Type:      m.Type,
-				Enabled:   m.Enabled,
-				Config:    m.Config,
-				CreatedAt: m.CreatedAt,
-				UpdatedAt: m.UpdatedAt,
-			})
-		}
+	// Force transition from pending to down if we've been pending for too long
+	// This ensures monitors don't get stuck in pending state
+	if status == "pending" && previousStatus == "pending" && !monitor.LastChecked.IsZero() && monitor.LastChecked.Add(time.Duration(monitor.CheckInterval*3)*time.Second).Before(time.Now()) {
+		log.Printf("  Monitor %s has been pending for too long, forcing to DOWN state", monitor.Name)
+		status = "down"
+	}
 
-		notificationService := services.NewNotificationService(typedMethods)
+	// Update monitor status
+	monitor.Status = status
+	monitor.ResponseTime = responseTime
+	monitor.LastChecked = time.Now()
+
+	// Log status changes
+	log.Printf("  Monitor %s status: %s -> %s (Failures: %d/%d)",
+		monitor.Name,
+		previousStatus,
+		status,
+		monitor.FailureCount,
+		monitor.FailureThreshold,
+	)
+
+	// Add more detailed logging
+	log.Printf("  Monitor Check Summary: %s", monitor.Name)
+	log.Printf("    URL: %s", monitor.URL)
+	log.Printf("    Response Code: %d", monitor.ResponseCode)
+	log.Printf("    Response Time: %d ms", responseTime)
+	log.Printf("    Status: %s", status)
+	log.Printf("    Message: %s", message)
+	log.Printf("    Last Checked: %v", monitor.LastChecked)
+
+	// Record the certificate and raise expiry alerts for TLS connections
+	if result.TLS != nil {
+		s.processCertificate(monitor, result.TLS)
+	}
 
-		// Check and send notification
-		shouldNotify := false
-		if status != previousStatus {
-			// Always notify on status change
-			log.Printf("  Status changed from %s to %s - Notification required", previousStatus, status)
+	// Open, update or close the monitor's incident
+	incident := s.trackIncident(monitor, status, message)
+
+	// Check and send notification
+	shouldNotify := false
+	if leavingMaintenance && status == "up" {
+		log.Printf("  Monitor is up after maintenance - No notification required")
+	} else if status != previousStatus {
+		// Always notify on status change
+		log.Printf("  Status changed from %s to %s - Notification required", previousStatus, status)
+		shouldNotify = true
+	} else if incident != nil && incident.IsAcknowledged() {
+		// Someone is already working on the outage
+		log.Printf("  Incident %s acknowledged by %s - Skipping repeat notification",
+			incident.ID, incident.AcknowledgedBy)
+	} else if status == "down" || status == "unauthorized" {
+		// For ongoing down status, implement exponential backoff
+		notifyMutex.Lock()
+		lastNotified, exists := lastNotifiedMap[monitor.ID]
+		if !exists {
+			log.Printf("  First notification for ongoing DOWN status")
 			shouldNotify = true
-		} else if status == "down" {
-			// For ongoing down status, implement exponential backoff
-			lastNotified, exists := lastNotifiedMap[monitor.ID]
-			if !exists {
-				log.Printf("  First notification for ongoing DOWN status")
-				shouldNotify = true
-				lastNotifiedMap[monitor.ID] = time.Now()
-			} else if time.Since(lastNotified) > calculateNotificationInterval(monitor.FailureCount) {
-				log.Printf("  Notification interval elapsed for ongoing DOWN status - Notification required")
-				shouldNotify = true
-				lastNotifiedMap[monitor.ID] = time.Now()
-			} else {
-				log.Printf("  Skipping notification for ongoing DOWN status - Next notification in %v",
-					calculateNotificationInterval(monitor.FailureCount)-time.Since(lastNotified))
-			}
-		}
-
-		if shouldNotify {
-			log.Printf("  SENDING NOTIFICATION: Monitor %s changed from %s to %s",
-				monitor.Name, previousStatus, status)
-			err := notificationService.SendNotification(monitor, status, message)
-			if err != nil {
-				log.Printf("  ERROR SENDING NOTIFICATION: %v", err)
-			} else {
-				log.Printf("  Notification sent successfully")
-			}
+			lastNotifiedMap[monitor.ID] = time.Now()
+		} else if time.Since(lastNotified) > calculateNotificationInterval(monitor.FailureCount) {
+			log.Printf("  Notification interval elapsed for ongoing DOWN status - Notification required")
+			shouldNotify = true
+			lastNotifiedMap[monitor.ID] = time.Now()
 		} else {
-			log.Printf("  No notification required")
+			log.Printf("  Skipping notification for ongoing DOWN status - Next notification in %v",
+				calculateNotificationInterval(monitor.FailureCount)-time.Since(lastNotified))
 		}
+		notifyMutex.Unlock()
+	}
 
-		// Update monitor in repository
-		log.Printf("  Updating monitor in database")
-		if err := s.monitorRepo.UpdateMonitor(monitor); err != nil {
-			log.Printf("  ERROR updating monitor status: %v", err)
-		} else {
-			log.Printf("  Monitor updated successfully in database")
+	if shouldNotify {
+		log.Printf("  SENDING NOTIFICATION: Monitor %s changed from %s to %s",
+			monitor.Name, previousStatus, status)
+		n := &services.Notification{
+			Monitor:        monitor,
+			Status:         status,
+			PreviousStatus: previousStatus,
+			Message:        message,
+			Time:           time.Now(),
 		}
-
-		// Create log entry
-		log.Printf("  Creating log entry")
-		logEntry := types.Log{
-			ID:        uuid.New().String(),
-			MonitorID: monitor.ID,
-			Status:    status,
-			Message:   message,
-			CreatedAt: time.Now(),
+		if incident != nil {
+			n.IncidentStartedAt = incident.StartedAt
 		}
 
-		// Create log in repository
-		if err := s.logRepo.CreateLog(&logEntry); err != nil {
-			log.Printf("  ERROR creating log entry: %v", err)
-		} else {
-			log.Printf("  Log entry created successfully")
+		switch {
+		case (status == "down" || status == "unauthorized") && incident != nil && s.escalations.Escalate(monitor, incident):
+			// The policy notifies its levels in turn until the outage is acknowledged
+			log.Printf("  Outage of %s is notified by its escalation policy", monitor.Name)
+		case n.IsRecovery() && incident != nil:
+			s.sendNotification(n, s.escalations.Resolve(incident)...)
+		default:
+			s.sendNotification(n)
 		}
+	} else {
+		log.Printf("  No notification required")
+	}
+
+	// Alert when an up monitor gets slower than its degraded threshold, and when it recovers
+	s.processDegradation(monitor, status, previousStatus)
 
-		log.Printf("========== MONITOR CHECK COMPLETED: %s ==========\n", monitor.Name)
+	// Update monitor in repository. Only the check results are written so that
+	// edits made through the API while the check ran are not overwritten.
+	log.Printf("  Updating monitor in database")
+	if err := s.monitorRepo.UpdateMonitorStatus(monitor); err != nil {
+		log.Printf("  ERROR updating monitor status: %v", err)
+	} else {
+		log.Printf("  Monitor updated successfully in database")
 	}
+
+	s.createLog(monitor, status, message, result)
+
+	log.Printf("========== MONITOR CHECK COMPLETED: %s ==========\n", monitor.Name)
 	return nil
 }
 
-func getStatusFromCode(code int) string {
-	switch {
-	case code >= 200 && code < 300:
-		return "up"
-	case code >= 300 && code < 400:
-		return "redirect"
-	case code >= 400 && code < 500:
-		return "down"
-	case code >= 500:
-		return "down"
-	case code == 401:
-		return "unauthorized"
+// recordMaintenanceCheck stores the result of a check made during a maintenance
+// window. The monitor and log get the maintenance status, failures are not
+// counted and nobody is notified.
+func (s *Scheduler) recordMaintenanceCheck(monitor *types.Monitor, window *types.MaintenanceWindow, result *services.CheckResult) {
+	log.Printf("  🔧 %s is in maintenance window %q, holding back notifications", monitor.Name, window.Name)
+
+	monitor.Status = "maintenance"
+	monitor.FailureCount = 0
+	monitor.ResponseTime = result.ResponseTime
+	monitor.LastChecked = time.Now()
+	if err := s.monitorRepo.UpdateMonitorStatus(monitor); err != nil {
+		log.Printf("  ERROR updating monitor status: %v", err)
+	}
+
+	message := fmt.Sprintf("Maintenance (%s): check was %s - %s", window.Name, result.Status, result.Message)
+	s.createLog(monitor, "maintenance", message, result)
+	log.Printf("========== MONITOR CHECK COMPLETED: %s ==========\n", monitor.Name)
+}
+
+// createLog adds a check to the monitor's history
+func (s *Scheduler) createLog(monitor *types.Monitor, status, message string, result *services.CheckResult) {
+	log.Printf("  Creating log entry")
+	logEntry := types.Log{
+		ID:            uuid.New().String(),
+		MonitorID:     monitor.ID,
+		Status:        status,
+		Message:       message,
+		ResponseTime:  result.ResponseTime,
+		ResponseCode:  result.ResponseCode,
+		ErrorCategory: result.ErrorCategory,
+		Location:      s.location,
+		Worker:        s.worker,
+		CreatedAt:     time.Now(),
+	}
+	if result.Timings != nil {
+		logEntry.DNSMs = result.Timings.DNSMs
+		logEntry.ConnectMs = result.Timings.ConnectMs
+		logEntry.TLSMs = result.Timings.TLSMs
+		logEntry.TTFBMs = result.Timings.TTFBMs
+	}
+	if len(result.Steps) > 0 {
+		if steps, err := json.Marshal(result.Steps); err == nil {
+			logEntry.Steps = string(steps)
+		}
+		logEntry.FailedStep = result.FailedStep
+	}
+
+	if err := s.logRepo.CreateLog(&logEntry); err != nil {
+		log.Printf("  ERROR creating log entry: %v", err)
+	} else {
+		log.Printf("  Log entry created successfully")
+	}
+}
+
+// activeMaintenance returns the maintenance window the monitor is in right now, if any
+func (s *Scheduler) activeMaintenance(monitor *types.Monitor) *types.MaintenanceWindow {
+	windows, err := s.maintenance.GetWindowsForProfile(monitor.ProfileID, true)
+	if err != nil {
+		log.Printf("  ERROR loading maintenance windows: %v", err)
+		return nil
+	}
+	return services.ActiveMaintenanceWindow(windows, monitor, time.Now())
+}
+
+// trackIncident keeps the monitor's incident in line with its status: a failing
+// monitor gets an incident opened or updated and a recovered monitor has its
+// incident closed. It returns the monitor's unresolved incident, or the one
+// this check resolved, if any.
+func (s *Scheduler) trackIncident(monitor *types.Monitor, status, message string) *types.Incident {
+	incident, err := s.incidents.GetOpenIncident(monitor.ID)
+	if err != nil {
+		log.Printf("  ERROR loading incident for %s: %v", monitor.Name, err)
+		return nil
+	}
+
+	now := time.Now()
+	switch status {
+	case "down", "unauthorized":
+		if incident == nil {
+			incident, err = s.incidents.OpenIncident(monitor, message, now)
+			if err != nil {
+				log.Printf("  ERROR opening incident for %s: %v", monitor.Name, err)
+				return nil
+			}
+			log.Printf("  🚨 Opened incident %s for %s", incident.ID, monitor.Name)
+			return incident
+		}
+		if err := s.incidents.RecordFailure(incident, message, now); err != nil {
+			log.Printf("  ERROR updating incident %s: %v", incident.ID, err)
+		}
+		return incident
+	case "up":
+		if incident == nil {
+			return nil
+		}
+		if err := s.incidents.Resolve(incident, types.IncidentEventRecovered, "system", message); err != nil {
+			log.Printf("  ERROR resolving incident %s: %v", incident.ID, err)
+			return incident
+		}
+		log.Printf("  ✅ Resolved incident %s for %s after %v",
+			incident.ID, monitor.Name, now.Sub(incident.StartedAt).Round(time.Second))
+		return incident
 	default:
-		return "down"
+		return incident
 	}
 }
 
-func sendEmail(settings *types.SMTPSettings, subject, body string) error {
-	log.Printf("Preparing to send email via SMTP server %s:%d to %s",
-		settings.Host, settings.Port, settings.RecipientEmail)
+// processDegradation tracks whether an up monitor responds slower than its
+// degraded threshold, and notifies when it becomes degraded and when it is fast
+// again. A monitor that isn't up is no longer degraded; its outage is notified instead.
+func (s *Scheduler) processDegradation(monitor *types.Monitor, status, previousStatus string) {
+	wasDegraded := monitor.Degraded
+	monitor.Degraded = status == "up" && monitor.DegradedThreshold > 0 && monitor.ResponseTime > monitor.DegradedThreshold
+	if monitor.Degraded == wasDegraded || status != "up" {
+		return
+	}
 
-	auth := smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
-	to := []string{settings.RecipientEmail}
+	n := &services.Notification{
+		Monitor: monitor,
+		Time:    time.Now(),
+	}
+	if monitor.Degraded {
+		n.Status = "degraded"
+		n.PreviousStatus = previousStatus
+		n.Message = fmt.Sprintf("Response time %dms is above the %dms degraded threshold", monitor.ResponseTime, monitor.DegradedThreshold)
+		log.Printf("  🐢 %s is degraded: %s", monitor.Name, n.Message)
+	} else {
+		// Recovered from degradation, unless the monitor just came back up, which is notified already
+		if previousStatus != "up" {
+			return
+		}
+		n.Status = "up"
+		n.PreviousStatus = "degraded"
+		n.Message = fmt.Sprintf("Response time %dms is back under the %dms degraded threshold", monitor.ResponseTime, monitor.DegradedThreshold)
+		log.Printf("  ✅ %s is no longer degraded", monitor.Name)
+	}
+	s.sendNotification(n)
+}
+
+// processCertificate copies the certificate details onto the monitor and sends a
+// notification when the certificate crosses a warning, critical or expired threshold
+func (s *Scheduler) processCertificate(monitor *types.Monitor, info *services.TLSCertificateInfo) {
+	monitor.TLSCertExpiry = info.NotAfter
+	monitor.TLSCertIssuer = info.Issuer
+	monitor.TLSCertSANs = strings.Join(info.SANs, ",")
+	monitor.TLSChainValid = info.ChainValid
+	monitor.TLSChainError = info.ChainError
+	log.Printf("  TLS certificate: issuer=%s expires=%v (%d days) chain_valid=%v",
+		info.Issuer, info.NotAfter, info.DaysRemaining, info.ChainValid)
+
+	level := services.TLSAlertLevel(monitor, info)
+	previousLevel := monitor.TLSAlertLevel
+	monitor.TLSAlertLevel = level
+	if level == "" {
+		if previousLevel != "" {
+			log.Printf("  ✅ Certificate for %s renewed, clearing %s alert", monitor.Name, previousLevel)
+		}
+		return
+	}
+
+	// Only notify when the alert gets worse; a renewal resets the level above
+	if tlsAlertRank(level) <= tlsAlertRank(previousLevel) {
+		return
+	}
 
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"Content-Type: text/plain; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s\r\n",
-		settings.RecipientEmail,
-		settings.Username,
-		subject,
-		body))
+	var message string
+	if level == "expired" {
+		message = fmt.Sprintf("TLS certificate for %s expired on %s",
+			monitor.Name, info.NotAfter.Format(time.RFC1123))
+	} else {
+		message = fmt.Sprintf("TLS certificate for %s expires in %d days on %s (issuer: %s)",
+			monitor.Name, info.DaysRemaining, info.NotAfter.Format(time.RFC1123), info.Issuer)
+	}
+	log.Printf("  ⚠️ %s", message)
+	s.sendNotification(&services.Notification{
+		Monitor: monitor,
+		Status:  "cert_" + level,
+		Message: message,
+		Time:    time.Now(),
+	})
+}
 
-	addr := fmt.Sprintf("%s:%d", settings.Host, settings.Port)
-	log.Printf("Connecting to SMTP server at %s", addr)
+// tlsAlertRank orders certificate alert levels by severity
+func tlsAlertRank(level string) int {
+	switch level {
+	case "warning":
+		return 1
+	case "critical":
+		return 2
+	case "expired":
+		return 3
+	default:
+		return 0
+	}
+}
 
-	err := smtp.SendMail(addr, auth, settings.Username, to, msg)
+// sendNotification queues a monitor notification for the profile's notification
+// methods its notification rules pick, and for the given methods, e.g. the ones
+// an escalation notified of the outage. The dispatcher delivers it, so a slow or
+// failing method never holds up the check loop.
+func (s *Scheduler) sendNotification(n *services.Notification, methodIDs ...string) {
+	methods, err := s.profiles.GetNotificationMethods(n.Monitor.ProfileID)
 	if err != nil {
-		log.Printf("SMTP Error sending to %s: %v", settings.RecipientEmail, err)
-		return err
+		log.Printf("  Error getting notification methods: %v", err)
+		return
+	}
+	rules, err := s.rules.GetRules(n.Monitor.ProfileID, "")
+	if err != nil {
+		log.Printf("  Error getting notification rules: %v", err)
+		return
+	}
+	targets := services.RouteNotification(n, methods, rules)
+	if len(methodIDs) > 0 {
+		routed := make(map[string]bool)
+		for _, method := range targets {
+			routed[method.ID] = true
+		}
+		for _, method := range methods {
+			if method.Enabled && !routed[method.ID] && containsID(methodIDs, method.ID) {
+				targets = append(targets, method)
+			}
+		}
+	}
+	log.Printf("  Found %d notification methods, %d routed for %s event", len(methods), len(targets), n.Event())
+	if len(targets) == 0 {
+		return
+	}
+
+	if err := s.dispatcher.Enqueue(n, targets); err != nil {
+		log.Printf("  ERROR QUEUEING NOTIFICATION: %v", err)
+	} else {
+		log.Printf("  Notification queued for delivery")
 	}
-	return nil
 }
 
 // Helper function for notification interval
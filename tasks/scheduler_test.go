@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+	"uptime-monitor/repository"
+	"uptime-monitor/services"
+	"uptime-monitor/types"
+
+	"gorm.io/gorm"
+)
+
+// newTestScheduler creates a scheduler on db whose HTTP checks are made by checker
+func newTestScheduler(t *testing.T, db *gorm.DB, checker services.CheckerFunc) *Scheduler {
+	t.Helper()
+	checkers := services.NewCheckerRegistry()
+	checkers.Register(types.MonitorTypeHTTP, checker)
+
+	profiles := repository.NewProfileRepository(db)
+	incidents := repository.NewIncidentRepository(db)
+	monitors := repository.NewMonitorRepository(db)
+	dispatcher := NewNotificationDispatcher(services.NewNotifierRegistry(), repository.NewDeliveryRepository(db), profiles)
+	escalations := NewEscalationWorker(repository.NewEscalationRepository(db), incidents, monitors, profiles, dispatcher)
+	s := NewScheduler(checkers, dispatcher, escalations, monitors, repository.NewLogRepository(db), incidents,
+		repository.NewMaintenanceRepository(db), profiles, repository.NewNotificationRuleRepository(db))
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// waitFor fails the test unless cond becomes true within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulerReloadMonitors(t *testing.T) {
+	db := newTestDB(t)
+	checked := make(chan string, 10)
+	s := newTestScheduler(t, db, func(ctx context.Context, monitor *types.Monitor) *services.CheckResult {
+		checked <- monitor.ID + " " + monitor.URL
+		return &services.CheckResult{Status: "up", ResponseCode: 200}
+	})
+	monitors := s.monitorRepo
+
+	// expectCheck fails the test unless the next check is of the given monitor and URL
+	expectCheck := func(want string) {
+		t.Helper()
+		select {
+		case got := <-checked:
+			if got != want {
+				t.Fatalf("checked %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for a check of %q", want)
+		}
+	}
+	// worker returns the running worker of a monitor, or nil
+	worker := func(id string) *monitorWorker {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.workers[id]
+	}
+	// logged reports whether a check of the monitor finished
+	logged := func(id string) bool {
+		var count int64
+		db.Model(&types.Log{}).Where("monitor_id = ?", id).Count(&count)
+		return count > 0
+	}
+
+	for _, monitor := range []*types.Monitor{
+		{ID: "api", ProfileID: "p1", Name: "api", Type: types.MonitorTypeHTTP, URL: "http://api.test", CheckInterval: 60, FailureThreshold: 1, IsActive: true},
+		{ID: "paused", ProfileID: "p2", Name: "paused", Type: types.MonitorTypeHTTP, URL: "http://paused.test", CheckInterval: 60, FailureThreshold: 1},
+	} {
+		if err := monitors.CreateMonitor(monitor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// New active monitors are started
+	s.reloadMonitors()
+	expectCheck("api http://api.test")
+	if worker("paused") != nil {
+		t.Error("reloadMonitors() started a paused monitor")
+	}
+	api := worker("api")
+	if api == nil {
+		t.Fatal("reloadMonitors() did not start an active monitor")
+	}
+
+	// Check results written back are not configuration changes
+	waitFor(t, "the check of api to be logged", func() bool { return logged("api") })
+	s.reloadMonitors()
+	if worker("api") != api {
+		t.Error("reloadMonitors() restarted a monitor whose configuration did not change")
+	}
+
+	// A changed monitor is restarted with its new configuration
+	monitor, err := monitors.GetMonitorByID("p1", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor.URL = "http://api.test/health"
+	if err := monitors.UpdateMonitor(monitor); err != nil {
+		t.Fatal(err)
+	}
+	s.reloadMonitors()
+	expectCheck("api http://api.test/health")
+	restarted := worker("api")
+	if restarted == nil || restarted == api {
+		t.Fatal("reloadMonitors() did not restart a changed monitor")
+	}
+	select {
+	case <-api.done:
+	default:
+		t.Error("the worker of the previous configuration is still running")
+	}
+
+	// Deleted and paused monitors are stopped, and resumed ones started
+	if err := db.Model(&types.Monitor{}).Where("id = ?", "paused").Update("is_active", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := monitors.DeleteMonitor("p1", "api"); err != nil {
+		t.Fatal(err)
+	}
+	s.reloadMonitors()
+	if worker("api") != nil {
+		t.Error("reloadMonitors() kept the worker of a deleted monitor")
+	}
+	select {
+	case <-restarted.done:
+	default:
+		t.Error("reloadMonitors() returned before the worker of a deleted monitor exited")
+	}
+	expectCheck("paused http://paused.test")
+
+	if err := db.Model(&types.Monitor{}).Where("id = ?", "paused").Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.reloadMonitors()
+	if worker("paused") != nil {
+		t.Error("reloadMonitors() kept the worker of a paused monitor")
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+import "time"
+
+// EscalationPolicy notifies more people the longer an outage goes unacknowledged.
+// Its levels are notified in order, each once its delay after the start of the
+// outage has passed, until the incident is acknowledged or resolved.
+type EscalationPolicy struct {
+	ID          string            `json:"id"`
+	ProfileID   string            `json:"profile_id" gorm:"index"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	IsDefault   bool              `json:"is_default"` // Applies to the profile's monitors that have no policy of their own
+	Levels      []EscalationLevel `json:"levels" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// EscalationLevel is a step of an escalation policy
+type EscalationLevel struct {
+	ID           string `json:"id"`
+	PolicyID     string `json:"policy_id" gorm:"index"`
+	Position     int    `json:"position"`
+	DelayMinutes int    `json:"delay_minutes"` // Minutes after the outage started
+	MethodIDs    string `json:"method_ids"`    // Comma separated notification methods
+}
+
+// Escalation states
+const (
+	EscalationStatusActive    = "active"    // Levels are left to notify
+	EscalationStatusStopped   = "stopped"   // Incident acknowledged or resolved, or the policy went away
+	EscalationStatusCompleted = "completed" // Every level was notified
+)
+
+// Escalation is the progress of an escalation policy through the levels for an
+// incident. It is stored so that escalations carry on after a restart.
+type Escalation struct {
+	ID          string    `json:"id"`
+	ProfileID   string    `json:"profile_id" gorm:"index"`
+	PolicyID    string    `json:"policy_id" gorm:"index"`
+	IncidentID  string    `json:"incident_id" gorm:"uniqueIndex"`
+	MonitorID   string    `json:"monitor_id" gorm:"index"`
+	Status      string    `json:"status" gorm:"index"` // active, stopped or completed
+	NextLevel   int       `json:"next_level"`          // Position of the level notified next
+	NextLevelAt time.Time `json:"next_level_at"`       // When the next level is due
+
+	NotifiedMethodIDs string `json:"notified_method_ids,omitempty"` // Comma separated methods notified so far, told when the outage recovers
+	StopReason        string `json:"stop_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,57 @@
+package types
+
+import "time"
+
+// Incident lifecycle states
+const (
+	IncidentStatusOpen         = "open"
+	IncidentStatusAcknowledged = "acknowledged"
+	IncidentStatusResolved     = "resolved"
+)
+
+// Incident timeline event types
+const (
+	IncidentEventOpened       = "opened"
+	IncidentEventCheckFailed  = "check_failed"
+	IncidentEventAcknowledged = "acknowledged"
+	IncidentEventComment      = "comment"
+	IncidentEventResolved     = "resolved"
+	IncidentEventRecovered    = "recovered"
+	IncidentEventEscalated    = "escalated"
+)
+
+// Incident is an outage of a monitor, from the check that took it down until it
+// recovered or was resolved by hand
+type Incident struct {
+	ID             string          `json:"id"`
+	MonitorID      string          `json:"monitor_id" gorm:"index"`
+	ProfileID      string          `json:"profile_id" gorm:"index"`
+	MonitorName    string          `json:"monitor_name"`
+	Status         string          `json:"status" gorm:"index"` // open, acknowledged or resolved
+	Cause          string          `json:"cause"`               // Message of the check that opened the incident
+	FailedChecks   int             `json:"failed_checks"`
+	LastFailureAt  time.Time       `json:"last_failure_at"`
+	StartedAt      time.Time       `json:"started_at"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string          `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *time.Time      `json:"resolved_at,omitempty"`
+	ResolvedBy     string          `json:"resolved_by,omitempty"` // "system" when the monitor recovered
+	Events         []IncidentEvent `json:"events,omitempty" gorm:"foreignKey:IncidentID"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// IsAcknowledged returns true if someone is working on the incident
+func (i *Incident) IsAcknowledged() bool {
+	return i.Status == IncidentStatusAcknowledged
+}
+
+// IncidentEvent is an entry in an incident's timeline
+type IncidentEvent struct {
+	ID         string    `json:"id"`
+	IncidentID string    `json:"incident_id" gorm:"index"`
+	Type       string    `json:"type"` // opened, check_failed, acknowledged, comment, resolved, recovered or escalated
+	Message    string    `json:"message"`
+	Author     string    `json:"author,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -3,9 +3,29 @@ package types
 import "time"
 
 type Log struct {
-	ID        string    `json:"id"`
-	MonitorID string    `json:"monitor_id"`
-	Status    string    `json:"status"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           string `json:"id"`
+	MonitorID    string `json:"monitor_id" gorm:"index:idx_logs_monitor_created,priority:1"`
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	ResponseTime int64  `json:"response_time"` // Latency in milliseconds
+	ResponseCode int    `json:"response_code"` // HTTP status code, 0 for non-HTTP checks
+
+	// ErrorCategory classifies a failed check: dns, connect, timeout, tls, assertion or credential
+	ErrorCategory string `json:"error_category,omitempty"`
+
+	// Check duration breakdown in milliseconds, zero when a phase did not happen
+	DNSMs     int64 `json:"dns_ms"`
+	ConnectMs int64 `json:"connect_ms"`
+	TLSMs     int64 `json:"tls_ms"`
+	TTFBMs    int64 `json:"ttfb_ms"`
+
+	// Transaction checks record a JSON array of step results and the step that failed
+	Steps      string `json:"steps,omitempty"`
+	FailedStep string `json:"failed_step,omitempty"`
+
+	// Where the check ran
+	Location string `json:"location,omitempty"`
+	Worker   string `json:"worker,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_logs_monitor_created,priority:2"`
 }
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a planned period during which monitors are still checked
+// but their failures do not notify anyone or count against uptime. A window is
+// either one-off (StartsAt to EndsAt) or recurring, starting at every match of
+// a cron expression in its timezone and lasting DurationMinutes.
+type MaintenanceWindow struct {
+	ID          string `json:"id"`
+	ProfileID   string `json:"profile_id" gorm:"index"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+
+	// One-off windows run from StartsAt to EndsAt. For recurring windows they
+	// optionally bound the period in which the recurrence applies.
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+
+	Recurrence      string `json:"recurrence,omitempty"`       // Cron expression, empty for one-off windows
+	DurationMinutes int    `json:"duration_minutes,omitempty"` // Length of each recurring window
+	Timezone        string `json:"timezone,omitempty"`         // IANA timezone of the recurrence, UTC if empty
+
+	// Targets: every monitor of the profile, or the listed monitors and tags
+	AllMonitors bool   `json:"all_monitors"`
+	MonitorIDs  string `json:"monitor_ids,omitempty"` // Comma separated monitor IDs
+	Tags        string `json:"tags,omitempty"`        // Comma separated monitor tags
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsRecurring returns true if the window repeats on a cron schedule
+func (w *MaintenanceWindow) IsRecurring() bool {
+	return w.Recurrence != ""
+}
+
+// AppliesTo returns true if the window covers the monitor
+func (w *MaintenanceWindow) AppliesTo(monitor *Monitor) bool {
+	if w.AllMonitors {
+		return true
+	}
+	for _, id := range SplitList(w.MonitorIDs) {
+		if id == monitor.ID {
+			return true
+		}
+	}
+	for _, tag := range SplitList(w.Tags) {
+		if monitor.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitList splits a comma separated list, dropping empty entries
+func SplitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -3,40 +3,141 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"uptime-monitor/secrets"
+)
+
+// Monitor types. An empty type is treated as an HTTP monitor.
+const (
+	MonitorTypeHTTP     = "http"
+	MonitorTypeMySQL    = "mysql"
+	MonitorTypePostgres = "postgres"
+	MonitorTypeMongoDB  = "mongodb"
+	MonitorTypeRedis    = "redis"
+	MonitorTypeTLS      = "tls"
+	MonitorTypeTCP      = "tcp"
+	MonitorTypeDNS      = "dns"
+
+	MonitorTypeTransaction = "transaction"
+)
+
+// Monitor severities, used to route notifications. An empty severity is critical.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
 )
 
 type Monitor struct {
-	ID               string    `json:"id"`
-	ProfileID        string    `json:"profile_id"`
-	Name             string    `json:"name"`
-	Type             string    `json:"type"`
-	URL              string    `json:"url"`
-	Method           string    `json:"method"`
-	RequestType      string    `json:"request_type"`
-	Headers          string    `json:"headers"`
-	Body             string    `json:"body"`
-	CredentialID     string    `json:"credential_id"`
-	CheckInterval    int       `json:"check_interval"`
-	FailureThreshold int       `json:"failure_threshold"`
-	FailureCount     int       `json:"failure_count"`
-	Timeout          int       `json:"timeout"`
-	IsActive         bool      `json:"is_active"`
-	Status           string    `json:"status"`
-	ResponseCode     int       `json:"response_code"`
-	ResponseTime     int64     `json:"response_time"`
-	LastChecked      time.Time `json:"last_checked"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               string           `json:"id"`
+	ProfileID        string           `json:"profile_id"`
+	Name             string           `json:"name"`
+	Type             string           `json:"type"`
+	URL              string           `json:"url"`
+	Method           string           `json:"method"`
+	RequestType      string           `json:"request_type"`
+	Headers          secrets.JSONText `json:"headers"` // JSON object, credential headers stored encrypted
+	Body             string           `json:"body"`
+	CredentialID     string           `json:"credential_id"`
+	CheckInterval    int              `json:"check_interval"`
+	FailureThreshold int              `json:"failure_threshold"`
+	FailureCount     int              `json:"failure_count"`
+	Timeout          int              `json:"timeout"`
+	IsActive         bool             `json:"is_active"`
+	Status           string           `json:"status"`
+	ResponseCode     int              `json:"response_code"`
+	ResponseTime     int64            `json:"response_time"`
+	LastChecked      time.Time        `json:"last_checked"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+
+	Tags string `json:"tags,omitempty"` // Comma separated tags used to group monitors
+
+	// Notification routing, escalation and performance alerts
+	Severity           string `json:"severity,omitempty"`             // critical, warning or info, used by notification rules (default critical)
+	DegradedThreshold  int64  `json:"degraded_threshold,omitempty"`   // Response time in ms above which an up monitor is degraded, 0 to disable
+	Degraded           bool   `json:"degraded"`                       // Whether the last check was slower than DegradedThreshold
+	EscalationPolicyID string `json:"escalation_policy_id,omitempty"` // Escalation policy of outages, the profile's default if empty
+
+	// Response status handling for HTTP monitors
+	ExpectedStatus      int    `json:"expected_status,omitempty"`       // Single accepted status code, used when AcceptedStatusCodes is empty
+	AcceptedStatusCodes string `json:"accepted_status_codes,omitempty"` // Accepted codes and ranges, e.g. "200-299,301,418"
+	FollowRedirects     *bool  `json:"follow_redirects,omitempty"`      // Whether redirects are followed (default true)
+
+	// Response body assertions for HTTP monitors
+	BodyContains       string `json:"body_contains,omitempty"`        // Keyword the response body must contain
+	BodyNotContains    string `json:"body_not_contains,omitempty"`    // Keyword the response body must not contain
+	BodyRegex          string `json:"body_regex,omitempty"`           // Regular expression the response body must match
+	JSONPathAssertions string `json:"json_path_assertions,omitempty"` // JSON array of JSONPathAssertion
+	MaxBodyBytes       int64  `json:"max_body_bytes,omitempty"`       // Maximum number of body bytes read for assertions
+
+	// Host-based monitors (tls, tcp, dns) connect to Hostname:Port instead of a URL.
+	// For dns monitors Hostname is the name that is resolved.
+	Hostname string `json:"hostname,omitempty"`
+	Port     int    `json:"port,omitempty"`
+
+	// DNS-specific fields
+	DNSResolver   string `json:"dns_resolver,omitempty"`    // Resolver host:port, system resolver if empty
+	DNSRecordType string `json:"dns_record_type,omitempty"` // A, AAAA, CNAME, MX or TXT (default A)
+	DNSExpected   string `json:"dns_expected,omitempty"`    // Comma separated values that must be returned
+
+	// TLS certificate monitoring for HTTPS and tls monitors
+	TLSWarningDays  int       `json:"tls_warning_days,omitempty"`                          // Warn when the certificate expires within this many days
+	TLSCriticalDays int       `json:"tls_critical_days,omitempty"`                         // Critical alert when the certificate expires within this many days
+	TLSCertExpiry   time.Time `json:"tls_cert_expiry"`                                     // Leaf certificate expiry recorded by the last check
+	TLSCertIssuer   string    `json:"tls_cert_issuer,omitempty"`                           // Leaf certificate issuer recorded by the last check
+	TLSCertSANs     string    `json:"tls_cert_sans,omitempty" gorm:"column:tls_cert_sans"` // Comma separated subject alternative names
+	TLSChainValid   bool      `json:"tls_chain_valid"`                                     // Whether the chain verified against the system roots
+	TLSChainError   string    `json:"tls_chain_error,omitempty"`                           // Verification error when the chain is invalid
+	TLSAlertLevel   string    `json:"tls_alert_level,omitempty"`                           // Last certificate alert sent: warning, critical or expired
+
+	// Transaction monitors run a JSON array of TransactionStep in order
+	TransactionSteps string `json:"transaction_steps,omitempty"`
 
 	// Database-specific fields
-	DBHost          string `json:"db_host,omitempty"`
-	DBPort          string `json:"db_port,omitempty"`
-	DBName          string `json:"db_name,omitempty"`
-	DBUsername      string `json:"db_username,omitempty"`
-	DBPassword      string `json:"db_password,omitempty"`
-	DBQuery         string `json:"db_query,omitempty"`
-	DBExpectedValue string `json:"db_expected_value,omitempty"`
+	DBHost          string         `json:"db_host,omitempty"`
+	DBPort          string         `json:"db_port,omitempty"`
+	DBName          string         `json:"db_name,omitempty"`
+	DBUsername      string         `json:"db_username,omitempty"`
+	DBPassword      secrets.String `json:"db_password,omitempty"`
+	DBQuery         string         `json:"db_query,omitempty"`
+	DBExpectedValue string         `json:"db_expected_value,omitempty"`
+}
+
+// JSONPathAssertion checks the value at a JSONPath/jq-style path in a JSON response body
+type JSONPathAssertion struct {
+	Path     string `json:"path"`               // e.g. $.status, .data.items[0].healthy
+	Operator string `json:"operator,omitempty"` // equals (default), not_equals, contains, exists
+	Expected string `json:"expected,omitempty"` // Expected value, compared as a string
+}
+
+// TransactionStep is one HTTP request of a transaction monitor. The method, URL,
+// header values and body are templates in which {{name}} is replaced by the
+// value of a variable extracted by an earlier step.
+type TransactionStep struct {
+	Name         string            `json:"name"`
+	Method       string            `json:"method,omitempty"` // GET if empty
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers,omitempty"`       // Stored as is: secrets belong in CredentialID or extracted variables
+	Body         string            `json:"body,omitempty"`          // JSON object
+	CredentialID string            `json:"credential_id,omitempty"` // Stored credential applied to the request
+
+	// Assertions, as for HTTP monitors
+	AcceptedStatusCodes string              `json:"accepted_status_codes,omitempty"` // 200-299 if empty
+	BodyContains        string              `json:"body_contains,omitempty"`
+	BodyNotContains     string              `json:"body_not_contains,omitempty"`
+	BodyRegex           string              `json:"body_regex,omitempty"`
+	JSONPathAssertions  []JSONPathAssertion `json:"json_path_assertions,omitempty"`
+
+	Extract []TransactionVariable `json:"extract,omitempty"` // Variables set from the response
+}
+
+// TransactionVariable extracts a value from a step's response for later steps
+type TransactionVariable struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"` // json (default) or header
+	Path   string `json:"path"`             // JSONPath into the body, or the header name
 }
 
 // GetHeadersMap parses the Headers string into a map
@@ -85,3 +186,85 @@ func (m *Monitor) IsCurlRequest() bool {
 func (m *Monitor) IsFormData() bool {
 	return m.IsCurlRequest() && m.Body != "" && m.Body[0] != '{' && m.Body[0] != '['
 }
+
+// IsHTTPMonitor returns true if this monitor sends an HTTP request to its URL
+func (m *Monitor) IsHTTPMonitor() bool {
+	return m.Type == "" || m.Type == MonitorTypeHTTP
+}
+
+// GetDNSRecordType returns the record type checked by a dns monitor
+func (m *Monitor) GetDNSRecordType() string {
+	if m.DNSRecordType == "" {
+		return "A"
+	}
+	return strings.ToUpper(m.DNSRecordType)
+}
+
+// GetSeverity returns the monitor's severity, critical if it isn't set
+func (m *Monitor) GetSeverity() string {
+	if m.Severity == "" {
+		return SeverityCritical
+	}
+	return strings.ToLower(m.Severity)
+}
+
+// GetDNSExpectedValues returns the values a dns monitor expects to resolve
+func (m *Monitor) GetDNSExpectedValues() []string {
+	return SplitList(m.DNSExpected)
+}
+
+// HasTag returns true if the monitor carries the tag, ignoring case
+func (m *Monitor) HasTag(tag string) bool {
+	for _, t := range SplitList(m.Tags) {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDatabaseMonitor returns true if this monitor checks a database rather than an HTTP endpoint
+func (m *Monitor) IsDatabaseMonitor() bool {
+	switch m.Type {
+	case MonitorTypeMySQL, MonitorTypePostgres, MonitorTypeMongoDB, MonitorTypeRedis:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetJSONPathAssertions parses the JSONPathAssertions string into a slice
+func (m *Monitor) GetJSONPathAssertions() ([]JSONPathAssertion, error) {
+	if m.JSONPathAssertions == "" {
+		return nil, nil
+	}
+
+	var assertions []JSONPathAssertion
+	if err := json.Unmarshal([]byte(m.JSONPathAssertions), &assertions); err != nil {
+		return nil, err
+	}
+	return assertions, nil
+}
+
+// GetTransactionSteps parses the TransactionSteps string into a slice
+func (m *Monitor) GetTransactionSteps() ([]TransactionStep, error) {
+	if m.TransactionSteps == "" {
+		return nil, nil
+	}
+
+	var steps []TransactionStep
+	if err := json.Unmarshal([]byte(m.TransactionSteps), &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// HasBodyAssertions returns true if the response body needs to be read to evaluate the check
+func (m *Monitor) HasBodyAssertions() bool {
+	return m.BodyContains != "" || m.BodyNotContains != "" || m.BodyRegex != "" || m.JSONPathAssertions != ""
+}
+
+// ShouldFollowRedirects returns true unless redirects were explicitly disabled
+func (m *Monitor) ShouldFollowRedirects() bool {
+	return m.FollowRedirects == nil || *m.FollowRedirects
+}
@@ -0,0 +1,76 @@
+package types
+
+import "time"
+
+// Notification delivery states
+const (
+	DeliveryStatusPending   = "pending"   // Waiting for its first attempt or a retry
+	DeliveryStatusSending   = "sending"   // Claimed by a dispatcher worker
+	DeliveryStatusDelivered = "delivered" // Accepted by the notification method
+	DeliveryStatusDead      = "dead"      // Gave up after the last attempt, kept for inspection and resending
+)
+
+// NotificationDelivery is a notification queued for one notification method. It
+// holds what the notification needs of the monitor at the time of the event, so
+// it is delivered as it happened even when the monitor changes in between.
+type NotificationDelivery struct {
+	ID           string `json:"id"`
+	ProfileID    string `json:"profile_id" gorm:"index"`
+	MethodID     string `json:"method_id" gorm:"index"`
+	MethodType   string `json:"method_type"`
+	MonitorID    string `json:"monitor_id" gorm:"index"`
+	MonitorName  string `json:"monitor_name"`
+	MonitorType  string `json:"monitor_type"`
+	URL          string `json:"url,omitempty"`
+	Hostname     string `json:"hostname,omitempty"`
+	DBHost       string `json:"db_host,omitempty"`
+	Tags         string `json:"tags,omitempty"`
+	Severity     string `json:"severity,omitempty"`
+	ResponseCode int    `json:"response_code"`
+	ResponseTime int64  `json:"response_time"`
+
+	Event             string     `json:"event"`                     // Monitor status notified: up, down, unauthorized or cert_*
+	PreviousStatus    string     `json:"previous_status,omitempty"` // Status before the change
+	Message           string     `json:"message"`
+	EventTime         time.Time  `json:"event_time"`
+	IncidentStartedAt *time.Time `json:"incident_started_at,omitempty"`
+	ResendOf          string     `json:"resend_of,omitempty"` // Delivery this one was resent from
+
+	Status        string                `json:"status" gorm:"index"` // pending, sending, delivered or dead
+	Attempts      int                   `json:"attempts"`
+	MaxAttempts   int                   `json:"max_attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" gorm:"index"`
+	LastError     string                `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+	History       []NotificationAttempt `json:"history,omitempty" gorm:"foreignKey:DeliveryID"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+// Monitor returns the monitor as it was when the notification was queued
+func (d *NotificationDelivery) Monitor() *Monitor {
+	return &Monitor{
+		ID:           d.MonitorID,
+		ProfileID:    d.ProfileID,
+		Name:         d.MonitorName,
+		Type:         d.MonitorType,
+		URL:          d.URL,
+		Hostname:     d.Hostname,
+		DBHost:       d.DBHost,
+		Tags:         d.Tags,
+		Severity:     d.Severity,
+		ResponseCode: d.ResponseCode,
+		ResponseTime: d.ResponseTime,
+	}
+}
+
+// NotificationAttempt is an attempt to deliver a notification
+type NotificationAttempt struct {
+	ID         string    `json:"id"`
+	DeliveryID string    `json:"delivery_id" gorm:"index"`
+	Attempt    int       `json:"attempt"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Duration   int64     `json:"duration"` // Milliseconds
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -3,16 +3,24 @@ package types
 import (
 	"encoding/json"
 	"time"
+	"uptime-monitor/secrets"
 )
 
 type NotificationMethod struct {
-	ID        string          `json:"id"`
-	ProfileID string          `json:"profile_id"`
-	Type      string          `json:"type"`
-	Enabled   bool            `json:"enabled"`
-	Config    json.RawMessage `json:"config"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID          string       `json:"id"`
+	ProfileID   string       `json:"profile_id"`
+	Type        string       `json:"type"`
+	Enabled     bool         `json:"enabled"`
+	Config      secrets.JSON `json:"config"`                 // Secret fields are encrypted at rest and masked in responses
+	MaxAttempts int          `json:"max_attempts,omitempty"` // Delivery attempts per notification, the default if 0
+
+	// Templates of the notification text, the method type's default if empty
+	SubjectTemplate string `json:"subject_template,omitempty"` // text/template
+	BodyTemplate    string `json:"body_template,omitempty"`    // text/template
+	HTMLTemplate    string `json:"html_template,omitempty"`    // html/template, email only
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ParseConfig helps parse the config into a specific type
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Notification events, the transitions rules route to notification methods
+const (
+	NotificationEventDown         = "down"          // Monitor went down or unauthorized
+	NotificationEventRecovered    = "recovered"     // Monitor is up again, or no longer degraded
+	NotificationEventDegraded     = "degraded"      // Monitor responds slower than its degraded threshold
+	NotificationEventCertExpiring = "cert_expiring" // TLS certificate expiring or expired
+)
+
+// NotificationEvents lists the notification events rules may select
+var NotificationEvents = []string{
+	NotificationEventDown,
+	NotificationEventRecovered,
+	NotificationEventDegraded,
+	NotificationEventCertExpiring,
+}
+
+// NotificationRule sends the notifications of matching monitors and events
+// through chosen notification methods. A profile's rules apply to all of its
+// monitors; rules with a MonitorID override them for that monitor. Without any
+// enabled rule, every enabled method receives every notification.
+type NotificationRule struct {
+	ID        string `json:"id"`
+	ProfileID string `json:"profile_id" gorm:"index"`
+	Name      string `json:"name"`
+	Enabled   bool   `json:"enabled"`
+	MonitorID string `json:"monitor_id,omitempty" gorm:"index"` // Monitor whose profile rules this rule overrides, empty for a profile rule
+
+	// Conditions, each matching everything when empty
+	Tags       string `json:"tags,omitempty"`       // Comma separated monitor tags, any of which must be set
+	Severities string `json:"severities,omitempty"` // Comma separated monitor severities
+	Events     string `json:"events,omitempty"`     // Comma separated notification events
+
+	MethodIDs string `json:"method_ids,omitempty"` // Comma separated notification methods, all enabled methods if empty
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches returns true if the rule's conditions select a notification event of a monitor
+func (r *NotificationRule) Matches(monitor *Monitor, event string) bool {
+	if r.MonitorID != "" && r.MonitorID != monitor.ID {
+		return false
+	}
+	if tags := SplitList(r.Tags); len(tags) > 0 {
+		tagged := false
+		for _, tag := range tags {
+			if monitor.HasTag(tag) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if severities := SplitList(r.Severities); len(severities) > 0 && !listContains(severities, monitor.GetSeverity()) {
+		return false
+	}
+	if events := SplitList(r.Events); len(events) > 0 && !listContains(events, event) {
+		return false
+	}
+	return true
+}
+
+// listContains returns true if list holds value, ignoring case
+func listContains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,7 +6,38 @@ type Profile struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
-	IsActive    bool      `json:"is_active"`
+	IsActive    bool      `json:"is_active" gorm:"-"` // Whether this is the caller's default profile
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// Profile roles. Owners manage the profile and its members, editors change
+// monitors and settings, viewers have read-only access to monitors and history.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// ProfileMembership gives a user a role in a profile
+type ProfileMembership struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	ProfileID string    `json:"profile_id" gorm:"uniqueIndex:idx_membership_profile_user;not null"`
+	UserID    string    `json:"user_id" gorm:"uniqueIndex:idx_membership_profile_user;not null"`
+	Username  string    `json:"username" gorm:"->;-:migration"` // Loaded with the member listing
+	Role      string    `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsValidRole returns true for a known profile role
+func IsValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAllows returns true if role grants at least the required role
+func RoleAllows(role, required string) bool {
+	return IsValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
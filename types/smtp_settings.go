@@ -1,9 +1,11 @@
 package types
 
+import "uptime-monitor/secrets"
+
 type SMTPSettings struct {
-	Host           string `json:"host"`
-	Port           int    `json:"port"`
-	Username       string `json:"username"`
-	Password       string `json:"password"`
-	RecipientEmail string `json:"recipient_email"`
+	Host           string         `json:"host"`
+	Port           int            `json:"port"`
+	Username       string         `json:"username"`
+	Password       secrets.String `json:"password"`
+	RecipientEmail string         `json:"recipient_email"`
 }
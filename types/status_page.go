@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// StatusPage is a public, read-only page that groups monitors into components
+type StatusPage struct {
+	ID          string                `json:"id"`
+	ProfileID   string                `json:"profile_id" gorm:"index"`
+	Slug        string                `json:"slug" gorm:"uniqueIndex"` // Served at /status/:slug
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	LogoURL     string                `json:"logo_url,omitempty"`
+	Published   bool                  `json:"published"` // Unpublished pages are not served publicly
+	Components  []StatusPageComponent `json:"components" gorm:"foreignKey:StatusPageID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// StatusPageComponent is a named group of monitors shown as one entry on a status page
+type StatusPageComponent struct {
+	ID           string `json:"id"`
+	StatusPageID string `json:"status_page_id" gorm:"index"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	MonitorIDs   string `json:"monitor_ids"` // Comma separated monitor IDs
+	Position     int    `json:"position"`
+}